@@ -2,9 +2,7 @@ package cmd
 
 import (
 	e "github.com/cloudposse/atmos/internal/exec"
-	"github.com/fatih/color"
 	"github.com/spf13/cobra"
-	"os"
 )
 
 // describeComponentCmd describes configuration for components
@@ -16,8 +14,7 @@ var describeComponentCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		err := e.ExecuteDescribeComponent(cmd, args)
 		if err != nil {
-			color.Red("%s\n\n", err)
-			os.Exit(1)
+			exitWithError(err)
 		}
 	},
 }
@@ -25,11 +22,13 @@ var describeComponentCmd = &cobra.Command{
 func init() {
 	describeComponentCmd.DisableFlagParsing = false
 	describeComponentCmd.PersistentFlags().StringP("stack", "s", "", "")
+	describeComponentCmd.PersistentFlags().String("stacks-archive", "", "Read the stack config files from a '.zip', '.tar.gz' or '.tar' archive")
+	describeComponentCmd.PersistentFlags().Bool("unmask", false, "Show the values of the sensitive variables and ENV vars (requires a terminal)")
+	describeComponentCmd.PersistentFlags().Bool("resolve-secrets", false, "Resolve the '!secret' references in the variables and ENV vars (the values are masked unless '--unmask' is given)")
 
 	err := describeComponentCmd.MarkPersistentFlagRequired("stack")
 	if err != nil {
-		color.Red("%s\n\n", err)
-		os.Exit(1)
+		exitWithError(err)
 	}
 
 	describeCmd.AddCommand(describeComponentCmd)
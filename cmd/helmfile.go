@@ -2,9 +2,7 @@ package cmd
 
 import (
 	e "github.com/cloudposse/atmos/internal/exec"
-	"github.com/fatih/color"
 	"github.com/spf13/cobra"
-	"os"
 )
 
 // terraformCmd represents the base command for all terraform sub-commands
@@ -16,8 +14,7 @@ var helmfileCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		err := e.ExecuteHelmfile(cmd, args)
 		if err != nil {
-			color.Red("%s\n\n", err)
-			os.Exit(1)
+			exitWithError(err)
 		}
 	},
 }
@@ -29,8 +26,7 @@ func init() {
 
 	err := helmfileCmd.MarkPersistentFlagRequired("stack")
 	if err != nil {
-		color.Red("%s\n\n", err)
-		os.Exit(1)
+		exitWithError(err)
 	}
 
 	RootCmd.AddCommand(helmfileCmd)
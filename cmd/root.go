@@ -1,7 +1,10 @@
 package cmd
 
 import (
+	e "github.com/cloudposse/atmos/internal/exec"
+	l "github.com/cloudposse/atmos/pkg/logger"
 	"github.com/spf13/cobra"
+	"os"
 )
 
 // RootCmd represents the base command when called without any subcommands
@@ -19,9 +22,37 @@ func Execute() error {
 
 func init() {
 	cobra.OnInitialize(initConfig)
+	RootCmd.PersistentFlags().Bool("ci", false, "Run in CI mode: fail the commands which require a user interaction, disable colors and prompts, "+
+		"and print group markers around the output of the executed commands. Can also be enabled with the 'ATMOS_CI' ENV variable")
+	RootCmd.PersistentFlags().String("log-level", "", "Level of the atmos logs: trace, debug, info, warn or error. "+
+		"Overrides the 'logs.level' config and the 'ATMOS_LOGS_LEVEL' ENV variable")
+	RootCmd.PersistentFlags().Bool("quiet", false, "Print only the errors in the atmos logs. The output of the commands is not affected")
 }
 
 func initConfig() {
+	// The `--ci`, `--log-level` and `--quiet` flags of the commands with the disabled flag parsing (e.g. `terraform`) are processed by the commands
+	ci, _ := RootCmd.PersistentFlags().GetBool("ci")
+	err := e.ProcessCIMode(ci)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	logLevel, _ := RootCmd.PersistentFlags().GetString("log-level")
+	quiet, _ := RootCmd.PersistentFlags().GetBool("quiet")
+	err = e.ProcessLogLevelFlags(logLevel, quiet)
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+// exitWithError logs the error and exits with the exit code of the error
+// (the exit code of the failed terraform or helmfile command, or the atmos exit code for the error).
+// The silent errors (e.g. `terraform plan -detailed-exitcode` with changes) are not logged
+func exitWithError(err error) {
+	if !e.IsSilent(err) {
+		l.Error("%s\n", err)
+	}
+	os.Exit(e.ExitCode(err))
 }
 
 // https://blog.knoldus.com/create-kubectl-like-cli-with-go-and-cobra/
@@ -0,0 +1,17 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// stacksCmd manages the stack config files
+var stacksCmd = &cobra.Command{
+	Use:                "stacks",
+	Short:              "stacks",
+	Long:               `This command manages the stack config files`,
+	FParseErrWhitelist: struct{ UnknownFlags bool }{UnknownFlags: false},
+}
+
+func init() {
+	RootCmd.AddCommand(stacksCmd)
+}
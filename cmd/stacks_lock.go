@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	e "github.com/cloudposse/atmos/internal/exec"
+	s "github.com/cloudposse/atmos/pkg/stack"
+	"github.com/spf13/cobra"
+)
+
+// stacksLockCmd writes the resolved imports of all stacks to the lock file
+var stacksLockCmd = &cobra.Command{
+	Use:                "lock",
+	Short:              "lock stacks",
+	Long:               `This command writes the resolved imports of all stacks and the checksums of the imported files to the lock file`,
+	FParseErrWhitelist: struct{ UnknownFlags bool }{UnknownFlags: false},
+	Run: func(cmd *cobra.Command, args []string) {
+		err := e.ExecuteStacksLock(cmd, args)
+		if err != nil {
+			exitWithError(err)
+		}
+	},
+}
+
+func init() {
+	stacksLockCmd.DisableFlagParsing = false
+	stacksLockCmd.PersistentFlags().String("file", s.LockFileName, "Path to the lock file")
+	stacksLockCmd.PersistentFlags().String("stacks-archive", "", "Read the stack config files from a '.zip', '.tar.gz' or '.tar' archive")
+
+	stacksCmd.AddCommand(stacksLockCmd)
+}
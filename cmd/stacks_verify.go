@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	e "github.com/cloudposse/atmos/internal/exec"
+	s "github.com/cloudposse/atmos/pkg/stack"
+	"github.com/spf13/cobra"
+)
+
+// stacksVerifyCmd checks that the resolved imports of all stacks match the lock file
+var stacksVerifyCmd = &cobra.Command{
+	Use:                "verify",
+	Short:              "verify stacks",
+	Long:               `This command checks that the resolved imports of all stacks and the checksums of the imported files match the lock file`,
+	FParseErrWhitelist: struct{ UnknownFlags bool }{UnknownFlags: false},
+	Run: func(cmd *cobra.Command, args []string) {
+		err := e.ExecuteStacksVerify(cmd, args)
+		if err != nil {
+			exitWithError(err)
+		}
+	},
+}
+
+func init() {
+	stacksVerifyCmd.DisableFlagParsing = false
+	stacksVerifyCmd.PersistentFlags().String("file", s.LockFileName, "Path to the lock file")
+	stacksVerifyCmd.PersistentFlags().String("stacks-archive", "", "Read the stack config files from a '.zip', '.tar.gz' or '.tar' archive")
+
+	stacksCmd.AddCommand(stacksVerifyCmd)
+}
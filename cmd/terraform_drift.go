@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	e "github.com/cloudposse/atmos/internal/exec"
+	"github.com/spf13/cobra"
+)
+
+// terraformDriftCmd detects drift of the terraform components in all stacks
+var terraformDriftCmd = &cobra.Command{
+	Use:   "drift",
+	Short: "detect drift",
+	Long: "This command runs 'terraform plan -detailed-exitcode' for all terraform components in the stacks, " +
+		"and reports the components without changes, the drifted components, and the components with errors",
+	FParseErrWhitelist: struct{ UnknownFlags bool }{UnknownFlags: false},
+	Run: func(cmd *cobra.Command, args []string) {
+		err := e.ExecuteTerraformDrift(cmd, args)
+		if err != nil {
+			exitWithError(err)
+		}
+	},
+}
+
+func init() {
+	terraformDriftCmd.DisableFlagParsing = false
+	// Override the required `stack` flag of the `terraform` command, the stacks are selected by the `--stacks` glob
+	terraformDriftCmd.PersistentFlags().StringP("stack", "s", "", "")
+	_ = terraformDriftCmd.PersistentFlags().MarkHidden("stack")
+	terraformDriftCmd.PersistentFlags().String("stacks", "", "Glob pattern of the stacks to check (e.g. 'tenant1-ue2-*' or 'tenant1/**'). Defaults to all stacks")
+	terraformDriftCmd.PersistentFlags().String("components", "", "Glob pattern of the terraform components to check (e.g. 'infra/*'). Defaults to all components")
+	terraformDriftCmd.PersistentFlags().String("stacks-archive", "", "Read the stack config files from a '.zip', '.tar.gz' or '.tar' archive")
+	terraformDriftCmd.PersistentFlags().String("format", "json", "Report format: 'json', 'junit' or 'markdown'")
+	terraformDriftCmd.PersistentFlags().String("file", "", "Write the report to the file instead of the console")
+	terraformDriftCmd.PersistentFlags().Int("concurrency", 4, "Number of the components to plan in parallel")
+
+	terraformCmd.AddCommand(terraformDriftCmd)
+}
@@ -2,9 +2,7 @@ package cmd
 
 import (
 	e "github.com/cloudposse/atmos/internal/exec"
-	"github.com/fatih/color"
 	"github.com/spf13/cobra"
-	"os"
 )
 
 // terraformGenerateBackendCmd generates backend config for a terraform components
@@ -16,8 +14,7 @@ var terraformGenerateBackendCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		err := e.ExecuteTerraformGenerateBackend(cmd, args)
 		if err != nil {
-			color.Red("%s\n\n", err)
-			os.Exit(1)
+			exitWithError(err)
 		}
 	},
 }
@@ -25,11 +22,11 @@ var terraformGenerateBackendCmd = &cobra.Command{
 func init() {
 	terraformGenerateBackendCmd.DisableFlagParsing = false
 	terraformGenerateBackendCmd.PersistentFlags().StringP("stack", "s", "", "")
+	terraformGenerateBackendCmd.PersistentFlags().String("stacks-archive", "", "Read the stack config files from a '.zip', '.tar.gz' or '.tar' archive")
 
 	err := terraformGenerateBackendCmd.MarkPersistentFlagRequired("stack")
 	if err != nil {
-		color.Red("%s\n\n", err)
-		os.Exit(1)
+		exitWithError(err)
 	}
 
 	terraformGenerateCmd.AddCommand(terraformGenerateBackendCmd)
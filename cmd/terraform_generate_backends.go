@@ -2,22 +2,19 @@ package cmd
 
 import (
 	e "github.com/cloudposse/atmos/internal/exec"
-	"github.com/fatih/color"
 	"github.com/spf13/cobra"
-	"os"
 )
 
 // terraformGenerateBackendsCmd generates backend configs for all terraform components
 var terraformGenerateBackendsCmd = &cobra.Command{
 	Use:                "backends",
 	Short:              "generate backends",
-	Long:               `This command generates the backend configs for all terraform components`,
+	Long:               `This command generates the backend configs for all terraform components in the stack`,
 	FParseErrWhitelist: struct{ UnknownFlags bool }{UnknownFlags: false},
 	Run: func(cmd *cobra.Command, args []string) {
 		err := e.ExecuteTerraformGenerateBackends(cmd, args)
 		if err != nil {
-			color.Red("%s\n\n", err)
-			os.Exit(1)
+			exitWithError(err)
 		}
 	},
 }
@@ -25,11 +22,11 @@ var terraformGenerateBackendsCmd = &cobra.Command{
 func init() {
 	terraformGenerateBackendsCmd.DisableFlagParsing = false
 	terraformGenerateBackendsCmd.PersistentFlags().StringP("stack", "s", "", "")
+	terraformGenerateBackendsCmd.PersistentFlags().String("stacks-archive", "", "Read the stack config files from a '.zip', '.tar.gz' or '.tar' archive")
 
 	err := terraformGenerateBackendsCmd.MarkPersistentFlagRequired("stack")
 	if err != nil {
-		color.Red("%s\n\n", err)
-		os.Exit(1)
+		exitWithError(err)
 	}
 
 	terraformGenerateCmd.AddCommand(terraformGenerateBackendsCmd)
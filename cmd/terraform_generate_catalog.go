@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	e "github.com/cloudposse/atmos/internal/exec"
+	"github.com/spf13/cobra"
+)
+
+// terraformGenerateCatalogCmd generates the catalog config for a terraform component from its variables
+var terraformGenerateCatalogCmd = &cobra.Command{
+	Use:                "catalog",
+	Short:              "generate catalog",
+	Long:               `This command generates the catalog config for a terraform component from the 'variable' blocks in the component folder`,
+	FParseErrWhitelist: struct{ UnknownFlags bool }{UnknownFlags: false},
+	Run: func(cmd *cobra.Command, args []string) {
+		err := e.ExecuteTerraformGenerateCatalog(cmd, args)
+		if err != nil {
+			exitWithError(err)
+		}
+	},
+}
+
+func init() {
+	terraformGenerateCatalogCmd.DisableFlagParsing = false
+	// The catalog does not depend on a stack. This flag overrides the required `stack` flag of the `terraform` command
+	terraformGenerateCatalogCmd.PersistentFlags().StringP("stack", "s", "", "")
+	terraformGenerateCatalogCmd.PersistentFlags().String("file", "", "Path to the catalog file (defaults to 'catalog/terraform/<component>.yaml' in the stacks base path)")
+	terraformGenerateCatalogCmd.PersistentFlags().Bool("update", false, "Add the new variables to the existing catalog file without changing the existing values")
+	terraformGenerateCatalogCmd.PersistentFlags().Bool("include-context", false, "Include the context variables from 'context.tf'")
+
+	terraformGenerateCmd.AddCommand(terraformGenerateCatalogCmd)
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	e "github.com/cloudposse/atmos/internal/exec"
+	"github.com/spf13/cobra"
+)
+
+// terraformGenerateProvidersCmd generates provider overrides for a terraform component
+var terraformGenerateProvidersCmd = &cobra.Command{
+	Use:                "providers",
+	Short:              "generate providers",
+	Long:               `This command generates the provider overrides file from the 'providers' section of a terraform component`,
+	FParseErrWhitelist: struct{ UnknownFlags bool }{UnknownFlags: false},
+	Run: func(cmd *cobra.Command, args []string) {
+		err := e.ExecuteTerraformGenerateProviders(cmd, args)
+		if err != nil {
+			exitWithError(err)
+		}
+	},
+}
+
+func init() {
+	terraformGenerateProvidersCmd.DisableFlagParsing = false
+	terraformGenerateProvidersCmd.PersistentFlags().StringP("stack", "s", "", "")
+	terraformGenerateProvidersCmd.PersistentFlags().String("stacks-archive", "", "Read the stack config files from a '.zip', '.tar.gz' or '.tar' archive")
+
+	err := terraformGenerateProvidersCmd.MarkPersistentFlagRequired("stack")
+	if err != nil {
+		exitWithError(err)
+	}
+
+	terraformGenerateCmd.AddCommand(terraformGenerateProvidersCmd)
+}
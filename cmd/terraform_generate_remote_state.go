@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	e "github.com/cloudposse/atmos/internal/exec"
+	"github.com/spf13/cobra"
+)
+
+// terraformGenerateRemoteStateCmd generates the remote state config to read the state of a terraform component
+var terraformGenerateRemoteStateCmd = &cobra.Command{
+	Use:                "remote-state",
+	Short:              "generate remote-state",
+	Long:               `This command generates the 'terraform_remote_state' data source or the remote state backend config to read the state of a terraform component in a stack`,
+	FParseErrWhitelist: struct{ UnknownFlags bool }{UnknownFlags: false},
+	Run: func(cmd *cobra.Command, args []string) {
+		err := e.ExecuteTerraformGenerateRemoteState(cmd, args)
+		if err != nil {
+			exitWithError(err)
+		}
+	},
+}
+
+func init() {
+	terraformGenerateRemoteStateCmd.DisableFlagParsing = false
+	terraformGenerateRemoteStateCmd.PersistentFlags().StringP("stack", "s", "", "")
+	terraformGenerateRemoteStateCmd.PersistentFlags().String("stacks-archive", "", "Read the stack config files from a '.zip', '.tar.gz' or '.tar' archive")
+	terraformGenerateRemoteStateCmd.PersistentFlags().String("format", "hcl", "Output format: 'hcl' or 'json' for the 'terraform_remote_state' data source, 'backend' for the remote state backend config")
+	terraformGenerateRemoteStateCmd.PersistentFlags().String("name", "", "Name of the 'terraform_remote_state' data source (defaults to the component name with '/' replaced by '-')")
+	terraformGenerateRemoteStateCmd.PersistentFlags().String("file", "", "Write the remote state config to the file instead of the console")
+
+	err := terraformGenerateRemoteStateCmd.MarkPersistentFlagRequired("stack")
+	if err != nil {
+		exitWithError(err)
+	}
+
+	terraformGenerateCmd.AddCommand(terraformGenerateRemoteStateCmd)
+}
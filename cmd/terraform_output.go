@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	e "github.com/cloudposse/atmos/internal/exec"
+	"github.com/spf13/cobra"
+)
+
+// terraformOutputCmd shows the outputs of a terraform component
+var terraformOutputCmd = &cobra.Command{
+	Use:   "output",
+	Short: "show the outputs of a terraform component",
+	Long: "This command runs 'terraform output -json' for the terraform component in the stack and prints the values of the outputs as JSON. " +
+		"If the output name is provided ('atmos terraform output <component> -s <stack> <output>'), only the value of the output is printed",
+	FParseErrWhitelist: struct{ UnknownFlags bool }{UnknownFlags: false},
+	Run: func(cmd *cobra.Command, args []string) {
+		err := e.ExecuteTerraformOutput(cmd, args)
+		if err != nil {
+			exitWithError(err)
+		}
+	},
+}
+
+func init() {
+	terraformOutputCmd.DisableFlagParsing = false
+	terraformOutputCmd.PersistentFlags().StringP("stack", "s", "", "")
+	terraformOutputCmd.PersistentFlags().String("stacks-archive", "", "Read the stack config files from a '.zip', '.tar.gz' or '.tar' archive")
+
+	terraformCmd.AddCommand(terraformOutputCmd)
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	e "github.com/cloudposse/atmos/internal/exec"
+	"github.com/spf13/cobra"
+)
+
+// terraformValidateVarsCmd validates the vars of a terraform component against the variables declared in the component
+var terraformValidateVarsCmd = &cobra.Command{
+	Use:                "validate-vars",
+	Short:              "validate vars",
+	Long:               `This command compares the final vars of a terraform component in a stack with the 'variable' blocks declared in the component's '*.tf' files`,
+	FParseErrWhitelist: struct{ UnknownFlags bool }{UnknownFlags: false},
+	Run: func(cmd *cobra.Command, args []string) {
+		err := e.ExecuteTerraformValidateVars(cmd, args)
+		if err != nil {
+			exitWithError(err)
+		}
+	},
+}
+
+func init() {
+	terraformValidateVarsCmd.DisableFlagParsing = false
+	terraformValidateVarsCmd.PersistentFlags().StringP("stack", "s", "", "")
+	terraformValidateVarsCmd.PersistentFlags().String("stacks-archive", "", "Read the stack config files from a '.zip', '.tar.gz' or '.tar' archive")
+
+	err := terraformValidateVarsCmd.MarkPersistentFlagRequired("stack")
+	if err != nil {
+		exitWithError(err)
+	}
+
+	terraformCmd.AddCommand(terraformValidateVarsCmd)
+}
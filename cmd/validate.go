@@ -0,0 +1,17 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// validateCmd validates the components in the stacks
+var validateCmd = &cobra.Command{
+	Use:                "validate",
+	Short:              "validate",
+	Long:               `This command validates the final config of the components in the stacks`,
+	FParseErrWhitelist: struct{ UnknownFlags bool }{UnknownFlags: false},
+}
+
+func init() {
+	RootCmd.AddCommand(validateCmd)
+}
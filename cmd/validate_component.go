@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	e "github.com/cloudposse/atmos/internal/exec"
+	"github.com/spf13/cobra"
+)
+
+// validateComponentCmd validates a component in a stack
+var validateComponentCmd = &cobra.Command{
+	Use:                "component",
+	Short:              "validate component",
+	Long:               `This command validates the final vars and settings of a component in a stack with the validations from its 'settings.validation' section`,
+	FParseErrWhitelist: struct{ UnknownFlags bool }{UnknownFlags: false},
+	Run: func(cmd *cobra.Command, args []string) {
+		err := e.ExecuteValidateComponent(cmd, args)
+		if err != nil {
+			exitWithError(err)
+		}
+	},
+}
+
+func init() {
+	validateComponentCmd.DisableFlagParsing = false
+	validateComponentCmd.PersistentFlags().StringP("stack", "s", "", "")
+	validateComponentCmd.PersistentFlags().String("stacks-archive", "", "Read the stack config files from a '.zip', '.tar.gz' or '.tar' archive")
+
+	err := validateComponentCmd.MarkPersistentFlagRequired("stack")
+	if err != nil {
+		exitWithError(err)
+	}
+
+	validateCmd.AddCommand(validateComponentCmd)
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	e "github.com/cloudposse/atmos/internal/exec"
+	"github.com/spf13/cobra"
+)
+
+// validatePoliciesCmd evaluates the policies against all components in all stacks
+var validatePoliciesCmd = &cobra.Command{
+	Use:                "policies",
+	Short:              "validate policies",
+	Long:               `This command evaluates the Rego policies from 'policies.base_path' against the final config of all components in all stacks and reports the 'deny' and 'warn' messages`,
+	FParseErrWhitelist: struct{ UnknownFlags bool }{UnknownFlags: false},
+	Run: func(cmd *cobra.Command, args []string) {
+		err := e.ExecuteValidatePolicies(cmd, args)
+		if err != nil {
+			exitWithError(err)
+		}
+	},
+}
+
+func init() {
+	validatePoliciesCmd.DisableFlagParsing = false
+	validatePoliciesCmd.PersistentFlags().String("stacks-archive", "", "Read the stack config files from a '.zip', '.tar.gz' or '.tar' archive")
+
+	validateCmd.AddCommand(validatePoliciesCmd)
+}
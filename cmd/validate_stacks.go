@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	e "github.com/cloudposse/atmos/internal/exec"
+	"github.com/spf13/cobra"
+)
+
+// validateStacksCmd validates all components in all stacks
+var validateStacksCmd = &cobra.Command{
+	Use:                "stacks",
+	Short:              "validate stacks",
+	Long:               `This command checks the structure of all stack config files and validates the final vars and settings of all components with the validations from their 'settings.validation' sections`,
+	FParseErrWhitelist: struct{ UnknownFlags bool }{UnknownFlags: false},
+	Run: func(cmd *cobra.Command, args []string) {
+		err := e.ExecuteValidateStacks(cmd, args)
+		if err != nil {
+			exitWithError(err)
+		}
+	},
+}
+
+func init() {
+	validateStacksCmd.DisableFlagParsing = false
+	validateStacksCmd.PersistentFlags().String("format", "text", "Output format: 'text', 'json' or 'sarif'")
+	validateStacksCmd.PersistentFlags().String("file", "", "Write the 'json' or 'sarif' report to the file")
+	validateStacksCmd.PersistentFlags().String("stacks-archive", "", "Read the stack config files from a '.zip', '.tar.gz' or '.tar' archive")
+
+	validateCmd.AddCommand(validateStacksCmd)
+}
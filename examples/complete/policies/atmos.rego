@@ -0,0 +1,29 @@
+# Atmos policies
+# The policies are evaluated against the final config of each component in each stack.
+# The input is the component config (`vars`, `settings`, `env`, `backend_type`, `backend`, etc.)
+# with the component and stack names in `atmos_component`, `atmos_component_type`, `atmos_stack` and `atmos_stack_file`.
+# Messages from the `deny` rules fail the validation and prevent `atmos terraform apply`,
+# messages from the `warn` rules are reported only
+package atmos
+
+# Production stages must not use burstable `t3` instances
+deny[msg] {
+	input.vars.stage == "prod"
+	startswith(input.vars.nat_instance_type, "t3.")
+	msg := sprintf("'nat_instance_type' must not be a 't3' instance in the 'prod' stage, but it's '%s'", [input.vars.nat_instance_type])
+}
+
+# Every `s3` backend must encrypt the state
+deny[msg] {
+	input.backend_type == "s3"
+	not input.backend.encrypt == true
+	msg := "'backend.encrypt' must be set to 'true' for the 's3' backend"
+}
+
+# Production VPCs should use at least three availability zones
+warn[msg] {
+	input.atmos_component == "infra/vpc"
+	input.vars.stage == "prod"
+	count(input.vars.availability_zones) < 3
+	msg := sprintf("'infra/vpc' in the 'prod' stage should use at least 3 availability zones, but it uses %d", [count(input.vars.availability_zones)])
+}
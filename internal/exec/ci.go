@@ -0,0 +1,65 @@
+package exec
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	c "github.com/cloudposse/atmos/pkg/config"
+	l "github.com/cloudposse/atmos/pkg/logger"
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// ProcessCIMode disables the colors of the atmos output if the `--ci` flag is provided or the `ATMOS_CI` ENV var is set to `true`.
+// The other behavior of the CI mode (the commands don't ask for input, fail the commands which require a user interaction,
+// run terraform with `-input=false`, and print the output of each executed command in a `::group::` / `::endgroup::` block)
+// is controlled by the `CI` setting of the CLI config of the command (see `ciFlag`)
+func ProcessCIMode(ciFlag bool) error {
+	ci := ciFlag
+
+	ciEnvVar := os.Getenv("ATMOS_CI")
+	if len(ciEnvVar) > 0 {
+		ciEnvVarBool, err := strconv.ParseBool(ciEnvVar)
+		if err != nil {
+			return withExitCode(errors.New(fmt.Sprintf("invalid ENV var ATMOS_CI=%s: %s", ciEnvVar, err)), ExitCodeInvalidConfig)
+		}
+		ci = ci || ciEnvVarBool
+	}
+
+	if ci {
+		enableCIMode()
+	}
+	return nil
+}
+
+func enableCIMode() {
+	color.NoColor = true
+	l.DisableColorsOverride()
+}
+
+// ciFlag returns the value of the `--ci` flag, which the commands inherit from the root command
+func ciFlag(cmd *cobra.Command) bool {
+	ci, _ := cmd.Flags().GetBool("ci")
+	return ci
+}
+
+// isInteractive checks if atmos can ask the user for input: the CI mode is not enabled, and stdin is a terminal
+func isInteractive(cliConfig c.Configuration) bool {
+	if cliConfig.CI {
+		return false
+	}
+	fd := os.Stdin.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
+
+// isTerminalOutput checks if the output is printed to a terminal, and not to a file, a pipe or the CI logs
+func isTerminalOutput(cliConfig c.Configuration) bool {
+	if cliConfig.CI {
+		return false
+	}
+	fd := os.Stdout.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
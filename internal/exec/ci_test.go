@@ -0,0 +1,53 @@
+package exec
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	c "github.com/cloudposse/atmos/pkg/config"
+	l "github.com/cloudposse/atmos/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecCommandCIMode(t *testing.T) {
+	var logs, out bytes.Buffer
+	log := l.New(&logs, l.LevelInfo, l.FormatText, false)
+
+	err := execCommandWithIO(log, false, "echo", []string{"plan"}, t.TempDir(), nil, nil, &out, &out)
+	assert.Nil(t, err)
+	assert.Equal(t, "plan\n", out.String())
+
+	// In the CI mode, the output of the command is printed in a group
+	out.Reset()
+	err = execCommandWithIO(log, true, "echo", []string{"plan"}, t.TempDir(), nil, nil, &out, &out)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Equal(t, 3, len(lines))
+	assert.True(t, strings.HasPrefix(lines[0], "::group::"))
+	assert.Equal(t, "plan", lines[1])
+	assert.Equal(t, "::endgroup::", lines[2])
+
+	var cliConfig c.Configuration
+	cliConfig.CI = true
+	assert.False(t, isInteractive(cliConfig))
+	assert.False(t, isTerminalOutput(cliConfig))
+}
+
+func TestComponentLoggersMask(t *testing.T) {
+	var logs bytes.Buffer
+	log := l.New(&logs, l.LevelInfo, l.FormatText, false)
+
+	// The components processed concurrently mask their own secrets without changing the shared logger
+	vpcMasker, err := newComponentMasker(c.DefaultConfig(), nil, nil, map[string]interface{}{"GITHUB_TOKEN": "ghp_vpc"})
+	assert.Nil(t, err)
+	eksMasker, err := newComponentMasker(c.DefaultConfig(), nil, nil, map[string]interface{}{"GITHUB_TOKEN": "ghp_eks"})
+	assert.Nil(t, err)
+
+	vpcLog := log.WithMask(vpcMasker.Text)
+	eksLog := log.WithMask(eksMasker.Text)
+
+	vpcLog.Info("GITHUB_TOKEN=ghp_vpc")
+	eksLog.Info("GITHUB_TOKEN=ghp_eks")
+	assert.Equal(t, "GITHUB_TOKEN=***\nGITHUB_TOKEN=***\n", logs.String())
+}
@@ -1,21 +1,18 @@
 package exec
 
 import (
-	"fmt"
 	c "github.com/cloudposse/atmos/pkg/config"
-	g "github.com/cloudposse/atmos/pkg/globals"
-	s "github.com/cloudposse/atmos/pkg/stack"
+	l "github.com/cloudposse/atmos/pkg/logger"
+	"github.com/cloudposse/atmos/pkg/secrets"
 	u "github.com/cloudposse/atmos/pkg/utils"
-	"github.com/fatih/color"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
-	"strings"
 )
 
 // ExecuteDescribeComponent executes `describe component` command
 func ExecuteDescribeComponent(cmd *cobra.Command, args []string) error {
 	if len(args) != 1 {
-		return errors.New("invalid arguments. The command requires one argument `component`")
+		return withExitCode(errors.New("invalid arguments. The command requires one argument `component`"), ExitCodeInvalidArguments)
 	}
 	flags := cmd.Flags()
 
@@ -24,161 +21,76 @@ func ExecuteDescribeComponent(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	var configAndStacksInfo c.ConfigAndStacksInfo
-	configAndStacksInfo.Stack = stack
+	stacksArchive, err := flags.GetString("stacks-archive")
+	if err != nil {
+		return err
+	}
 
-	err = c.InitConfig()
+	unmask, err := flags.GetBool("unmask")
 	if err != nil {
 		return err
 	}
 
-	err = c.ProcessConfig(configAndStacksInfo)
+	resolveSecrets, err := flags.GetBool("resolve-secrets")
 	if err != nil {
 		return err
 	}
 
-	component := args[0]
+	var configAndStacksInfo c.ConfigAndStacksInfo
+	configAndStacksInfo.CI = ciFlag(cmd)
+	configAndStacksInfo.Stack = stack
+	configAndStacksInfo.StacksArchive = stacksArchive
 
-	// Print the stack config files
-	if g.LogVerbose {
-		fmt.Println()
-		var msg string
-		if c.ProcessedConfig.StackType == "Directory" {
-			msg = "Found the config file for the provided stack:"
-		} else {
-			msg = "Found config files:"
-		}
-		color.Cyan(msg)
+	client, err := newClient(configAndStacksInfo)
+	if err != nil {
+		return err
+	}
 
-		err = u.PrintAsYAML(c.ProcessedConfig.StackConfigFilesRelativePaths)
-		if err != nil {
-			return err
-		}
+	// Don't let the secrets end up in the files or the CI logs
+	if unmask && !isTerminalOutput(client.Config()) {
+		return withExitCode(errors.New("'--unmask' flag requires a terminal. "+
+			"The sensitive values are not printed when the output is redirected to a file or a pipe, or in the CI mode"), ExitCodeInvalidArguments)
 	}
 
-	_, stacksMap, err := s.ProcessYAMLConfigFiles(
-		c.ProcessedConfig.StacksBaseAbsolutePath,
-		c.ProcessedConfig.StackConfigFilesAbsolutePaths,
-		true,
-		true)
+	component := args[0]
 
+	componentConfig, err := client.DescribeComponent(stack, component)
 	if err != nil {
 		return err
 	}
 
-	var componentSection map[string]interface{}
-	var componentVarsSection map[interface{}]interface{}
-
-	// Check and process stacks
-	if c.ProcessedConfig.StackType == "Directory" {
-		componentSection,
-			componentVarsSection,
-			_, _, _, _, _, _,
-			err = findComponentConfig(stack, stacksMap, "terraform", component)
+	// The secret references are shown as they are in the stacks (e.g. `!secret env:GITHUB_TOKEN`) unless `--resolve-secrets` is given
+	var resolvedSecrets []string
+	if resolveSecrets {
+		var varsSecrets, envSecrets []string
+		componentConfig.Vars, varsSecrets, err = secrets.ResolveMap(componentConfig.Vars)
 		if err != nil {
-			componentSection,
-				componentVarsSection,
-				_, _, _, _, _, _,
-				err = findComponentConfig(stack, stacksMap, "helmfile", component)
-			if err != nil {
-				return err
-			}
-		}
-	} else {
-		if g.LogVerbose {
-			color.Cyan("Searching for stack config where the component '%s' is defined\n", component)
-		}
-
-		if len(c.Config.Stacks.NamePattern) < 1 {
-			return errors.New("stack name pattern must be provided in 'stacks.name_pattern' config or 'ATMOS_STACKS_NAME_PATTERN' ENV variable")
-		}
-
-		stackParts := strings.Split(stack, "-")
-		stackNamePatternParts := strings.Split(c.Config.Stacks.NamePattern, "-")
-
-		var tenant string
-		var environment string
-		var stage string
-		var tenantFound bool
-		var environmentFound bool
-		var stageFound bool
-
-		for i, part := range stackNamePatternParts {
-			if part == "{tenant}" {
-				tenant = stackParts[i]
-			} else if part == "{environment}" {
-				environment = stackParts[i]
-			} else if part == "{stage}" {
-				stage = stackParts[i]
-			}
+			return err
 		}
-
-		for stackName := range stacksMap {
-			componentSection,
-				componentVarsSection,
-				_, _, _, _, _, _,
-				err = findComponentConfig(stackName, stacksMap, "terraform", component)
-			if err != nil {
-				componentSection,
-					componentVarsSection,
-					_, _, _, _, _, _,
-					err = findComponentConfig(stackName, stacksMap, "helmfile", component)
-				if err != nil {
-					continue
-				}
-			}
-
-			tenantFound = true
-			environmentFound = true
-			stageFound = true
-
-			// Search for tenant in stack
-			if len(tenant) > 0 {
-				if tenantInStack, ok := componentVarsSection["tenant"].(string); !ok || tenantInStack != tenant {
-					tenantFound = false
-				}
-			}
-
-			// Search for environment in stack
-			if len(environment) > 0 {
-				if environmentInStack, ok := componentVarsSection["environment"].(string); !ok || environmentInStack != environment {
-					environmentFound = false
-				}
-			}
-
-			// Search for stage in stack
-			if len(stage) > 0 {
-				if stageInStack, ok := componentVarsSection["stage"].(string); !ok || stageInStack != stage {
-					stageFound = false
-				}
-			}
-
-			if tenantFound == true && environmentFound == true && stageFound == true {
-				if g.LogVerbose == true {
-					color.Cyan("Found stack config for component '%s' in the stack '%s'\n\n", component, stackName)
-				}
-				stack = stackName
-				break
-			}
+		componentConfig.Env, envSecrets, err = secrets.ResolveMap(componentConfig.Env)
+		if err != nil {
+			return err
 		}
+		resolvedSecrets = append(varsSecrets, envSecrets...)
+	}
 
-		if tenantFound == false || environmentFound == false || stageFound == false {
-			return errors.New(fmt.Sprintf("\nCould not find config for the component '%s' in the stack '%s'.\n"+
-				"Check that all attributes in the stack name pattern '%s' are defined in stack config files.\n"+
-				"Are the component and stack names correct? Did you forget an import?",
-				component,
-				stack,
-				c.Config.Stacks.NamePattern,
-			))
+	if !unmask {
+		masker, err := newComponentMasker(client.Config(), componentConfig.Settings, componentConfig.Vars, componentConfig.Env)
+		if err != nil {
+			return err
 		}
+		masker.AddValues(resolvedSecrets...)
+		componentConfig.Vars = masker.Map(componentConfig.Vars)
+		componentConfig.Settings = masker.Map(componentConfig.Settings)
+		componentConfig.Env = masker.Map(componentConfig.Env)
+		componentConfig.Backend = masker.Map(componentConfig.Backend)
+		componentConfig.RemoteStateBackend = masker.Map(componentConfig.RemoteStateBackend)
+		componentConfig.Providers = masker.Map(componentConfig.Providers)
 	}
 
-	if g.LogVerbose {
-		fmt.Println()
-		color.Cyan("Component config:\n\n")
-	}
+	l.Debug("\nComponent config:\n")
 
-	err = u.PrintAsYAML(componentSection)
+	err = u.PrintAsYAML(componentConfig)
 	if err != nil {
 		return err
 	}
@@ -195,15 +107,15 @@ func ExecuteDescribeConfig(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	err = c.InitConfig()
+	cliConfig, err := c.InitConfig()
 	if err != nil {
-		return err
+		return withExitCode(err, ExitCodeInvalidConfig)
 	}
 
 	if format == "json" {
-		err = u.PrintAsJSON(c.Config)
+		err = u.PrintAsJSON(cliConfig)
 	} else if format == "yaml" {
-		err = u.PrintAsYAML(c.Config)
+		err = u.PrintAsYAML(cliConfig)
 	} else {
 		err = errors.New("invalid flag '--format'. Accepted values are 'json' or 'yaml'")
 	}
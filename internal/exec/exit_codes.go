@@ -0,0 +1,100 @@
+package exec
+
+import (
+	"os/exec"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// The exit codes of atmos.
+// When terraform, helmfile or another command executed by atmos fails, atmos exits with the exit code of the command.
+// The errors of atmos itself have exit codes starting from 100 to not overlap with the exit codes of the commands
+const (
+	ExitCodeSuccess = 0
+	// ExitCodeFailure is returned by `terraform drift` when the plan of any component failed
+	ExitCodeFailure = 1
+	// ExitCodeChanges is returned by `terraform drift` when drift is found (like `terraform plan -detailed-exitcode`)
+	ExitCodeChanges = 2
+
+	ExitCodeError            = 100
+	ExitCodeInvalidArguments = 101
+	ExitCodeInvalidConfig    = 102
+	ExitCodeInvalidStacks    = 103
+	ExitCodeValidationFailed = 104
+)
+
+// ErrorWithExitCode is an error with the exit code of atmos
+type ErrorWithExitCode struct {
+	Err  error
+	Code int
+	// Silent is set if the error is not logged, and only its exit code is returned (e.g. the plan has changes)
+	Silent bool
+}
+
+func (e ErrorWithExitCode) Error() string {
+	return e.Err.Error()
+}
+
+// Cause returns the original error, for `errors.Cause`
+func (e ErrorWithExitCode) Cause() error {
+	return e.Err
+}
+
+// Unwrap returns the original error, for `errors.As`
+func (e ErrorWithExitCode) Unwrap() error {
+	return e.Err
+}
+
+// withExitCode adds the exit code to the error. A `nil` error stays `nil`, and the errors of the executed commands keep their exit codes
+func withExitCode(err error, code int) error {
+	if err == nil {
+		return nil
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return err
+	}
+	return ErrorWithExitCode{Err: err, Code: code}
+}
+
+// silentError marks the error to not be logged. The error keeps its exit code
+func silentError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return ErrorWithExitCode{Err: err, Code: ExitCode(err), Silent: true}
+}
+
+// IsSilent returns `true` if the error must not be logged, and only its exit code must be returned
+func IsSilent(err error) bool {
+	var errorWithExitCode ErrorWithExitCode
+	return errors.As(err, &errorWithExitCode) && errorWithExitCode.Silent
+}
+
+// ExitCode returns the exit code of atmos for the error.
+// It is the exit code of the executed command if the command failed, `128 + signal` if the command was killed by a signal,
+// the exit code added to the error by atmos, or `ExitCodeError` for the other errors
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitCodeSuccess
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal())
+		}
+		if exitErr.ExitCode() > 0 {
+			return exitErr.ExitCode()
+		}
+		return ExitCodeError
+	}
+
+	var errorWithExitCode ErrorWithExitCode
+	if errors.As(err, &errorWithExitCode) {
+		return errorWithExitCode.Code
+	}
+
+	return ExitCodeError
+}
@@ -5,52 +5,59 @@ package exec
 import (
 	"fmt"
 	c "github.com/cloudposse/atmos/pkg/config"
+	l "github.com/cloudposse/atmos/pkg/logger"
 	"github.com/cloudposse/atmos/pkg/utils"
-	"github.com/fatih/color"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"os"
 	"path"
+	"strings"
 )
 
 // ExecuteHelmfile executes helmfile commands
 func ExecuteHelmfile(cmd *cobra.Command, args []string) error {
-	info, err := processConfigAndStacks("helmfile", cmd, args)
+	info, client, err := processConfigAndStacks("helmfile", cmd, args)
 	if err != nil {
 		return err
 	}
 
+	cliConfig := client.Config()
+	processedConfig := client.ProcessedConfig()
+
+	// The sensitive values of the component are masked in the logs of the command
+	log := l.Default().WithMask(info.Masker.Text)
+
 	if len(info.Stack) < 1 {
-		return errors.New("stack must be specified")
+		return withExitCode(errors.New("stack must be specified"), ExitCodeInvalidArguments)
 	}
 
-	err = checkHelmfileConfig()
+	err = checkHelmfileConfig(cliConfig)
 	if err != nil {
-		return err
+		return withExitCode(err, ExitCodeInvalidConfig)
 	}
 
 	// Check if the component exists as helmfile component
-	componentPath := path.Join(c.ProcessedConfig.HelmfileDirAbsolutePath, info.ComponentFolderPrefix, info.Component)
+	componentPath := path.Join(processedConfig.HelmfileDirAbsolutePath, info.ComponentFolderPrefix, info.Component)
 	componentPathExists, err := utils.IsDirectory(componentPath)
 	if err != nil || !componentPathExists {
-		return errors.New(fmt.Sprintf("Component '%s' does not exist in %s",
+		return withExitCode(errors.New(fmt.Sprintf("Component '%s' does not exist in %s",
 			info.Component,
-			path.Join(c.ProcessedConfig.HelmfileDirAbsolutePath, info.ComponentFolderPrefix),
-		))
+			path.Join(processedConfig.HelmfileDirAbsolutePath, info.ComponentFolderPrefix),
+		)), ExitCodeInvalidStacks)
 	}
 
 	// Write variables to a file
 	var varFileName string
 	if len(info.ComponentFolderPrefix) == 0 {
 		varFileName = fmt.Sprintf("%s/%s/%s-%s.helmfile.vars.yaml",
-			c.Config.Components.Helmfile.BasePath,
+			cliConfig.Components.Helmfile.BasePath,
 			info.Component,
 			info.ContextPrefix,
 			info.Component,
 		)
 	} else {
 		varFileName = fmt.Sprintf("%s/%s/%s/%s-%s.helmfile.vars.yaml",
-			c.Config.Components.Helmfile.BasePath,
+			cliConfig.Components.Helmfile.BasePath,
 			info.ComponentFolderPrefix,
 			info.Component,
 			info.ContextPrefix,
@@ -58,12 +65,31 @@ func ExecuteHelmfile(cmd *cobra.Command, args []string) error {
 		)
 	}
 
-	color.Cyan("Writing variables to file:")
-	fmt.Println(varFileName)
-	err = utils.WriteToFileAsYAML(varFileName, info.ComponentVarsSection, 0644)
+	// Resolve the secret references in the vars and ENV vars only now, right before running helmfile
+	varsContainSecrets, err := resolveComponentSecrets(&info)
+	if err != nil {
+		return err
+	}
+
+	// Read the outputs of the terraform components referenced by `!terraform.output`
+	varsContainSensitiveOutputs, err := resolveTerraformOutputs(client, cliConfig, &info, log, os.Stderr)
 	if err != nil {
 		return err
 	}
+	varsContainSecrets = varsContainSecrets || varsContainSensitiveOutputs
+
+	log.Info("Writing variables to file:\n%s", varFileName)
+	err = writeVarFile(varFileName, info.ComponentVarsSection, varsContainSecrets, utils.WriteToFileAsYAML)
+	if err != nil {
+		return err
+	}
+
+	// Don't leave the varfile with the secrets if helmfile fails
+	if varsContainSecrets {
+		defer func() {
+			_ = os.Remove(varFileName)
+		}()
+	}
 
 	// Handle `helmfile deploy` custom command
 	if info.SubCommand == "deploy" {
@@ -73,15 +99,15 @@ func ExecuteHelmfile(cmd *cobra.Command, args []string) error {
 	context := c.GetContextFromVars(info.ComponentVarsSection)
 
 	// Prepare AWS profile
-	helmAwsProfile := c.ReplaceContextTokens(context, c.Config.Components.Helmfile.HelmAwsProfilePattern)
-	color.Cyan(fmt.Sprintf("\nUsing AWS_PROFILE=%s\n\n", helmAwsProfile))
+	helmAwsProfile := c.ReplaceContextTokens(context, cliConfig.Components.Helmfile.HelmAwsProfilePattern)
+	log.Info("\nUsing AWS_PROFILE=%s\n", helmAwsProfile)
 
 	// Download kubeconfig by running `aws eks update-kubeconfig`
-	kubeconfigPath := fmt.Sprintf("%s/%s-kubecfg", c.Config.Components.Helmfile.KubeconfigPath, info.ContextPrefix)
-	clusterName := c.ReplaceContextTokens(context, c.Config.Components.Helmfile.ClusterNamePattern)
-	color.Cyan(fmt.Sprintf("Downloading kubeconfig from the cluster '%s' and saving it to %s\n\n", clusterName, kubeconfigPath))
+	kubeconfigPath := fmt.Sprintf("%s/%s-kubecfg", cliConfig.Components.Helmfile.KubeconfigPath, info.ContextPrefix)
+	clusterName := c.ReplaceContextTokens(context, cliConfig.Components.Helmfile.ClusterNamePattern)
+	log.Info("Downloading kubeconfig from the cluster '%s' and saving it to %s\n", clusterName, kubeconfigPath)
 
-	err = execCommand("aws",
+	err = execCommand(log, cliConfig.CI, "aws",
 		[]string{
 			"--profile",
 			helmAwsProfile,
@@ -99,31 +125,32 @@ func ExecuteHelmfile(cmd *cobra.Command, args []string) error {
 	}
 
 	// Print command info
-	color.Cyan("\nCommand info:")
-	fmt.Println("Helmfile binary: " + info.Command)
-	fmt.Println("Helmfile command: " + info.SubCommand)
+	commandInfo := "\nCommand info:\n" +
+		"Helmfile binary: " + info.Command + "\n" +
+		"Helmfile command: " + info.SubCommand + "\n"
 
 	// https://github.com/roboll/helmfile#cli-reference
 	// atmos helmfile diff echo-server -s tenant1-ue2-dev --global-options "--no-color --namespace=test"
 	// atmos helmfile diff echo-server -s tenant1-ue2-dev --global-options "--no-color --namespace test"
 	// atmos helmfile diff echo-server -s tenant1-ue2-dev --global-options="--no-color --namespace=test"
 	// atmos helmfile diff echo-server -s tenant1-ue2-dev --global-options="--no-color --namespace test"
-	fmt.Println(fmt.Sprintf("Global options: %v", info.GlobalOptions))
+	commandInfo += fmt.Sprintf("Global options: %v\n", info.GlobalOptions)
 
-	fmt.Println(fmt.Sprintf("Arguments and flags: %v", info.AdditionalArgsAndFlags))
-	fmt.Println("Component: " + info.ComponentFromArg)
+	commandInfo += fmt.Sprintf("Arguments and flags: %v\n", info.AdditionalArgsAndFlags) +
+		"Component: " + info.ComponentFromArg + "\n"
 	if len(info.BaseComponent) > 0 {
-		fmt.Println("Base component: " + info.BaseComponent)
+		commandInfo += "Base component: " + info.BaseComponent + "\n"
 	}
-	fmt.Println("Stack: " + info.Stack)
+	commandInfo += "Stack: " + info.Stack + "\n"
 
 	var workingDir string
 	if len(info.ComponentFolderPrefix) == 0 {
-		workingDir = path.Join(c.Config.Components.Helmfile.BasePath, info.Component)
+		workingDir = path.Join(cliConfig.Components.Helmfile.BasePath, info.Component)
 	} else {
-		workingDir = path.Join(c.Config.Components.Helmfile.BasePath, info.ComponentFolderPrefix, info.Component)
+		workingDir = path.Join(cliConfig.Components.Helmfile.BasePath, info.ComponentFolderPrefix, info.Component)
 	}
-	fmt.Println(fmt.Sprintf("Working dir: %s\n\n", workingDir))
+	commandInfo += fmt.Sprintf("Working dir: %s\n", workingDir)
+	log.Info(commandInfo)
 
 	varFile := fmt.Sprintf("%s-%s.helmfile.vars.yaml", info.ContextPrefix, info.Component)
 
@@ -147,12 +174,9 @@ func ExecuteHelmfile(cmd *cobra.Command, args []string) error {
 		fmt.Sprintf("STACK=%s", info.Stack),
 	}...)
 
-	color.Cyan("Using ENV vars:\n")
-	for _, v := range envVars {
-		fmt.Println(v)
-	}
+	log.Info("Using ENV vars:\n%s", strings.Join(envVars, "\n"))
 
-	err = execCommand(info.Command, allArgsAndFlags, componentPath, envVars)
+	err = execCommand(log, cliConfig.CI, info.Command, allArgsAndFlags, componentPath, envVars)
 	if err != nil {
 		return err
 	}
@@ -160,29 +184,29 @@ func ExecuteHelmfile(cmd *cobra.Command, args []string) error {
 	// Cleanup
 	err = os.Remove(varFileName)
 	if err != nil {
-		color.Yellow("Error deleting helmfile varfile: %s\n", err)
+		log.Warn("Error deleting helmfile varfile: %s\n", err)
 	}
 
 	return nil
 }
 
-func checkHelmfileConfig() error {
-	if len(c.Config.Components.Helmfile.BasePath) < 1 {
+func checkHelmfileConfig(cliConfig c.Configuration) error {
+	if len(cliConfig.Components.Helmfile.BasePath) < 1 {
 		return errors.New("Base path to helmfile components must be provided in 'components.helmfile.base_path' config or " +
 			"'ATMOS_COMPONENTS_HELMFILE_BASE_PATH' ENV variable")
 	}
 
-	if len(c.Config.Components.Helmfile.KubeconfigPath) < 1 {
+	if len(cliConfig.Components.Helmfile.KubeconfigPath) < 1 {
 		return errors.New("Kubeconfig path must be provided in 'components.helmfile.kubeconfig_path' config or " +
 			"'ATMOS_COMPONENTS_HELMFILE_KUBECONFIG_PATH' ENV variable")
 	}
 
-	if len(c.Config.Components.Helmfile.HelmAwsProfilePattern) < 1 {
+	if len(cliConfig.Components.Helmfile.HelmAwsProfilePattern) < 1 {
 		return errors.New("Helm AWS profile pattern must be provided in 'components.helmfile.helm_aws_profile_pattern' config or " +
 			"'ATMOS_COMPONENTS_HELMFILE_HELM_AWS_PROFILE_PATTERN' ENV variable")
 	}
 
-	if len(c.Config.Components.Helmfile.ClusterNamePattern) < 1 {
+	if len(cliConfig.Components.Helmfile.ClusterNamePattern) < 1 {
 		return errors.New("Cluster name pattern must be provided in 'components.helmfile.cluster_name_pattern' config or " +
 			"'ATMOS_COMPONENTS_HELMFILE_CLUSTER_NAME_PATTERN' ENV variable")
 	}
@@ -0,0 +1,29 @@
+package exec
+
+import (
+	"fmt"
+
+	g "github.com/cloudposse/atmos/pkg/globals"
+	l "github.com/cloudposse/atmos/pkg/logger"
+	"github.com/pkg/errors"
+)
+
+// ProcessLogLevelFlags sets the level of the atmos logs from the `--log-level` and `--quiet` flags.
+// The flags take precedence over the `logs.level` config and the `ATMOS_LOGS_LEVEL` ENV var.
+// `--quiet` prints only the errors, the output of the commands (e.g. `describe` or `terraform plan`) is not affected
+func ProcessLogLevelFlags(logLevel string, quiet bool) error {
+	if quiet {
+		l.SetLevelOverride(l.LevelError)
+		return nil
+	}
+
+	if len(logLevel) > 0 {
+		level, err := l.ParseLevel(logLevel)
+		if err != nil {
+			return withExitCode(errors.New(fmt.Sprintf("invalid '%s' flag: %s", g.LogLevelFlag, err)), ExitCodeInvalidArguments)
+		}
+		l.SetLevelOverride(level)
+	}
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package exec
+
+import (
+	c "github.com/cloudposse/atmos/pkg/config"
+	"github.com/cloudposse/atmos/pkg/mask"
+)
+
+// newComponentMasker creates a masker for the sensitive values of the component: the values of the vars and ENV vars matching
+// the `masking.key_patterns` CLI config or the keys in the `settings.sensitive` section of the component
+func newComponentMasker(cliConfig c.Configuration, settings map[string]interface{}, vars map[string]interface{}, env map[string]interface{}) (*mask.Masker, error) {
+	masker, err := mask.New(cliConfig.Masking.KeyPatterns...)
+	if err != nil {
+		return nil, withExitCode(err, ExitCodeInvalidConfig)
+	}
+
+	err = masker.AddPatterns(mask.SensitiveKeys(settings)...)
+	if err != nil {
+		return nil, withExitCode(err, ExitCodeInvalidStacks)
+	}
+
+	// Remember the sensitive values to mask them in the printed commands and messages
+	masker.Map(vars)
+	masker.Map(env)
+
+	return masker, nil
+}
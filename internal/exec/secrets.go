@@ -0,0 +1,48 @@
+package exec
+
+import (
+	"os"
+
+	c "github.com/cloudposse/atmos/pkg/config"
+	"github.com/cloudposse/atmos/pkg/secrets"
+)
+
+// resolveComponentSecrets resolves the `!secret` references in the vars and ENV vars of the component.
+// The resolved values are added to the masker of the component to mask them in the logs.
+// Returns `true` if any of the vars is a secret (the varfile must then be protected)
+func resolveComponentSecrets(info *c.ConfigAndStacksInfo) (bool, error) {
+	vars, varsSecrets, err := secrets.ResolveMap(info.ComponentVarsSection)
+	if err != nil {
+		return false, err
+	}
+
+	env, envSecrets, err := secrets.ResolveEnvList(info.ComponentEnvList)
+	if err != nil {
+		return false, err
+	}
+
+	if info.Masker != nil {
+		info.Masker.AddValues(varsSecrets...)
+		info.Masker.AddValues(envSecrets...)
+	}
+
+	info.ComponentVarsSection = vars
+	info.ComponentEnvList = env
+
+	return len(varsSecrets) > 0, nil
+}
+
+// writeVarFile writes the vars of the component to the varfile using the provided function (JSON or YAML).
+// The varfile with the secrets is readable only by the current user
+func writeVarFile(varFileName string, vars map[string]interface{}, containsSecrets bool, write func(string, interface{}, os.FileMode) error) error {
+	if !containsSecrets {
+		return write(varFileName, vars, 0644)
+	}
+
+	// The permissions of an existing file are not changed when writing it
+	err := os.Remove(varFileName)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return write(varFileName, vars, 0600)
+}
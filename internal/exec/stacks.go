@@ -0,0 +1,92 @@
+package exec
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	c "github.com/cloudposse/atmos/pkg/config"
+	l "github.com/cloudposse/atmos/pkg/logger"
+	s "github.com/cloudposse/atmos/pkg/stack"
+	u "github.com/cloudposse/atmos/pkg/utils"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// ExecuteStacksLock executes `stacks lock` command
+func ExecuteStacksLock(cmd *cobra.Command, args []string) error {
+	lockFile, currentLock, err := processStacksLock(cmd)
+	if err != nil {
+		return err
+	}
+
+	err = u.WriteToFileAsYAML(lockFile, currentLock, 0644)
+	if err != nil {
+		return err
+	}
+
+	l.Info("Wrote the resolved imports of %d stacks to '%s'\n", len(currentLock.Stacks), lockFile)
+	return nil
+}
+
+// ExecuteStacksVerify executes `stacks verify` command
+func ExecuteStacksVerify(cmd *cobra.Command, args []string) error {
+	lockFile, currentLock, err := processStacksLock(cmd)
+	if err != nil {
+		return err
+	}
+
+	content, err := ioutil.ReadFile(lockFile)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Could not read the lock file '%s': %s\nRun 'atmos stacks lock' to create it", lockFile, err))
+	}
+
+	var lock s.StacksLock
+	err = yaml.Unmarshal(content, &lock)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Invalid lock file '%s': %s", lockFile, err))
+	}
+
+	diffs := lock.Verify(currentLock)
+	if len(diffs) > 0 {
+		return withExitCode(errors.New(fmt.Sprintf("The resolved imports do not match the lock file '%s':\n\n%s\n\n"+
+			"Run 'atmos stacks lock' to update the lock file if the changes are expected",
+			lockFile,
+			strings.Join(diffs, "\n"))), ExitCodeValidationFailed)
+	}
+
+	l.Info("The resolved imports of %d stacks match the lock file '%s'\n", len(currentLock.Stacks), lockFile)
+	return nil
+}
+
+// processStacksLock processes all stacks and returns the lock file path and the current resolved imports
+func processStacksLock(cmd *cobra.Command) (string, s.StacksLock, error) {
+	flags := cmd.Flags()
+
+	lockFile, err := flags.GetString("file")
+	if err != nil {
+		return "", s.StacksLock{}, err
+	}
+
+	stacksArchive, err := flags.GetString("stacks-archive")
+	if err != nil {
+		return "", s.StacksLock{}, err
+	}
+
+	var configAndStacksInfo c.ConfigAndStacksInfo
+	configAndStacksInfo.CI = ciFlag(cmd)
+	configAndStacksInfo.StacksArchive = stacksArchive
+
+	client, err := newClient(configAndStacksInfo)
+	if err != nil {
+		return "", s.StacksLock{}, err
+	}
+
+	lock, err := client.Lock()
+	if err != nil {
+		return "", s.StacksLock{}, err
+	}
+
+	return lockFile, lock, nil
+}
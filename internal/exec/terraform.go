@@ -2,34 +2,80 @@ package exec
 
 import (
 	"fmt"
+	"github.com/cloudposse/atmos/pkg/atmos"
 	c "github.com/cloudposse/atmos/pkg/config"
+	l "github.com/cloudposse/atmos/pkg/logger"
+	"github.com/cloudposse/atmos/pkg/terraform"
 	"github.com/cloudposse/atmos/pkg/utils"
-	"github.com/fatih/color"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"io"
+	"io/ioutil"
 	"os"
 	"path"
 	"strings"
 )
 
 const (
-	autoApproveFlag = "-auto-approve"
+	autoApproveFlag           = "-auto-approve"
+	detailedExitCodeFlag      = "-detailed-exitcode"
+	inputFalseFlag            = "-input=false"
+	providersOverrideFileName = "providers_override.tf.json"
 )
 
 // ExecuteTerraform executes terraform commands
 func ExecuteTerraform(cmd *cobra.Command, args []string) error {
-	info, err := processConfigAndStacks("terraform", cmd, args)
+	info, client, err := processConfigAndStacks("terraform", cmd, args)
 	if err != nil {
 		return err
 	}
 
+	// The sensitive values of the component are masked in the logs of the command
+	return executeTerraform(client, client.Config(), info, l.Default().WithMask(info.Masker.Text), os.Stdin, os.Stdout, os.Stderr)
+}
+
+// executeTerraform executes the terraform command for the component in the stack.
+// The CLI config can differ from the client config (e.g. `terraform drift` enables the working dirs to run the components in parallel).
+// The atmos logs are written to the logger, the output of terraform to `out`, and the errors of terraform to `stderr`
+func executeTerraform(
+	client *atmos.Client,
+	cliConfig c.Configuration,
+	info c.ConfigAndStacksInfo,
+	log *l.Logger,
+	stdin io.Reader,
+	out io.Writer,
+	stderr io.Writer,
+) error {
+	processedConfig := client.ProcessedConfig()
+
 	if len(info.Stack) < 1 {
-		return errors.New("stack must be specified")
+		return withExitCode(errors.New("stack must be specified"), ExitCodeInvalidArguments)
 	}
 
-	err = checkTerraformConfig()
+	err := checkTerraformConfig(cliConfig)
 	if err != nil {
-		return err
+		return withExitCode(err, ExitCodeInvalidConfig)
+	}
+
+	// Fail before running any commands if the terraform command requires a user interaction,
+	// but it's running in a scripted environment (where a `tty` is not attached to `stdin`, or in the CI mode)
+	if !isInteractive(cliConfig) {
+		err = checkTerraformNonInteractive(cliConfig, info)
+		if err != nil {
+			return withExitCode(err, ExitCodeInvalidArguments)
+		}
+	}
+
+	// Refuse to apply the changes if the component violates a `deny` policy
+	if info.SubCommand == "apply" || info.SubCommand == "deploy" {
+		component, err := client.FindComponent(info.Stack, "terraform", info.ComponentFromArg)
+		if err != nil {
+			return err
+		}
+		err = checkComponentPolicies(client, component)
+		if err != nil {
+			return withExitCode(err, ExitCodeValidationFailed)
+		}
 	}
 
 	var finalComponent string
@@ -41,51 +87,117 @@ func ExecuteTerraform(cmd *cobra.Command, args []string) error {
 	}
 
 	// Check if the component exists as Terraform component
-	componentPath := path.Join(c.ProcessedConfig.TerraformDirAbsolutePath, info.ComponentFolderPrefix, finalComponent)
+	componentPath := path.Join(processedConfig.TerraformDirAbsolutePath, info.ComponentFolderPrefix, finalComponent)
 	componentPathExists, err := utils.IsDirectory(componentPath)
 	if err != nil || !componentPathExists {
-		return errors.New(fmt.Sprintf("Component '%s' does not exist in %s",
+		return withExitCode(errors.New(fmt.Sprintf("Component '%s' does not exist in %s",
 			finalComponent,
-			path.Join(c.ProcessedConfig.TerraformDirAbsolutePath, info.ComponentFolderPrefix),
-		))
+			path.Join(processedConfig.TerraformDirAbsolutePath, info.ComponentFolderPrefix),
+		)), ExitCodeInvalidStacks)
 	}
 
 	varFile := fmt.Sprintf("%s-%s.terraform.tfvars.json", info.ContextPrefix, info.Component)
 	planFile := fmt.Sprintf("%s-%s.planfile", info.ContextPrefix, info.Component)
 
+	// The folder to write the generated files to and to run terraform in
+	workingDir := path.Join(cliConfig.Components.Terraform.BasePath, info.ComponentFolderPrefix, finalComponent)
+
+	var componentWorkingDir string
+	if cliConfig.Components.Terraform.WorkingDirs.Enabled {
+		componentWorkingDir, err = terraformWorkingDir(cliConfig, info)
+		if err != nil {
+			return err
+		}
+	}
+
+	if info.SubCommand == "clean" && len(componentWorkingDir) > 0 {
+		log.Info("Deleting working dir: %s\n", componentWorkingDir)
+		_ = os.RemoveAll(componentWorkingDir)
+		return nil
+	}
+
 	if info.SubCommand == "clean" {
-		fmt.Println("Deleting '.terraform' folder")
+		log.Info("Deleting '.terraform' folder")
 		_ = os.RemoveAll(path.Join(componentPath, ".terraform"))
 
-		fmt.Println("Deleting '.terraform.lock.hcl' file")
+		log.Info("Deleting '.terraform.lock.hcl' file")
 		_ = os.Remove(path.Join(componentPath, ".terraform.lock.hcl"))
 
-		fmt.Println(fmt.Sprintf("Deleting terraform varfile: %s", varFile))
+		log.Info("Deleting terraform varfile: %s", varFile)
 		_ = os.Remove(path.Join(componentPath, varFile))
 
-		fmt.Println(fmt.Sprintf("Deleting terraform planfile: %s", planFile))
+		log.Info("Deleting terraform planfile: %s", planFile)
 		_ = os.Remove(path.Join(componentPath, planFile))
 
 		tfDataDir := os.Getenv("TF_DATA_DIR")
-		if len(tfDataDir) > 0 && tfDataDir != "." && tfDataDir != "/" && tfDataDir != "./" {
-			color.Cyan("Found ENV var TF_DATA_DIR=%s", tfDataDir)
+		if len(tfDataDir) > 0 && tfDataDir != "." && tfDataDir != "/" && tfDataDir != "./" && !isInteractive(cliConfig) {
+			log.Warn("Found ENV var TF_DATA_DIR=%s. Not deleting the folder since atmos is running non-interactively", tfDataDir)
+		} else if len(tfDataDir) > 0 && tfDataDir != "." && tfDataDir != "/" && tfDataDir != "./" {
+			log.Info("Found ENV var TF_DATA_DIR=%s", tfDataDir)
 			var userAnswer string
-			fmt.Println(fmt.Sprintf("Do you want to delete the folder '%s'? (only 'yes' will be accepted to approve)", tfDataDir))
-			fmt.Print("Enter a value: ")
-			count, err := fmt.Scanln(&userAnswer)
+			fmt.Fprintln(out, fmt.Sprintf("Do you want to delete the folder '%s'? (only 'yes' will be accepted to approve)", tfDataDir))
+			fmt.Fprint(out, "Enter a value: ")
+			count, err := fmt.Fscanln(stdin, &userAnswer)
 			if count > 0 && err != nil {
 				return err
 			}
 			if userAnswer == "yes" {
-				fmt.Println(fmt.Sprintf("Deleting folder '%s'", tfDataDir))
+				log.Info("Deleting folder '%s'", tfDataDir)
 				_ = os.RemoveAll(tfDataDir)
 			}
 		}
 
-		fmt.Println()
 		return nil
 	}
 
+	// Resolve the secret references in the vars and ENV vars only now, right before running terraform
+	varsContainSecrets, err := resolveComponentSecrets(&info)
+	if err != nil {
+		return err
+	}
+
+	// Read the outputs of the referenced components. `terraform output` does not use the vars, so the references are not resolved for it
+	if info.SubCommand != "output" {
+		varsContainSensitiveOutputs, err := resolveTerraformOutputs(client, cliConfig, &info, log, stderr)
+		if err != nil {
+			return err
+		}
+		varsContainSecrets = varsContainSecrets || varsContainSensitiveOutputs
+	}
+
+	if len(componentWorkingDir) > 0 {
+		componentPath, err = prepareTerraformWorkingDir(
+			cliConfig,
+			processedConfig.TerraformDirAbsolutePath,
+			path.Join(info.ComponentFolderPrefix, finalComponent),
+			componentWorkingDir,
+		)
+		if err != nil {
+			return err
+		}
+		workingDir = componentPath
+
+		if cliConfig.Components.Terraform.WorkingDirs.Cleanup && info.SubCommand != "varfile" && info.SubCommand != "write varfile" {
+			defer func() {
+				_ = os.RemoveAll(componentWorkingDir)
+			}()
+		}
+
+		// Keep the providers and modules downloaded by `terraform init` in the working dir, unless `TF_DATA_DIR` is set in the stacks
+		if _, ok := info.ComponentEnvSection["TF_DATA_DIR"]; !ok {
+			info.ComponentEnvList = append(info.ComponentEnvList, fmt.Sprintf("TF_DATA_DIR=%s", path.Join(componentPath, terraformDataDirName)))
+		}
+	}
+
+	// Handle `terraform show-plan` custom command
+	if info.SubCommand == "show-plan" {
+		if !utils.FileExists(path.Join(componentPath, planFile)) {
+			return errors.New(fmt.Sprintf("The planfile '%s' does not exist in '%s'. Run 'atmos terraform plan %s -s %s' first",
+				planFile, componentPath, info.ComponentFromArg, info.ContextPrefix))
+		}
+		return showTerraformPlanSummary(info, componentPath, planFile, log, out)
+	}
+
 	// Write variables to a file
 	var varFileName, varFileNameFromArg string
 
@@ -102,55 +214,31 @@ func ExecuteTerraform(cmd *cobra.Command, args []string) error {
 	if len(varFileNameFromArg) > 0 {
 		varFileName = varFileNameFromArg
 	} else {
-		if len(info.ComponentFolderPrefix) == 0 {
-			varFileName = path.Join(
-				c.Config.Components.Terraform.BasePath,
-				finalComponent,
-				varFile,
-			)
-		} else {
-			varFileName = path.Join(
-				c.Config.Components.Terraform.BasePath,
-				info.ComponentFolderPrefix,
-				finalComponent,
-				varFile,
-			)
-		}
+		varFileName = path.Join(workingDir, varFile)
 	}
 
-	color.Cyan("Writing variables to file:")
-	fmt.Println(varFileName)
-	err = utils.WriteToFileAsJSON(varFileName, info.ComponentVarsSection, 0644)
+	log.Info("Writing variables to file:\n%s", varFileName)
+	err = writeVarFile(varFileName, info.ComponentVarsSection, varsContainSecrets, utils.WriteToFileAsJSON)
 	if err != nil {
 		return err
 	}
 
+	// The varfile with the secrets is deleted after the run (but it's kept by `terraform varfile` and `terraform write varfile`)
+	if varsContainSecrets && info.SubCommand != "varfile" && info.SubCommand != "write varfile" {
+		defer func() {
+			_ = os.Remove(varFileName)
+		}()
+	}
+
 	// Handle `terraform varfile` and `terraform write varfile` custom commands
 	if info.SubCommand == "varfile" || info.SubCommand == "write varfile" {
-		fmt.Println()
 		return nil
 	}
 
 	// Auto generate backend file
-	if c.Config.Components.Terraform.AutoGenerateBackendFile == true {
-		var backendFileName string
-		fmt.Println()
-		if len(info.ComponentFolderPrefix) == 0 {
-			backendFileName = path.Join(
-				c.Config.Components.Terraform.BasePath,
-				finalComponent,
-				"backend.tf.json",
-			)
-		} else {
-			backendFileName = path.Join(
-				c.Config.Components.Terraform.BasePath,
-				info.ComponentFolderPrefix,
-				finalComponent,
-				"backend.tf.json",
-			)
-		}
-		color.Cyan("Writing backend config to file:")
-		fmt.Println(backendFileName)
+	if cliConfig.Components.Terraform.AutoGenerateBackendFile == true {
+		backendFileName := path.Join(workingDir, "backend.tf.json")
+		log.Info("\nWriting backend config to file:\n%s", backendFileName)
 		var componentBackendConfig = generateComponentBackendConfig(info.ComponentBackendType, info.ComponentBackendSection)
 		err = utils.WriteToFileAsJSON(backendFileName, componentBackendConfig, 0644)
 		if err != nil {
@@ -158,11 +246,29 @@ func ExecuteTerraform(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Generate provider overrides from the `providers` section
+	if len(info.ComponentProvidersSection) > 0 {
+		providersFileName := path.Join(workingDir, providersOverrideFileName)
+		log.Info("\nWriting provider overrides to file:\n%s", providersFileName)
+		var componentProviderOverrides = generateComponentProviderOverrides(info.ComponentProvidersSection)
+		err = utils.WriteToFileAsJSON(providersFileName, componentProviderOverrides, 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The output of `terraform output` is the only output written to `out`, so it can be parsed.
+	// The output of `terraform init` and `terraform workspace` is then written to `stderr`
+	setupOut := out
+	if info.SubCommand == "output" {
+		setupOut = stderr
+	}
+
 	// Run `terraform init`
 	runTerraformInit := true
 	if info.SubCommand == "init" ||
 		info.SubCommand == "clean" ||
-		(info.SubCommand == "deploy" && c.Config.Components.Terraform.DeployRunInit == false) {
+		(info.SubCommand == "deploy" && cliConfig.Components.Terraform.DeployRunInit == false) {
 		runTerraformInit = false
 	}
 	if runTerraformInit == true {
@@ -170,7 +276,10 @@ func ExecuteTerraform(cmd *cobra.Command, args []string) error {
 		if info.SubCommand == "workspace" {
 			initCommandWithArguments = []string{"init", "-reconfigure"}
 		}
-		err = execCommand(info.Command, initCommandWithArguments, componentPath, info.ComponentEnvList)
+		if cliConfig.CI {
+			initCommandWithArguments = append(initCommandWithArguments, inputFalseFlag)
+		}
+		err = execCommandWithIO(log, cliConfig.CI, info.Command, initCommandWithArguments, componentPath, info.ComponentEnvList, stdin, setupOut, stderr)
 		if err != nil {
 			return err
 		}
@@ -185,49 +294,34 @@ func ExecuteTerraform(cmd *cobra.Command, args []string) error {
 	}
 
 	// Handle Config.Components.Terraform.ApplyAutoApprove flag
-	if info.SubCommand == "apply" && c.Config.Components.Terraform.ApplyAutoApprove == true && info.UseTerraformPlan == false {
+	if info.SubCommand == "apply" && cliConfig.Components.Terraform.ApplyAutoApprove == true && info.UseTerraformPlan == false {
 		if !utils.SliceContainsString(info.AdditionalArgsAndFlags, autoApproveFlag) {
 			info.AdditionalArgsAndFlags = append(info.AdditionalArgsAndFlags, autoApproveFlag)
 		}
 	}
 
 	// Print command info
-	color.Cyan("\nCommand info:")
-	fmt.Println("Terraform binary: " + info.Command)
-	fmt.Println("Terraform command: " + info.SubCommand)
-	fmt.Println(fmt.Sprintf("Arguments and flags: %v", info.AdditionalArgsAndFlags))
-	fmt.Println("Component: " + info.ComponentFromArg)
+	commandInfo := "\nCommand info:\n" +
+		"Terraform binary: " + info.Command + "\n" +
+		"Terraform command: " + info.SubCommand + "\n" +
+		fmt.Sprintf("Arguments and flags: %v\n", info.AdditionalArgsAndFlags) +
+		"Component: " + info.ComponentFromArg + "\n"
 	if len(info.BaseComponentPath) > 0 {
-		fmt.Println("Base component: " + info.BaseComponentPath)
+		commandInfo += "Base component: " + info.BaseComponentPath + "\n"
 	}
 	if len(info.ComponentInheritanceChain) > 0 {
-		fmt.Println("Inheritance: " + info.ComponentFromArg + " -> " + strings.Join(info.ComponentInheritanceChain, " -> "))
-	}
-	fmt.Println("Stack: " + info.Stack)
-
-	var workingDir string
-	if len(info.ComponentFolderPrefix) == 0 {
-		workingDir = path.Join(c.Config.Components.Terraform.BasePath, finalComponent)
-	} else {
-		workingDir = path.Join(c.Config.Components.Terraform.BasePath, info.ComponentFolderPrefix, finalComponent)
+		commandInfo += "Inheritance: " + info.ComponentFromArg + " -> " + strings.Join(info.ComponentInheritanceChain, " -> ") + "\n"
 	}
-	fmt.Println(fmt.Sprintf(fmt.Sprintf("Working dir: %s", workingDir)))
+	commandInfo += "Stack: " + info.Stack + "\n" +
+		fmt.Sprintf("Working dir: %s", workingDir)
+	log.Info(commandInfo)
 
 	// Print ENV vars if they are found in the component stack config
 	if len(info.ComponentEnvList) > 0 {
-		fmt.Println()
-		color.Cyan("Using ENV vars:\n")
-		for _, v := range info.ComponentEnvList {
-			fmt.Println(v)
-		}
+		log.Info("\nUsing ENV vars:\n%s", strings.Join(info.ComponentEnvList, "\n"))
 	}
 
-	var workspaceName string
-	if len(info.BaseComponent) > 0 {
-		workspaceName = fmt.Sprintf("%s-%s", info.ContextPrefix, info.Component)
-	} else {
-		workspaceName = info.ContextPrefix
-	}
+	workspaceName := info.TerraformWorkspace
 
 	allArgsAndFlags := []string{info.SubCommand}
 
@@ -252,34 +346,59 @@ func ExecuteTerraform(cmd *cobra.Command, args []string) error {
 
 	allArgsAndFlags = append(allArgsAndFlags, info.AdditionalArgsAndFlags...)
 
+	// Don't let terraform ask for the values of the variables in the CI mode.
+	// The flag is added before the planfile, since terraform does not parse the flags after the positional arguments
+	if cliConfig.CI && utils.SliceContainsString(terraformCommandsWithInputFlag, info.SubCommand) && !hasInputFlag(info.AdditionalArgsAndFlags) {
+		allArgsAndFlags = append([]string{info.SubCommand, inputFalseFlag}, allArgsAndFlags[1:]...)
+	}
+
 	// Run `terraform workspace`
-	err = execCommand(info.Command, []string{"workspace", "select", workspaceName}, componentPath, info.ComponentEnvList)
+	err = execCommandWithIO(log, cliConfig.CI, info.Command, []string{"workspace", "select", workspaceName}, componentPath, info.ComponentEnvList, stdin, setupOut, stderr)
 	if err != nil {
-		err = execCommand(info.Command, []string{"workspace", "new", workspaceName}, componentPath, info.ComponentEnvList)
+		err = execCommandWithIO(log, cliConfig.CI, info.Command, []string{"workspace", "new", workspaceName}, componentPath, info.ComponentEnvList, stdin, setupOut, stderr)
 		if err != nil {
 			return err
 		}
 	}
 
-	// Check if the terraform command requires a user interaction,
-	// but it's running in a scripted environment (where a `tty` is not attached or `stdin` is not attached)
-	if os.Stdin == nil && !utils.SliceContainsString(info.AdditionalArgsAndFlags, autoApproveFlag) {
-		errorMessage := ""
-		if info.SubCommand == "apply" {
-			errorMessage = "'terraform apply' requires a user interaction, but it's running without `tty` or `stdin` attached." +
-				"\nUse 'terraform apply -auto-approve' or 'terraform deploy' instead."
-		} else if info.SubCommand == "destroy" {
-			errorMessage = "'terraform destroy' requires a user interaction, but it's running without `tty` or `stdin` attached." +
-				"\nUse 'terraform destroy -auto-approve' if you need to destroy resources without asking the user for confirmation."
-		}
-		if errorMessage != "" {
-			return errors.New(errorMessage)
+	// Handle `terraform output`. The command is executed without the CI group markers, so its output can be parsed
+	if info.SubCommand == "output" {
+		output, err := execCommandOutput(log, info.Command, allArgsAndFlags, componentPath, info.ComponentEnvList, stderr)
+		if err != nil {
+			return err
 		}
+		_, err = out.Write(output)
+		return err
 	}
 
-	// Execute the command
+	// Execute the command.
+	// `terraform plan -detailed-exitcode` exits with 2 if the plan has changes. Then the plan summary is printed, and the exit code is returned
+	// (the error is not logged, since the changes are not a failure)
+	var planChangesErr error
 	if info.SubCommand != "workspace" {
-		err = execCommand(info.Command, allArgsAndFlags, componentPath, info.ComponentEnvList)
+		err = execCommandWithIO(log, cliConfig.CI, info.Command, allArgsAndFlags, componentPath, info.ComponentEnvList, stdin, out, stderr)
+		if err != nil {
+			if info.SubCommand != "plan" ||
+				!utils.SliceContainsString(info.AdditionalArgsAndFlags, detailedExitCodeFlag) ||
+				ExitCode(err) != terraform.PlanExitCodeChanges {
+				return err
+			}
+			planChangesErr = silentError(err)
+		}
+	}
+
+	// The planfile has the values of the variables. If they contain secrets, the planfile is protected in the same way as the varfile.
+	// It's not deleted, since it's used by `terraform apply` with `--from-plan`
+	if info.SubCommand == "plan" && varsContainSecrets {
+		err = os.Chmod(path.Join(componentPath, planFile), 0600)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	// Print the summary of the plan
+	if info.SubCommand == "plan" && (info.PlanSummary || len(info.PlanSummaryFile) > 0) {
+		err = showTerraformPlanSummary(info, componentPath, planFile, log, out)
 		if err != nil {
 			return err
 		}
@@ -291,11 +410,71 @@ func ExecuteTerraform(cmd *cobra.Command, args []string) error {
 		_ = os.Remove(planFilePath)
 	}
 
+	return planChangesErr
+}
+
+// showTerraformPlanSummary prints the summary of the resource changes in the planfile,
+// and writes it to the `--summary-file` file (in Markdown if the file has the `.md` extension, in JSON otherwise)
+func showTerraformPlanSummary(info c.ConfigAndStacksInfo, componentPath string, planFile string, log *l.Logger, out io.Writer) error {
+	summary, err := terraform.ShowPlan(info.Command, componentPath, planFile, info.ComponentEnvList)
+	if err != nil {
+		return err
+	}
+	summary.Component = info.ComponentFromArg
+	summary.Stack = info.ContextPrefix
+
+	log.Info("\nPlan summary for '%s' in '%s':\n", summary.Component, summary.Stack)
+	fmt.Fprint(out, summary.Table())
+
+	if len(info.PlanSummaryFile) == 0 {
+		return nil
+	}
+
+	log.Info("\nWriting plan summary to file:\n%s", info.PlanSummaryFile)
+
+	ext := strings.ToLower(path.Ext(info.PlanSummaryFile))
+	if ext == ".md" || ext == ".markdown" {
+		return ioutil.WriteFile(info.PlanSummaryFile, []byte(summary.Markdown()), 0644)
+	}
+	return utils.WriteToFileAsJSON(info.PlanSummaryFile, summary, 0644)
+}
+
+// checkTerraformNonInteractive checks that the terraform command does not ask the user for the approval.
+// `terraform apply` with a planfile, `terraform deploy`, and `terraform apply` with `components.terraform.apply_auto_approve` don't ask for it
+func checkTerraformNonInteractive(cliConfig c.Configuration, info c.ConfigAndStacksInfo) error {
+	if utils.SliceContainsString(info.AdditionalArgsAndFlags, autoApproveFlag) {
+		return nil
+	}
+
+	if info.SubCommand == "apply" && !info.UseTerraformPlan && !cliConfig.Components.Terraform.ApplyAutoApprove {
+		return errors.New("'terraform apply' requires a user interaction, but atmos is running non-interactively " +
+			"(stdin is not a terminal, or the CI mode is enabled).\nUse 'terraform apply -auto-approve' or 'terraform deploy' instead.")
+	}
+
+	if info.SubCommand == "destroy" {
+		return errors.New("'terraform destroy' requires a user interaction, but atmos is running non-interactively " +
+			"(stdin is not a terminal, or the CI mode is enabled).\n" +
+			"Use 'terraform destroy -auto-approve' if you need to destroy resources without asking the user for confirmation.")
+	}
+
 	return nil
 }
 
-func checkTerraformConfig() error {
-	if len(c.Config.Components.Terraform.BasePath) < 1 {
+// The terraform commands which accept the `-input` flag
+var terraformCommandsWithInputFlag = []string{"apply", "destroy", "import", "plan", "refresh"}
+
+// hasInputFlag checks if the `-input` flag is in the arguments
+func hasInputFlag(args []string) bool {
+	for _, arg := range args {
+		if arg == "-input" || strings.HasPrefix(arg, "-input=") {
+			return true
+		}
+	}
+	return false
+}
+
+func checkTerraformConfig(cliConfig c.Configuration) error {
+	if len(cliConfig.Components.Terraform.BasePath) < 1 {
 		return errors.New("Base path to terraform components must be provided in 'components.terraform.base_path' config or " +
 			"'ATMOS_COMPONENTS_TERRAFORM_BASE_PATH' ENV variable")
 	}
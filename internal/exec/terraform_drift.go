@@ -0,0 +1,250 @@
+package exec
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/cloudposse/atmos/pkg/atmos"
+	c "github.com/cloudposse/atmos/pkg/config"
+	l "github.com/cloudposse/atmos/pkg/logger"
+	"github.com/cloudposse/atmos/pkg/mask"
+	"github.com/cloudposse/atmos/pkg/terraform"
+	"github.com/cloudposse/atmos/pkg/utils"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// The flags of `terraform plan` in the drift detection.
+// The state is not locked since the plan does not change it, and the plans are not blocked by the running deployments
+var driftPlanArgsAndFlags = []string{"-detailed-exitcode", "-input=false", "-lock=false", "-no-color"}
+
+// ExecuteTerraformDrift executes `terraform drift` command
+func ExecuteTerraformDrift(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+
+	stacksPattern, err := flags.GetString("stacks")
+	if err != nil {
+		return err
+	}
+
+	componentsPattern, err := flags.GetString("components")
+	if err != nil {
+		return err
+	}
+
+	format, err := flags.GetString("format")
+	if err != nil {
+		return err
+	}
+
+	file, err := flags.GetString("file")
+	if err != nil {
+		return err
+	}
+
+	concurrency, err := flags.GetInt("concurrency")
+	if err != nil {
+		return err
+	}
+
+	stacksArchive, err := flags.GetString("stacks-archive")
+	if err != nil {
+		return err
+	}
+
+	if format != "json" && format != "junit" && format != "markdown" {
+		return withExitCode(errors.New(fmt.Sprintf("invalid '--format' flag '%s'. Valid values are 'json', 'junit' and 'markdown'", format)),
+			ExitCodeInvalidArguments)
+	}
+
+	if concurrency < 1 {
+		return withExitCode(errors.New(fmt.Sprintf("invalid '--concurrency' flag '%d'. It must be greater than 0", concurrency)),
+			ExitCodeInvalidArguments)
+	}
+
+	var configAndStacksInfo c.ConfigAndStacksInfo
+	configAndStacksInfo.CI = ciFlag(cmd)
+	configAndStacksInfo.StacksArchive = stacksArchive
+
+	client, err := newClient(configAndStacksInfo)
+	if err != nil {
+		return err
+	}
+
+	cliConfig := client.Config()
+
+	err = checkTerraformConfig(cliConfig)
+	if err != nil {
+		return withExitCode(err, ExitCodeInvalidConfig)
+	}
+
+	// The components in different stacks can use the same terraform component folder.
+	// Run them in separate working dirs, otherwise the parallel runs would select the terraform workspaces in the same folder
+	if concurrency > 1 {
+		cliConfig.Components.Terraform.WorkingDirs.Enabled = true
+	}
+
+	targets, err := findDriftTargets(client, stacksPattern, componentsPattern)
+	if err != nil {
+		return err
+	}
+
+	if len(targets) == 0 {
+		return errors.New(fmt.Sprintf("\nCould not find any terraform components matching the stacks '%s' and the components '%s'.\n",
+			stacksPattern, componentsPattern))
+	}
+
+	results := make([]terraform.DriftResult, len(targets))
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	wg.Add(len(targets))
+
+	for i, target := range targets {
+		go func(i int, info c.ConfigAndStacksInfo) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			results[i] = detectComponentDrift(client, cliConfig, info)
+			l.Info("%s %s: %s", results[i].Stack, results[i].Component, results[i].Status)
+		}(i, target)
+	}
+
+	wg.Wait()
+
+	report := terraform.NewDriftReport(results)
+
+	var content []byte
+	switch format {
+	case "json":
+		content, err = utils.ConvertToJSON(report)
+	case "junit":
+		content, err = report.JUnit()
+	case "markdown":
+		content = []byte(report.Markdown())
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(file) == 0 {
+		fmt.Print(string(content))
+	} else {
+		err = ioutil.WriteFile(file, content, 0644)
+		if err != nil {
+			return err
+		}
+		l.Info("\nWriting drift report to file:\n%s", file)
+	}
+
+	// Exit with 1 if the plan of any component failed, and with 2 if drift is found
+	if report.Errors > 0 {
+		return withExitCode(errors.New(fmt.Sprintf("\nDrift detection: %s\n", report.Totals())), ExitCodeFailure)
+	}
+	if report.Drifted > 0 {
+		return withExitCode(errors.New(fmt.Sprintf("\nDrift detection: %s\n", report.Totals())), ExitCodeChanges)
+	}
+
+	return nil
+}
+
+// findDriftTargets returns the terraform components in the stacks matching the glob patterns.
+// The stacks pattern is matched against the logical stack names (e.g. `tenant1-ue2-*`) and the stack config files (e.g. `tenant1/**`)
+func findDriftTargets(client *atmos.Client, stacksPattern string, componentsPattern string) ([]c.ConfigAndStacksInfo, error) {
+	stacks, err := client.Stacks()
+	if err != nil {
+		return nil, err
+	}
+
+	var res []c.ConfigAndStacksInfo
+	found := map[string]bool{}
+
+	for _, st := range stacks {
+		for _, component := range st.Components {
+			if component.Type != "terraform" {
+				continue
+			}
+
+			stackMatch, err := matchesDriftPattern(stacksPattern, component.Stack)
+			if err != nil {
+				return nil, err
+			}
+			if !stackMatch {
+				stackMatch, err = matchesDriftPattern(stacksPattern, component.StackFile)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			componentMatch, err := matchesDriftPattern(componentsPattern, component.Name)
+			if err != nil {
+				return nil, err
+			}
+
+			key := component.Stack + ":" + component.Name
+			if !stackMatch || !componentMatch || found[key] {
+				continue
+			}
+			found[key] = true
+
+			res = append(res, c.ConfigAndStacksInfo{
+				Stack:                  component.Stack,
+				ComponentFromArg:       component.Name,
+				SubCommand:             "plan",
+				AdditionalArgsAndFlags: driftPlanArgsAndFlags,
+			})
+		}
+	}
+
+	return res, nil
+}
+
+func matchesDriftPattern(pattern string, name string) (bool, error) {
+	if len(pattern) == 0 {
+		return true, nil
+	}
+	match, err := doublestar.Match(pattern, name)
+	if err != nil {
+		return false, errors.New(fmt.Sprintf("invalid glob pattern '%s': %s", pattern, err))
+	}
+	return match, nil
+}
+
+// detectComponentDrift runs `terraform plan -detailed-exitcode` for the component and classifies the result by the exit code
+func detectComponentDrift(client *atmos.Client, cliConfig c.Configuration, info c.ConfigAndStacksInfo) terraform.DriftResult {
+	res := terraform.DriftResult{Stack: info.Stack, Component: info.ComponentFromArg}
+	start := time.Now()
+
+	// The logs and the output of terraform are added to the report
+	var output bytes.Buffer
+	log := l.New(&output, l.LevelInfo, l.FormatText, false)
+	// Nothing is masked if the component config can't be processed
+	masker, _ := mask.New()
+
+	info, err := processComponentConfig(client, "terraform", info)
+	if err == nil {
+		masker, err = newComponentMasker(cliConfig, info.ComponentSettingsSection, info.ComponentVarsSection, info.ComponentEnvSection)
+	}
+	if err == nil {
+		log.SetMask(masker.Text)
+		info.Masker = masker
+		err = executeTerraform(client, cliConfig, info, log, nil, &output, &output)
+	}
+
+	res.Duration = time.Since(start).Seconds()
+	res.Status = terraform.DriftStatusNoChanges
+
+	if err != nil {
+		res.Status = terraform.DriftStatus(ExitCode(err))
+		if res.Status == terraform.DriftStatusError {
+			res.Error = masker.Text(err.Error())
+		}
+		res.Output = masker.Text(output.String())
+	}
+
+	return res
+}
@@ -4,19 +4,22 @@ import (
 	"fmt"
 	"github.com/cloudposse/atmos/pkg/config"
 	g "github.com/cloudposse/atmos/pkg/globals"
-	s "github.com/cloudposse/atmos/pkg/stack"
+	l "github.com/cloudposse/atmos/pkg/logger"
+	"github.com/cloudposse/atmos/pkg/terraform"
 	"github.com/cloudposse/atmos/pkg/utils"
-	"github.com/fatih/color"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
 	"path"
+	"path/filepath"
 	"strings"
 )
 
 // ExecuteTerraformGenerateBackend executes `terraform generate backend` command
 func ExecuteTerraformGenerateBackend(cmd *cobra.Command, args []string) error {
 	if len(args) != 1 {
-		return errors.New("invalid arguments. The command requires one argument `component`")
+		return withExitCode(errors.New("invalid arguments. The command requires one argument `component`"), ExitCodeInvalidArguments)
 	}
 	flags := cmd.Flags()
 
@@ -25,206 +28,434 @@ func ExecuteTerraformGenerateBackend(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	stacksArchive, err := flags.GetString("stacks-archive")
+	if err != nil {
+		return err
+	}
+
 	var configAndStacksInfo config.ConfigAndStacksInfo
+	configAndStacksInfo.CI = ciFlag(cmd)
 	configAndStacksInfo.Stack = stack
+	configAndStacksInfo.StacksArchive = stacksArchive
+
+	client, err := newClient(configAndStacksInfo)
+	if err != nil {
+		return err
+	}
+
+	cliConfig := client.Config()
+
+	component := args[0]
+
+	res, err := client.FindComponent(stack, "terraform", component)
+	if err != nil {
+		return err
+	}
+
+	componentBackendSection := res.Config.Backend
+	componentBackendType := res.Config.BackendType
+
+	if componentBackendType == "" {
+		return errors.New(fmt.Sprintf("\n'backend_type' is missing for the '%s' component.\n", component))
+	}
+
+	if componentBackendSection == nil {
+		return errors.New(fmt.Sprintf("\nCould not find 'backend' config for the '%s' component.\n", component))
+	}
+
+	err = terraform.ValidateBackend(componentBackendType, componentBackendSection)
+	if err != nil {
+		return errors.New(fmt.Sprintf("\nInvalid backend config for the '%s' component: %s\n", component, err))
+	}
+
+	var componentBackendConfig = generateComponentBackendConfig(componentBackendType, componentBackendSection)
+
+	masker, err := newComponentMasker(cliConfig, res.Config.Settings, res.Config.Vars, res.Config.Env)
+	if err != nil {
+		return err
+	}
+
+	// The sensitive values (e.g. the access keys) are masked in the printed config, but not in the generated file
+	l.Info("\nComponent backend config:\n")
+	err = utils.PrintAsJSON(masker.Map(componentBackendConfig))
+	if err != nil {
+		return err
+	}
+
+	// Find if the component has a base component
+	baseComponent := res.Config.BaseComponent
+
+	var finalComponent string
+	if len(baseComponent) > 0 {
+		finalComponent = baseComponent
+	} else {
+		finalComponent = component
+	}
+
+	// Write backend config to file
+	var backendFileName = path.Join(
+		cliConfig.Components.Terraform.BasePath,
+		finalComponent,
+		"backend.tf.json",
+	)
 
-	err = config.InitConfig()
+	l.Info("\nWriting backend config to file:\n%s\n", backendFileName)
+	err = utils.WriteToFileAsJSON(backendFileName, componentBackendConfig, 0644)
 	if err != nil {
 		return err
 	}
 
-	err = config.ProcessConfig(configAndStacksInfo)
+	return nil
+}
+
+// ExecuteTerraformGenerateProviders executes `terraform generate providers` command
+func ExecuteTerraformGenerateProviders(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return withExitCode(errors.New("invalid arguments. The command requires one argument `component`"), ExitCodeInvalidArguments)
+	}
+	flags := cmd.Flags()
+
+	stack, err := flags.GetString("stack")
 	if err != nil {
 		return err
 	}
 
+	stacksArchive, err := flags.GetString("stacks-archive")
+	if err != nil {
+		return err
+	}
+
+	var configAndStacksInfo config.ConfigAndStacksInfo
+	configAndStacksInfo.CI = ciFlag(cmd)
+	configAndStacksInfo.Stack = stack
+	configAndStacksInfo.StacksArchive = stacksArchive
+
+	client, err := newClient(configAndStacksInfo)
+	if err != nil {
+		return err
+	}
+
+	cliConfig := client.Config()
+
 	component := args[0]
 
-	// Print the stack config files
-	if g.LogVerbose {
-		fmt.Println()
-		var msg string
-		if config.ProcessedConfig.StackType == "Directory" {
-			msg = "Found the config file for the provided stack:"
-		} else {
-			msg = "Found config files:"
-		}
-		color.Cyan(msg)
+	res, err := client.FindComponent(stack, "terraform", component)
+	if err != nil {
+		return err
+	}
 
-		err = utils.PrintAsYAML(config.ProcessedConfig.StackConfigFilesRelativePaths)
-		if err != nil {
-			return err
-		}
+	if len(res.Config.Providers) == 0 {
+		return errors.New(fmt.Sprintf("\nCould not find 'providers' config for the '%s' component.\n", component))
 	}
 
-	_, stacksMap, err := s.ProcessYAMLConfigFiles(
-		config.ProcessedConfig.StacksBaseAbsolutePath,
-		config.ProcessedConfig.StackConfigFilesAbsolutePaths,
-		false,
-		false)
+	var componentProviderOverrides = generateComponentProviderOverrides(res.Config.Providers)
 
+	masker, err := newComponentMasker(cliConfig, res.Config.Settings, res.Config.Vars, res.Config.Env)
 	if err != nil {
 		return err
 	}
 
-	var componentSection map[string]interface{}
-	var componentVarsSection map[interface{}]interface{}
-	var componentBackendSection map[interface{}]interface{}
-	var componentBackendType string
+	// The sensitive values are masked in the printed config, but not in the generated file
+	l.Info("\nComponent provider overrides:\n")
+	err = utils.PrintAsJSON(masker.Map(componentProviderOverrides))
+	if err != nil {
+		return err
+	}
+
+	var finalComponent string
+	if len(res.Config.BaseComponent) > 0 {
+		finalComponent = res.Config.BaseComponent
+	} else {
+		finalComponent = component
+	}
 
-	// Check and process stacks
-	if config.ProcessedConfig.StackType == "Directory" {
-		componentSection,
-			componentVarsSection,
-			_,
-			componentBackendSection,
-			componentBackendType,
-			_, _, _,
-			err = findComponentConfig(stack, stacksMap, "terraform", component)
+	// Write provider overrides to file
+	var providersFileName = path.Join(
+		cliConfig.Components.Terraform.BasePath,
+		finalComponent,
+		providersOverrideFileName,
+	)
+
+	l.Info("\nWriting provider overrides to file:\n%s\n", providersFileName)
+	err = utils.WriteToFileAsJSON(providersFileName, componentProviderOverrides, 0644)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ExecuteTerraformGenerateBackends executes `terraform generate backends` command
+func ExecuteTerraformGenerateBackends(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+
+	stack, err := flags.GetString("stack")
+	if err != nil {
+		return err
+	}
+
+	stacksArchive, err := flags.GetString("stacks-archive")
+	if err != nil {
+		return err
+	}
+
+	var configAndStacksInfo config.ConfigAndStacksInfo
+	configAndStacksInfo.CI = ciFlag(cmd)
+	configAndStacksInfo.Stack = stack
+	configAndStacksInfo.StacksArchive = stacksArchive
+
+	client, err := newClient(configAndStacksInfo)
+	if err != nil {
+		return err
+	}
+
+	cliConfig := client.Config()
+
+	res, err := client.DescribeStack(stack)
+	if err != nil {
+		return err
+	}
+
+	// Validate the backend configs of all the components before writing any files
+	backendFiles := map[string]map[string]interface{}{}
+	var backendFileNames []string
+
+	for _, component := range res.Components {
+		if component.Type != "terraform" || len(component.Config.BackendType) == 0 {
+			continue
+		}
+
+		err = terraform.ValidateBackend(component.Config.BackendType, component.Config.Backend)
 		if err != nil {
-			return err
+			return errors.New(fmt.Sprintf("\nInvalid backend config for the '%s' component: %s\n", component.Name, err))
 		}
-	} else {
-		if g.LogVerbose == true {
-			color.Cyan("Searching for stack config where the component '%s' is defined\n", component)
+
+		finalComponent := component.Name
+		if len(component.Config.BaseComponent) > 0 {
+			finalComponent = component.Config.BaseComponent
 		}
 
-		if len(config.Config.Stacks.NamePattern) < 1 {
-			return errors.New("stack name pattern must be provided in 'stacks.name_pattern' config or 'ATMOS_STACKS_NAME_PATTERN' ENV variable")
+		var backendFileName = path.Join(
+			cliConfig.Components.Terraform.BasePath,
+			finalComponent,
+			"backend.tf.json",
+		)
+
+		if _, ok := backendFiles[backendFileName]; !ok {
+			backendFileNames = append(backendFileNames, backendFileName)
 		}
+		backendFiles[backendFileName] = generateComponentBackendConfig(component.Config.BackendType, component.Config.Backend)
+	}
 
-		stackParts := strings.Split(stack, "-")
-		stackNamePatternParts := strings.Split(config.Config.Stacks.NamePattern, "-")
-
-		var tenant string
-		var environment string
-		var stage string
-		var tenantFound bool
-		var environmentFound bool
-		var stageFound bool
-
-		for i, part := range stackNamePatternParts {
-			if part == "{tenant}" {
-				tenant = stackParts[i]
-			} else if part == "{environment}" {
-				environment = stackParts[i]
-			} else if part == "{stage}" {
-				stage = stackParts[i]
-			}
+	if len(backendFileNames) == 0 {
+		return errors.New(fmt.Sprintf("\nCould not find any terraform components with 'backend_type' in the stack '%s'.\n", stack))
+	}
+
+	l.Info("\nWriting backend configs to files:\n%s\n", strings.Join(backendFileNames, "\n"))
+	for _, backendFileName := range backendFileNames {
+		err = utils.WriteToFileAsJSON(backendFileName, backendFiles[backendFileName], 0644)
+		if err != nil {
+			return err
 		}
+	}
 
-		for stackName := range stacksMap {
-			componentSection,
-				componentVarsSection,
-				_,
-				componentBackendSection,
-				componentBackendType,
-				_, _, _,
-				err = findComponentConfig(stackName, stacksMap, "terraform", component)
-			if err != nil {
-				continue
-			}
+	return nil
+}
 
-			tenantFound = true
-			environmentFound = true
-			stageFound = true
+// ExecuteTerraformGenerateRemoteState executes `terraform generate remote-state` command
+func ExecuteTerraformGenerateRemoteState(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return withExitCode(errors.New("invalid arguments. The command requires one argument `component`"), ExitCodeInvalidArguments)
+	}
+	flags := cmd.Flags()
 
-			// Search for tenant in stack
-			if len(tenant) > 0 {
-				if tenantInStack, ok := componentVarsSection["tenant"].(string); !ok || tenantInStack != tenant {
-					tenantFound = false
-				}
-			}
+	stack, err := flags.GetString("stack")
+	if err != nil {
+		return err
+	}
 
-			// Search for environment in stack
-			if len(environment) > 0 {
-				if environmentInStack, ok := componentVarsSection["environment"].(string); !ok || environmentInStack != environment {
-					environmentFound = false
-				}
-			}
+	stacksArchive, err := flags.GetString("stacks-archive")
+	if err != nil {
+		return err
+	}
 
-			// Search for stage in stack
-			if len(stage) > 0 {
-				if stageInStack, ok := componentVarsSection["stage"].(string); !ok || stageInStack != stage {
-					stageFound = false
-				}
-			}
+	format, err := flags.GetString("format")
+	if err != nil {
+		return err
+	}
 
-			if tenantFound == true && environmentFound == true && stageFound == true {
-				if g.LogVerbose == true {
-					color.Green("Found stack config for the '%s' component in the '%s' stack\n\n", component, stackName)
-				}
-				stack = stackName
-				break
-			}
+	name, err := flags.GetString("name")
+	if err != nil {
+		return err
+	}
+
+	file, err := flags.GetString("file")
+	if err != nil {
+		return err
+	}
+
+	var configAndStacksInfo config.ConfigAndStacksInfo
+	configAndStacksInfo.CI = ciFlag(cmd)
+	configAndStacksInfo.Stack = stack
+	configAndStacksInfo.StacksArchive = stacksArchive
+
+	client, err := newClient(configAndStacksInfo)
+	if err != nil {
+		return err
+	}
+
+	component := args[0]
+
+	res, err := client.FindComponent(stack, "terraform", component)
+	if err != nil {
+		return err
+	}
+
+	if res.Config.RemoteStateBackendType == "" {
+		return errors.New(fmt.Sprintf("\n'remote_state_backend_type' is missing for the '%s' component.\n", component))
+	}
+
+	if res.Config.RemoteStateBackendType != terraform.StaticBackendType {
+		err = terraform.ValidateBackend(res.Config.RemoteStateBackendType, res.Config.RemoteStateBackend)
+		if err != nil {
+			return errors.New(fmt.Sprintf("\nInvalid remote state backend config for the '%s' component: %s\n", component, err))
 		}
+	}
+
+	remoteState := terraform.RemoteState{
+		BackendType: res.Config.RemoteStateBackendType,
+		Backend:     res.Config.RemoteStateBackend,
+		Workspace:   res.Workspace,
+	}
+
+	if len(name) == 0 {
+		name = terraform.DataSourceName(component)
+	}
 
-		if tenantFound == false || environmentFound == false || stageFound == false {
-			return errors.New(fmt.Sprintf("\nCould not find config for the '%s' component in the '%s' stack.\n"+
-				"Check that all attributes in the stack name pattern '%s' are defined in stack config files.\n"+
-				"Are the component and stack names correct? Did you forget an import?",
-				component,
-				stack,
-				config.Config.Stacks.NamePattern,
-			))
+	var content []byte
+	switch format {
+	case "hcl":
+		content, err = terraform.RemoteStateDataSourceHCL(name, remoteState)
+	case "json":
+		var dataSource map[string]interface{}
+		dataSource, err = terraform.RemoteStateDataSource(name, remoteState)
+		if err == nil {
+			content, err = utils.ConvertToJSON(dataSource)
 		}
+	case "backend":
+		content, err = utils.ConvertToJSON(remoteState)
+	default:
+		return errors.New(fmt.Sprintf("invalid '--format' flag '%s'. Valid values are 'hcl', 'json' and 'backend'", format))
+	}
+	if err != nil {
+		return errors.New(fmt.Sprintf("\nError generating the remote state config for the '%s' component: %s\n", component, err))
 	}
 
-	if componentBackendType == "" {
-		return errors.New(fmt.Sprintf("\n'backend_type' is missing for the '%s' component.\n", component))
+	if len(file) == 0 {
+		fmt.Println(strings.TrimSpace(string(content)))
+		return nil
 	}
 
-	if componentBackendSection == nil {
-		return errors.New(fmt.Sprintf("\nCould not find 'backend' config for the '%s' component.\n", component))
+	err = ioutil.WriteFile(file, content, 0644)
+	if err != nil {
+		return err
 	}
 
-	var componentBackendConfig = generateComponentBackendConfig(componentBackendType, componentBackendSection)
+	l.Info("\nWriting remote state config to file:\n%s\n", file)
+	return nil
+}
+
+// ExecuteTerraformGenerateCatalog executes `terraform generate catalog` command
+func ExecuteTerraformGenerateCatalog(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return withExitCode(errors.New("invalid arguments. The command requires one argument `component`"), ExitCodeInvalidArguments)
+	}
+	flags := cmd.Flags()
 
-	fmt.Println()
-	color.Cyan("Component backend config:\n\n")
-	err = utils.PrintAsJSON(componentBackendConfig)
+	catalogFile, err := flags.GetString("file")
 	if err != nil {
 		return err
 	}
 
-	// Check if the `backend` section has `workspace_key_prefix`
-	if _, ok := componentBackendSection["workspace_key_prefix"].(string); !ok {
-		return errors.New(fmt.Sprintf("\nBackend config for the '%s' component is missing 'workspace_key_prefix'\n", component))
+	update, err := flags.GetBool("update")
+	if err != nil {
+		return err
 	}
 
-	// Find if the component has a base component
-	var baseComponent string
-	if baseComponentSection, ok := componentSection["component"].(string); ok {
-		baseComponent = baseComponentSection
+	includeContext, err := flags.GetBool("include-context")
+	if err != nil {
+		return err
 	}
 
-	var finalComponent string
-	if len(baseComponent) > 0 {
-		finalComponent = baseComponent
-	} else {
-		finalComponent = component
+	cliConfig, err := config.InitConfig()
+	if err != nil {
+		return err
 	}
 
-	// Write backend config to file
-	var backendFileName = path.Join(
-		config.Config.Components.Terraform.BasePath,
-		finalComponent,
-		"backend.tf.json",
-	)
+	err = config.ProcessConfig(&cliConfig, config.ConfigAndStacksInfo{})
+	if err != nil {
+		return err
+	}
 
-	fmt.Println()
-	color.Cyan("Writing backend config to file:")
-	fmt.Println(backendFileName)
-	err = utils.WriteToFileAsJSON(backendFileName, componentBackendConfig, 0644)
+	component := args[0]
+	componentPath := path.Join(cliConfig.Components.Terraform.BasePath, component)
+
+	variables, err := terraform.LoadVariables(componentPath)
 	if err != nil {
 		return err
 	}
 
-	fmt.Println()
-	return nil
-}
+	// The context variables are set in the global `vars` sections, and `null` values in the catalog would override them
+	if !includeContext {
+		var componentVariables []terraform.Variable
+		for _, variable := range variables {
+			if filepath.Base(variable.File) != terraform.ContextFileName {
+				componentVariables = append(componentVariables, variable)
+			}
+		}
+		variables = componentVariables
+	}
 
-// ExecuteTerraformGenerateBackends executes `terraform generate backends` command
-func ExecuteTerraformGenerateBackends(cmd *cobra.Command, args []string) error {
+	if len(catalogFile) == 0 {
+		catalogFile = path.Join(cliConfig.Stacks.BasePath, "catalog", "terraform", component+g.DefaultStackConfigFileExtension)
+	}
+
+	var content []byte
+	if utils.FileExists(catalogFile) {
+		if !update {
+			return errors.New(fmt.Sprintf("The catalog file '%s' already exists. Use the '--update' flag to add the new variables to it", catalogFile))
+		}
+		content, err = ioutil.ReadFile(catalogFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	catalog, added, err := terraform.UpdateCatalog(content, component, variables)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error updating the catalog file '%s': %s", catalogFile, err))
+	}
+
+	if len(content) > 0 && len(added) == 0 {
+		l.Info("The catalog file '%s' has all the variables of the component '%s'", catalogFile, component)
+		return nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(catalogFile), 0755)
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(catalogFile, catalog, 0644)
+	if err != nil {
+		return err
+	}
+
+	if len(content) > 0 {
+		l.Info("Added the variables to the catalog file '%s':\n%s\n", catalogFile, strings.Join(added, "\n"))
+		return nil
+	}
+
+	l.Info("Wrote the catalog for the component '%s' with %d variables to '%s'", component, len(added), catalogFile)
 	return nil
 }
@@ -0,0 +1,241 @@
+package exec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/cloudposse/atmos/pkg/atmos"
+	c "github.com/cloudposse/atmos/pkg/config"
+	l "github.com/cloudposse/atmos/pkg/logger"
+	"github.com/cloudposse/atmos/pkg/mask"
+	s "github.com/cloudposse/atmos/pkg/stack"
+	"github.com/cloudposse/atmos/pkg/terraform"
+	u "github.com/cloudposse/atmos/pkg/utils"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// ExecuteTerraformOutput executes `terraform output` command
+func ExecuteTerraformOutput(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return withExitCode(errors.New("invalid arguments. The command requires the argument `component` and an optional argument `output`"),
+			ExitCodeInvalidArguments)
+	}
+	flags := cmd.Flags()
+
+	stack, err := flags.GetString("stack")
+	if err != nil {
+		return err
+	}
+
+	stacksArchive, err := flags.GetString("stacks-archive")
+	if err != nil {
+		return err
+	}
+
+	if len(stack) < 1 {
+		return withExitCode(errors.New("stack must be specified"), ExitCodeInvalidArguments)
+	}
+
+	var configAndStacksInfo c.ConfigAndStacksInfo
+	configAndStacksInfo.CI = ciFlag(cmd)
+	configAndStacksInfo.Stack = stack
+	configAndStacksInfo.StacksArchive = stacksArchive
+
+	client, err := newClient(configAndStacksInfo)
+	if err != nil {
+		return err
+	}
+
+	outputs, err := terraformOutputs(client, client.Config(), stack, args[0], l.Default(), nil, os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 1 {
+		return u.PrintAsJSON(terraform.OutputValues(outputs))
+	}
+
+	value, err := terraform.OutputValue(outputs, args[1])
+	if err != nil {
+		return errors.New(fmt.Sprintf("%s in the component '%s' in the stack '%s'", err, args[0], stack))
+	}
+	return u.PrintAsJSON(value)
+}
+
+// terraformOutputs runs `terraform output -json` for the component in the stack and returns the outputs.
+// The component is initialized and its terraform workspace is selected in the same way as for all other terraform commands.
+// The outputs are cached on the client (see `atmos.Client.TerraformOutputs`). The logs and the output of `terraform init` are written to the logger and `stderr`.
+// The sensitive values of the component are masked in the logs, and are added to the provided masker (if any)
+func terraformOutputs(
+	client *atmos.Client,
+	cliConfig c.Configuration,
+	stack string,
+	component string,
+	log *l.Logger,
+	masker *mask.Masker,
+	stderr io.Writer,
+) (map[string]terraform.Output, error) {
+
+	info := c.ConfigAndStacksInfo{
+		Stack:                  stack,
+		ComponentFromArg:       component,
+		SubCommand:             "output",
+		AdditionalArgsAndFlags: []string{"-json"},
+	}
+
+	info, err := processComponentConfig(client, "terraform", info)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs, componentMasker, err := client.TerraformOutputs(info.Stack, component, func() (map[string]terraform.Output, *mask.Masker, error) {
+		componentMasker, err := newComponentMasker(cliConfig, info.ComponentSettingsSection, info.ComponentVarsSection, info.ComponentEnvSection)
+		if err != nil {
+			return nil, nil, err
+		}
+		info.Masker = componentMasker
+
+		var out bytes.Buffer
+		err = executeTerraform(client, cliConfig, info, log.WithMask(componentMasker.Text), nil, &out, stderr)
+		if err != nil {
+			return nil, componentMasker, err
+		}
+
+		outputs, err := terraform.ParseOutputs(out.Bytes())
+		return outputs, componentMasker, err
+	})
+
+	if masker != nil && componentMasker != nil {
+		masker.AddValuesFrom(componentMasker)
+	}
+
+	return outputs, err
+}
+
+// resolveTerraformOutputs replaces the `!terraform.output <component> [stack] <output>` references in the vars and ENV vars
+// of the component with the outputs of the referenced components. If the stack is not specified, the referenced component
+// is in the same stack. The values of the sensitive outputs and the secrets of the referenced components are added to the masker of the component.
+// Returns `true` if any of the vars is a sensitive output (the varfile must then be protected)
+func resolveTerraformOutputs(client *atmos.Client, cliConfig c.Configuration, info *c.ConfigAndStacksInfo, log *l.Logger, stderr io.Writer) (bool, error) {
+	varsContainSensitive := false
+
+	resolve := func(ref string) (interface{}, bool, error) {
+		parts := strings.Fields(strings.TrimPrefix(ref, s.TagTerraformOutput+" "))
+		var component, stack, output string
+		switch len(parts) {
+		case 2:
+			component, stack, output = parts[0], info.Stack, parts[1]
+		case 3:
+			component, stack, output = parts[0], parts[1], parts[2]
+		default:
+			return nil, false, errors.New(fmt.Sprintf("invalid reference '%s'. The format is '%s <component> [stack] <output>'",
+				ref, s.TagTerraformOutput))
+		}
+
+		outputs, err := terraformOutputs(client, cliConfig, stack, component, log, info.Masker, stderr)
+		if err != nil {
+			return nil, false, errors.New(fmt.Sprintf("could not resolve '%s': %s", ref, err))
+		}
+
+		value, err := terraform.OutputValue(outputs, output)
+		if err != nil {
+			return nil, false, errors.New(fmt.Sprintf("could not resolve '%s': %s in the component '%s' in the stack '%s'",
+				ref, err, component, stack))
+		}
+
+		sensitive := outputs[strings.Split(output, ".")[0]].Sensitive
+		if sensitive && info.Masker != nil {
+			if str, ok := value.(string); ok {
+				info.Masker.AddValues(str)
+			} else if j, err := json.Marshal(value); err == nil {
+				info.Masker.AddValues(string(j))
+			}
+		}
+		return value, sensitive, nil
+	}
+
+	vars, err := replaceTerraformOutputRefs(info.ComponentVarsSection, func(ref string) (interface{}, error) {
+		value, sensitive, err := resolve(ref)
+		varsContainSensitive = varsContainSensitive || sensitive
+		return value, err
+	})
+	if err != nil {
+		return false, err
+	}
+	if vars != nil {
+		info.ComponentVarsSection = vars.(map[string]interface{})
+	}
+
+	env := make([]string, 0, len(info.ComponentEnvList))
+	for _, v := range info.ComponentEnvList {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) == 2 && strings.HasPrefix(parts[1], s.TagTerraformOutput+" ") {
+			value, _, err := resolve(parts[1])
+			if err != nil {
+				return false, err
+			}
+			// The ENV vars are strings, the other outputs are converted to JSON
+			str, ok := value.(string)
+			if !ok {
+				j, err := json.Marshal(value)
+				if err != nil {
+					return false, err
+				}
+				str = string(j)
+			}
+			v = parts[0] + "=" + str
+		}
+		env = append(env, v)
+	}
+	info.ComponentEnvList = env
+
+	return varsContainSensitive, nil
+}
+
+// replaceTerraformOutputRefs returns a copy of the value with the `!terraform.output` references (at any level) replaced by the outputs
+func replaceTerraformOutputRefs(v interface{}, resolve func(string) (interface{}, error)) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		if !strings.HasPrefix(v, s.TagTerraformOutput+" ") {
+			return v, nil
+		}
+		return resolve(v)
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			resolved, err := replaceTerraformOutputRefs(val, resolve)
+			if err != nil {
+				return nil, err
+			}
+			res[k] = resolved
+		}
+		return res, nil
+	case map[interface{}]interface{}:
+		res := make(map[interface{}]interface{}, len(v))
+		for k, val := range v {
+			resolved, err := replaceTerraformOutputRefs(val, resolve)
+			if err != nil {
+				return nil, err
+			}
+			res[k] = resolved
+		}
+		return res, nil
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, val := range v {
+			resolved, err := replaceTerraformOutputRefs(val, resolve)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = resolved
+		}
+		return res, nil
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,144 @@
+package exec
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudposse/atmos/pkg/atmos"
+	c "github.com/cloudposse/atmos/pkg/config"
+	l "github.com/cloudposse/atmos/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+const terraformOutputsFixture = `{
+  "vpc_id": {"sensitive": false, "type": "string", "value": "vpc-0123456789"},
+  "private_subnet_ids": {"sensitive": false, "type": ["list", "string"], "value": ["subnet-1", "subnet-2"]},
+  "db_password": {"sensitive": true, "type": "string", "value": "hunter22"}
+}`
+
+// writeStubTerraformOutput writes a script which prints the fixture for `terraform output -json`
+// and appends a line to the returned file each time the outputs are read
+func writeStubTerraformOutput(t *testing.T, fixture string) (string, string) {
+	dir := t.TempDir()
+	fixtureFile := filepath.Join(dir, "outputs.json")
+	assert.Nil(t, ioutil.WriteFile(fixtureFile, []byte(fixture), 0644))
+
+	runsFile := filepath.Join(dir, "runs")
+	script := "#!/bin/sh\n" +
+		"if [ \"$1\" = \"init\" ] || [ \"$1\" = \"workspace\" ]; then exit 0; fi\n" +
+		"if [ \"$1\" = \"output\" ] && [ \"$2\" = \"-json\" ]; then echo output >> " + runsFile + "; cat " + fixtureFile + "; exit 0; fi\n" +
+		"echo \"unexpected arguments: $@\" >&2\nexit 1\n"
+	command := filepath.Join(dir, "terraform")
+	assert.Nil(t, ioutil.WriteFile(command, []byte(script), 0755))
+	return command, runsFile
+}
+
+func TestResolveTerraformOutputs(t *testing.T) {
+	command, runsFile := writeStubTerraformOutput(t, terraformOutputsFixture)
+
+	assert.Nil(t, os.Setenv("ATMOS_TEST_VPC_LABEL", "s3cr3t-label"))
+	defer os.Unsetenv("ATMOS_TEST_VPC_LABEL")
+
+	tmpDir := t.TempDir()
+	terraformBasePath := filepath.Join(tmpDir, "components", "terraform")
+	writeWorkingDirTestFile(t, filepath.Join(terraformBasePath, "vpc", "main.tf"), "")
+	writeWorkingDirTestFile(t, filepath.Join(terraformBasePath, "eks", "main.tf"), "")
+	writeWorkingDirTestFile(t, filepath.Join(tmpDir, "stacks", "tenant1-ue2-dev.yaml"), `
+vars:
+  tenant: tenant1
+  environment: ue2
+  stage: dev
+
+components:
+  terraform:
+    vpc:
+      command: `+command+`
+      env:
+        VPC_LABEL: !secret env:ATMOS_TEST_VPC_LABEL
+    eks:
+      command: `+command+`
+      vars:
+        vpc_id: !terraform.output vpc vpc_id
+        subnet_ids: !terraform.output vpc tenant1-ue2-dev private_subnet_ids
+        db_password: !terraform.output vpc db_password
+      env:
+        TF_VAR_private_subnet_ids: !terraform.output vpc private_subnet_ids
+`)
+
+	client, err := atmos.NewClient(atmos.Options{
+		StacksBasePath:    filepath.Join(tmpDir, "stacks"),
+		StackNamePattern:  "{tenant}-{environment}-{stage}",
+		TerraformBasePath: terraformBasePath,
+	})
+	assert.Nil(t, err)
+	cliConfig := client.Config()
+
+	var logs bytes.Buffer
+	log := l.New(&logs, l.LevelInfo, l.FormatText, false)
+
+	info := newTerraformOutputsTestInfo(t, client, "eks")
+	varsContainSensitive, err := resolveTerraformOutputs(client, cliConfig, &info, log, ioutil.Discard)
+	assert.Nil(t, err)
+	assert.True(t, varsContainSensitive)
+	assert.Equal(t, "vpc-0123456789", info.ComponentVarsSection["vpc_id"])
+	assert.Equal(t, []interface{}{"subnet-1", "subnet-2"}, info.ComponentVarsSection["subnet_ids"])
+	assert.Equal(t, "hunter22", info.ComponentVarsSection["db_password"])
+	assert.Contains(t, info.ComponentEnvList, `TF_VAR_private_subnet_ids=["subnet-1","subnet-2"]`)
+
+	// The secrets of the referenced component and the sensitive outputs are masked
+	assert.Contains(t, logs.String(), "VPC_LABEL=***")
+	assert.NotContains(t, logs.String(), "s3cr3t-label")
+	assert.Equal(t, "label=*** password=***", info.Masker.Text("label=s3cr3t-label password=hunter22"))
+
+	// The outputs of the referenced component are read once for the client
+	info = newTerraformOutputsTestInfo(t, client, "eks")
+	_, err = resolveTerraformOutputs(client, cliConfig, &info, log, ioutil.Discard)
+	assert.Nil(t, err)
+	assert.Equal(t, "label=***", info.Masker.Text("label=s3cr3t-label"))
+
+	runs, err := ioutil.ReadFile(runsFile)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, strings.Count(string(runs), "output"))
+
+	// A new client reads the outputs again
+	client2, err := atmos.NewClient(atmos.Options{
+		StacksBasePath:    filepath.Join(tmpDir, "stacks"),
+		StackNamePattern:  "{tenant}-{environment}-{stage}",
+		TerraformBasePath: terraformBasePath,
+	})
+	assert.Nil(t, err)
+	info = newTerraformOutputsTestInfo(t, client2, "eks")
+	_, err = resolveTerraformOutputs(client2, client2.Config(), &info, log, ioutil.Discard)
+	assert.Nil(t, err)
+
+	runs, err = ioutil.ReadFile(runsFile)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, strings.Count(string(runs), "output"))
+
+	// The referenced output does not exist
+	info = newTerraformOutputsTestInfo(t, client, "eks")
+	info.ComponentVarsSection = map[string]interface{}{"cluster_name": "!terraform.output vpc cluster_name"}
+	_, err = resolveTerraformOutputs(client, cliConfig, &info, log, ioutil.Discard)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "!terraform.output vpc cluster_name")
+
+	// The referenced component does not exist
+	info = newTerraformOutputsTestInfo(t, client, "eks")
+	info.ComponentVarsSection = map[string]interface{}{"cluster_name": "!terraform.output rds cluster_name"}
+	_, err = resolveTerraformOutputs(client, cliConfig, &info, log, ioutil.Discard)
+	assert.NotNil(t, err)
+}
+
+// newTerraformOutputsTestInfo returns the config of the component in the `tenant1-ue2-dev` stack with its masker
+func newTerraformOutputsTestInfo(t *testing.T, client *atmos.Client, component string) c.ConfigAndStacksInfo {
+	info, err := processComponentConfig(client, "terraform", c.ConfigAndStacksInfo{Stack: "tenant1-ue2-dev", ComponentFromArg: component})
+	assert.Nil(t, err)
+
+	info.Masker, err = newComponentMasker(client.Config(), info.ComponentSettingsSection, info.ComponentVarsSection, info.ComponentEnvSection)
+	assert.Nil(t, err)
+	return info
+}
@@ -0,0 +1,137 @@
+package exec
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudposse/atmos/pkg/atmos"
+	c "github.com/cloudposse/atmos/pkg/config"
+	l "github.com/cloudposse/atmos/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTerraformPlanFileWithSecrets(t *testing.T) {
+	// The stub `terraform plan` writes the planfile (the value of the `-out` flag) with the default permissions
+	stubDir := t.TempDir()
+	command := filepath.Join(stubDir, "terraform")
+	script := "#!/bin/sh\n" +
+		"if [ \"$1\" != \"plan\" ]; then exit 0; fi\n" +
+		"while [ $# -gt 0 ]; do if [ \"$1\" = \"-out\" ]; then echo plan > \"$2\"; chmod 0644 \"$2\"; fi; shift; done\n"
+	assert.Nil(t, ioutil.WriteFile(command, []byte(script), 0755))
+
+	assert.Nil(t, os.Setenv("ATMOS_TEST_DB_PASSWORD", "hunter22"))
+	defer os.Unsetenv("ATMOS_TEST_DB_PASSWORD")
+
+	tmpDir := t.TempDir()
+	terraformBasePath := filepath.Join(tmpDir, "components", "terraform")
+	writeWorkingDirTestFile(t, filepath.Join(terraformBasePath, "vpc", "main.tf"), "")
+	writeWorkingDirTestFile(t, filepath.Join(terraformBasePath, "rds", "main.tf"), "")
+	writeWorkingDirTestFile(t, filepath.Join(tmpDir, "stacks", "tenant1-ue2-dev.yaml"), `
+vars:
+  tenant: tenant1
+  environment: ue2
+  stage: dev
+
+components:
+  terraform:
+    vpc:
+      command: `+command+`
+    rds:
+      command: `+command+`
+      vars:
+        db_password: !secret env:ATMOS_TEST_DB_PASSWORD
+`)
+
+	client, err := atmos.NewClient(atmos.Options{
+		StacksBasePath:    filepath.Join(tmpDir, "stacks"),
+		StackNamePattern:  "{tenant}-{environment}-{stage}",
+		TerraformBasePath: terraformBasePath,
+	})
+	assert.Nil(t, err)
+
+	var logs, out bytes.Buffer
+	log := l.New(&logs, l.LevelInfo, l.FormatText, false)
+
+	plan := func(component string) os.FileMode {
+		info, err := processComponentConfig(client, "terraform", c.ConfigAndStacksInfo{
+			Stack:            "tenant1-ue2-dev",
+			ComponentFromArg: component,
+			SubCommand:       "plan",
+		})
+		assert.Nil(t, err)
+		info.Masker, err = newComponentMasker(client.Config(), info.ComponentSettingsSection, info.ComponentVarsSection, info.ComponentEnvSection)
+		assert.Nil(t, err)
+
+		err = executeTerraform(client, client.Config(), info, log, nil, &out, &out)
+		assert.Nil(t, err)
+
+		stat, err := os.Stat(filepath.Join(terraformBasePath, component, "tenant1-ue2-dev-"+component+".planfile"))
+		assert.Nil(t, err)
+		return stat.Mode().Perm()
+	}
+
+	// The planfile with the secrets can be read only by the owner
+	assert.Equal(t, os.FileMode(0644), plan("vpc"))
+	assert.Equal(t, os.FileMode(0600), plan("rds"))
+}
+
+func TestTerraformPlanDetailedExitCode(t *testing.T) {
+	// The stub `terraform plan` exits with 2 (the plan has changes)
+	stubDir := t.TempDir()
+	command := filepath.Join(stubDir, "terraform")
+	script := "#!/bin/sh\n" +
+		"if [ \"$1\" = \"plan\" ]; then exit 2; fi\n"
+	assert.Nil(t, ioutil.WriteFile(command, []byte(script), 0755))
+
+	tmpDir := t.TempDir()
+	terraformBasePath := filepath.Join(tmpDir, "components", "terraform")
+	writeWorkingDirTestFile(t, filepath.Join(terraformBasePath, "vpc", "main.tf"), "")
+	writeWorkingDirTestFile(t, filepath.Join(tmpDir, "stacks", "tenant1-ue2-dev.yaml"), `
+vars:
+  tenant: tenant1
+  environment: ue2
+  stage: dev
+
+components:
+  terraform:
+    vpc:
+      command: `+command+`
+`)
+
+	client, err := atmos.NewClient(atmos.Options{
+		StacksBasePath:    filepath.Join(tmpDir, "stacks"),
+		StackNamePattern:  "{tenant}-{environment}-{stage}",
+		TerraformBasePath: terraformBasePath,
+	})
+	assert.Nil(t, err)
+
+	var logs, out bytes.Buffer
+	log := l.New(&logs, l.LevelInfo, l.FormatText, false)
+
+	plan := func(args ...string) error {
+		info, err := processComponentConfig(client, "terraform", c.ConfigAndStacksInfo{
+			Stack:                  "tenant1-ue2-dev",
+			ComponentFromArg:       "vpc",
+			SubCommand:             "plan",
+			AdditionalArgsAndFlags: args,
+		})
+		assert.Nil(t, err)
+		info.Masker, err = newComponentMasker(client.Config(), info.ComponentSettingsSection, info.ComponentVarsSection, info.ComponentEnvSection)
+		assert.Nil(t, err)
+
+		return executeTerraform(client, client.Config(), info, log, nil, &out, &out)
+	}
+
+	// The plan with changes is not a failure, so only the exit code is returned
+	err = plan(detailedExitCodeFlag)
+	assert.Equal(t, 2, ExitCode(err))
+	assert.True(t, IsSilent(err))
+
+	// Without `-detailed-exitcode`, the exit code 2 is an error
+	err = plan()
+	assert.Equal(t, 2, ExitCode(err))
+	assert.False(t, IsSilent(err))
+}
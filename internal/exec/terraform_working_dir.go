@@ -0,0 +1,193 @@
+package exec
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	c "github.com/cloudposse/atmos/pkg/config"
+	"github.com/pkg/errors"
+)
+
+const (
+	workingDirModeSymlink = "symlink"
+	workingDirModeCopy    = "copy"
+
+	terraformDataDirName  = ".terraform"
+	terraformLockFileName = ".terraform.lock.hcl"
+
+	terraformStateFileName     = "terraform.tfstate"
+	terraformStateLockFileName = ".terraform.tfstate.lock.info"
+)
+
+// terraformWorkingDir returns the working directory of the component in the stack: `<working_dirs.base_path>/<stack>/<component>`.
+// The working directory mirrors the layout of the terraform components base path, so the relative paths to the modules
+// in the base path (e.g. `source = "../modules/vpc"`) still resolve
+func terraformWorkingDir(cliConfig c.Configuration, info c.ConfigAndStacksInfo) (string, error) {
+	basePath, err := filepath.Abs(cliConfig.Components.Terraform.WorkingDirs.BasePath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(basePath, info.ContextPrefix, strings.Replace(info.ComponentFromArg, "/", "-", -1)), nil
+}
+
+// prepareTerraformWorkingDir creates or updates the working directory of the component.
+// The folders of the terraform base path on the way to the component folder are created in the working directory,
+// and all the other files and folders in them are symlinked. The files in the component folder are symlinked or copied
+// depending on `working_dirs.mode`. The `.terraform` folder, the lock file and the local state in the working directory are kept between runs.
+// It returns the path of the component folder in the working directory
+func prepareTerraformWorkingDir(cliConfig c.Configuration, terraformBasePath string, componentFolder string, workingDir string) (string, error) {
+	mode := cliConfig.Components.Terraform.WorkingDirs.Mode
+	if mode != workingDirModeSymlink && mode != workingDirModeCopy {
+		return "", errors.New(fmt.Sprintf("invalid 'components.terraform.working_dirs.mode' '%s'. Valid values are '%s' and '%s'",
+			mode, workingDirModeSymlink, workingDirModeCopy))
+	}
+
+	source := terraformBasePath
+	target := workingDir
+
+	for _, part := range strings.Split(filepath.ToSlash(componentFolder), "/") {
+		if len(part) == 0 {
+			continue
+		}
+
+		err := os.MkdirAll(target, 0755)
+		if err != nil {
+			return "", err
+		}
+
+		err = syncWorkingDirLevel(source, target, func(name string) bool { return name == part }, symlinkPath)
+		if err != nil {
+			return "", err
+		}
+
+		source = filepath.Join(source, part)
+		target = filepath.Join(target, part)
+	}
+
+	err := os.MkdirAll(target, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	materialize := symlinkPath
+	if mode == workingDirModeCopy {
+		materialize = copyPath
+	}
+
+	err = syncWorkingDirLevel(source, target, isTerraformGeneratedFile, materialize)
+	if err != nil {
+		return "", err
+	}
+
+	// `terraform init` updates the lock file, so it's copied to not change the lock file in the component folder from parallel runs
+	lockFile := filepath.Join(source, terraformLockFileName)
+	targetLockFile := filepath.Join(target, terraformLockFileName)
+	if _, err := os.Stat(lockFile); err == nil {
+		if _, err := os.Lstat(targetLockFile); os.IsNotExist(err) {
+			err = copyPath(lockFile, targetLockFile)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return target, nil
+}
+
+// syncWorkingDirLevel replaces the entries in the `target` folder with the entries from the `source` folder,
+// except the entries for which `skip` returns `true`, which are not changed
+func syncWorkingDirLevel(source string, target string, skip func(name string) bool, materialize func(source string, target string) error) error {
+	targetEntries, err := ioutil.ReadDir(target)
+	if err != nil {
+		return err
+	}
+	for _, entry := range targetEntries {
+		if skip(entry.Name()) {
+			continue
+		}
+		err = os.RemoveAll(filepath.Join(target, entry.Name()))
+		if err != nil {
+			return err
+		}
+	}
+
+	sourceEntries, err := ioutil.ReadDir(source)
+	if err != nil {
+		return err
+	}
+	for _, entry := range sourceEntries {
+		if skip(entry.Name()) {
+			continue
+		}
+		err = materialize(filepath.Join(source, entry.Name()), filepath.Join(target, entry.Name()))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isTerraformGeneratedFile checks if the file in the component folder is written by atmos or terraform
+// and must not be linked or copied to the working directory. These files are also kept in the working directory between runs,
+// including the state written by the `local` backend (`terraform.tfstate`, `terraform.tfstate.backup` and `terraform.tfstate.d`)
+func isTerraformGeneratedFile(name string) bool {
+	return name == terraformDataDirName ||
+		name == terraformLockFileName ||
+		strings.HasPrefix(name, terraformStateFileName) ||
+		name == terraformStateLockFileName ||
+		name == "backend.tf.json" ||
+		name == providersOverrideFileName ||
+		strings.HasSuffix(name, ".terraform.tfvars.json") ||
+		strings.HasSuffix(name, ".planfile")
+}
+
+func symlinkPath(source string, target string) error {
+	return os.Symlink(source, target)
+}
+
+// copyPath copies the file or the folder with all its files, keeping the file modes
+func copyPath(source string, target string) error {
+	return filepath.Walk(source, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(source, p)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(target, rel)
+
+		if info.IsDir() {
+			return os.MkdirAll(dest, info.Mode().Perm())
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, dest)
+		}
+
+		in, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+
+		_, err = io.Copy(out, in)
+		return err
+	})
+}
@@ -0,0 +1,50 @@
+package exec
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	c "github.com/cloudposse/atmos/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrepareTerraformWorkingDirKeepsState(t *testing.T) {
+	tmpDir := t.TempDir()
+	terraformBasePath := filepath.Join(tmpDir, "components", "terraform")
+	workingDir := filepath.Join(tmpDir, "working-dirs", "tenant1-ue2-dev", "infra-vpc")
+
+	writeWorkingDirTestFile(t, filepath.Join(terraformBasePath, "infra", "vpc", "main.tf"), "resource \"null_resource\" \"vpc\" {}\n")
+	writeWorkingDirTestFile(t, filepath.Join(terraformBasePath, "infra", "vpc", "variables.tf"), "")
+
+	var cliConfig c.Configuration
+	cliConfig.Components.Terraform.WorkingDirs.Mode = workingDirModeCopy
+
+	componentPath, err := prepareTerraformWorkingDir(cliConfig, terraformBasePath, "infra/vpc", workingDir)
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(workingDir, "infra", "vpc"), componentPath)
+
+	// The state of the `local` backend is written to the working directory
+	writeWorkingDirTestFile(t, filepath.Join(componentPath, "terraform.tfstate"), "{}")
+	writeWorkingDirTestFile(t, filepath.Join(componentPath, "terraform.tfstate.backup"), "{}")
+	writeWorkingDirTestFile(t, filepath.Join(componentPath, "terraform.tfstate.d", "tenant1-ue2-dev", "terraform.tfstate"), "{}")
+
+	// The files removed from the component folder are removed from the working directory
+	assert.Nil(t, os.Remove(filepath.Join(terraformBasePath, "infra", "vpc", "variables.tf")))
+
+	_, err = prepareTerraformWorkingDir(cliConfig, terraformBasePath, "infra/vpc", workingDir)
+	assert.Nil(t, err)
+
+	for _, name := range []string{"main.tf", "terraform.tfstate", "terraform.tfstate.backup", "terraform.tfstate.d/tenant1-ue2-dev/terraform.tfstate"} {
+		_, err = os.Stat(filepath.Join(componentPath, name))
+		assert.Nil(t, err, name)
+	}
+	_, err = os.Stat(filepath.Join(componentPath, "variables.tf"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func writeWorkingDirTestFile(t *testing.T, filePath string, content string) {
+	assert.Nil(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte(content), 0644))
+}
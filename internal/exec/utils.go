@@ -3,12 +3,13 @@ package exec
 import (
 	"errors"
 	"fmt"
+	"github.com/cloudposse/atmos/pkg/atmos"
 	c "github.com/cloudposse/atmos/pkg/config"
 	g "github.com/cloudposse/atmos/pkg/globals"
-	s "github.com/cloudposse/atmos/pkg/stack"
+	l "github.com/cloudposse/atmos/pkg/logger"
 	"github.com/cloudposse/atmos/pkg/utils"
-	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
@@ -24,121 +25,107 @@ var (
 		g.HelmfileDirFlag,
 		g.ConfigDirFlag,
 		g.StackDirFlag,
+		g.StacksArchiveFlag,
 		g.GlobalOptionsFlag,
 		g.DeployRunInitFlag,
 		g.AutoGenerateBackendFileFlag,
+		g.PlanSummaryFileFlag,
+		g.LogLevelFlag,
+	}
+
+	// The common flags without values
+	commonBoolFlags = []string{
 		g.FromPlanFlag,
+		g.PlanSummaryFlag,
+		g.CIFlag,
+		g.QuietFlag,
 	}
 )
 
-// findComponentConfig finds component config sections
-func findComponentConfig(
-	stack string,
-	stacksMap map[string]interface{},
-	componentType string,
-	component string,
-) (map[string]interface{},
-	map[interface{}]interface{},
-	map[interface{}]interface{},
-	map[interface{}]interface{},
-	string,
-	string,
-	string,
-	[]string,
-	error,
-) {
-
-	var stackSection map[interface{}]interface{}
-	var componentsSection map[string]interface{}
-	var componentTypeSection map[string]interface{}
-	var componentSection map[string]interface{}
-	var componentVarsSection map[interface{}]interface{}
-	var componentEnvSection map[interface{}]interface{}
-	var componentBackendSection map[interface{}]interface{}
-	var componentBackendType string
-	var baseComponentPath string
-	var command string
-	var componentInheritanceChain []string
-	var ok bool
-
-	if len(stack) == 0 {
-		return nil, nil, nil, nil, "", "", "", nil, errors.New("stack must be provided and must not be empty")
-	}
-	if len(component) == 0 {
-		return nil, nil, nil, nil, "", "", "", nil, errors.New("component must be provided and must not be empty")
-	}
-	if len(componentType) == 0 {
-		return nil, nil, nil, nil, "", "", "", nil, errors.New("component type must be provided and must not be empty")
-	}
-	if stackSection, ok = stacksMap[stack].(map[interface{}]interface{}); !ok {
-		return nil, nil, nil, nil, "", "", "", nil, errors.New(fmt.Sprintf("Stack '%s' does not exist", stack))
-	}
-	if componentsSection, ok = stackSection["components"].(map[string]interface{}); !ok {
-		return nil, nil, nil, nil, "", "", "", nil, errors.New(fmt.Sprintf("'components' section is missing in the stack '%s'", stack))
-	}
-	if componentTypeSection, ok = componentsSection[componentType].(map[string]interface{}); !ok {
-		return nil, nil, nil, nil, "", "", "", nil, errors.New(fmt.Sprintf("'components/%s' section is missing in the stack '%s'", componentType, stack))
-	}
-	if componentSection, ok = componentTypeSection[component].(map[string]interface{}); !ok {
-		return nil, nil, nil, nil, "", "", "", nil, errors.New(fmt.Sprintf("Invalid or missing configuration for the component '%s' in the stack '%s'", component, stack))
-	}
-	if componentVarsSection, ok = componentSection["vars"].(map[interface{}]interface{}); !ok {
-		return nil, nil, nil, nil, "", "", "", nil, errors.New(fmt.Sprintf("Missing 'vars' section for the component '%s' in the stack '%s'", component, stack))
-	}
-	if componentBackendSection, ok = componentSection["backend"].(map[interface{}]interface{}); !ok {
-		componentBackendSection = nil
-	}
-	if componentBackendType, ok = componentSection["backend_type"].(string); !ok {
-		componentBackendType = ""
-	}
-	if baseComponentPath, ok = componentSection["component"].(string); !ok {
-		baseComponentPath = ""
-	}
-	if command, ok = componentSection["command"].(string); !ok {
-		command = ""
-	}
-	if componentEnvSection, ok = componentSection["env"].(map[interface{}]interface{}); !ok {
-		componentEnvSection = map[interface{}]interface{}{}
-	}
-	if componentInheritanceChain, ok = componentSection["inheritance"].([]string); !ok {
-		componentInheritanceChain = []string{}
-	}
-
-	return componentSection,
-		componentVarsSection,
-		componentEnvSection,
-		componentBackendSection,
-		componentBackendType,
-		baseComponentPath,
-		command,
-		componentInheritanceChain,
-		nil
+// newClient loads the CLI config, applies ENV vars and command-line arguments, and creates a client to query the stacks
+func newClient(configAndStacksInfo c.ConfigAndStacksInfo) (*atmos.Client, error) {
+	cliConfig, err := c.InitConfig()
+	if err != nil {
+		return nil, withExitCode(err, ExitCodeInvalidConfig)
+	}
+
+	err = c.ProcessConfig(&cliConfig, configAndStacksInfo)
+	if err != nil {
+		return nil, withExitCode(err, ExitCodeInvalidConfig)
+	}
+
+	var client *atmos.Client
+
+	if len(configAndStacksInfo.StacksArchive) > 0 {
+		stacksFS, err := utils.ArchiveFS(configAndStacksInfo.StacksArchive)
+		if err != nil {
+			return nil, withExitCode(err, ExitCodeInvalidStacks)
+		}
+
+		// The stacks base path is the root of the archive unless the stacks directory in the archive is provided on the command line
+		if len(configAndStacksInfo.StacksDir) == 0 {
+			cliConfig.Stacks.BasePath = "."
+		}
+
+		l.Debug("Using stacks archive '%s'\n", configAndStacksInfo.StacksArchive)
+
+		client, err = atmos.NewClientFromConfigAndFS(cliConfig, stacksFS)
+		if err != nil {
+			return nil, withExitCode(err, ExitCodeInvalidConfig)
+		}
+	} else {
+		client, err = atmos.NewClientFromConfig(cliConfig)
+		if err != nil {
+			return nil, withExitCode(err, ExitCodeInvalidConfig)
+		}
+	}
+
+	// Print the stack config files
+	if l.IsLevelEnabled(l.LevelDebug) {
+		y, err := utils.ConvertToYAML(client.ProcessedConfig().StackConfigFilesRelativePaths)
+		if err != nil {
+			return nil, err
+		}
+		l.Debug("\nFound config files:\n%s", y)
+	}
+
+	return client, nil
 }
 
 // processConfigAndStacks processes CLI config and stacks
-func processConfigAndStacks(componentType string, cmd *cobra.Command, args []string) (c.ConfigAndStacksInfo, error) {
+func processConfigAndStacks(componentType string, cmd *cobra.Command, args []string) (c.ConfigAndStacksInfo, *atmos.Client, error) {
 	var configAndStacksInfo c.ConfigAndStacksInfo
 
 	if len(args) < 1 {
-		return configAndStacksInfo, errors.New("invalid number of arguments")
+		return configAndStacksInfo, nil, withExitCode(errors.New("invalid number of arguments"), ExitCodeInvalidArguments)
 	}
 
 	cmd.DisableFlagParsing = false
 
 	err := cmd.ParseFlags(args)
 	if err != nil {
-		return configAndStacksInfo, err
+		return configAndStacksInfo, nil, withExitCode(err, ExitCodeInvalidArguments)
 	}
 	flags := cmd.Flags()
 
 	configAndStacksInfo.Stack, err = flags.GetString("stack")
 	if err != nil {
-		return configAndStacksInfo, err
+		return configAndStacksInfo, nil, err
 	}
 
 	argsAndFlagsInfo, err := processArgsAndFlags(args)
 	if err != nil {
-		return configAndStacksInfo, err
+		return configAndStacksInfo, nil, withExitCode(err, ExitCodeInvalidArguments)
+	}
+
+	if argsAndFlagsInfo.CI {
+		enableCIMode()
+	}
+	configAndStacksInfo.CI = argsAndFlagsInfo.CI || ciFlag(cmd)
+
+	err = ProcessLogLevelFlags(argsAndFlagsInfo.LogLevel, argsAndFlagsInfo.Quiet)
+	if err != nil {
+		return configAndStacksInfo, nil, err
 	}
 
 	configAndStacksInfo.AdditionalArgsAndFlags = argsAndFlagsInfo.AdditionalArgsAndFlags
@@ -148,177 +135,97 @@ func processConfigAndStacks(componentType string, cmd *cobra.Command, args []str
 	configAndStacksInfo.TerraformDir = argsAndFlagsInfo.TerraformDir
 	configAndStacksInfo.HelmfileDir = argsAndFlagsInfo.HelmfileDir
 	configAndStacksInfo.StacksDir = argsAndFlagsInfo.StacksDir
+	configAndStacksInfo.StacksArchive = argsAndFlagsInfo.StacksArchive
 	configAndStacksInfo.ConfigDir = argsAndFlagsInfo.ConfigDir
 	configAndStacksInfo.DeployRunInit = argsAndFlagsInfo.DeployRunInit
 	configAndStacksInfo.AutoGenerateBackendFile = argsAndFlagsInfo.AutoGenerateBackendFile
 	configAndStacksInfo.UseTerraformPlan = argsAndFlagsInfo.UseTerraformPlan
+	configAndStacksInfo.PlanSummary = argsAndFlagsInfo.PlanSummary
+	configAndStacksInfo.PlanSummaryFile = argsAndFlagsInfo.PlanSummaryFile
 
 	// Check if component was provided
 	if len(configAndStacksInfo.ComponentFromArg) < 1 {
-		return configAndStacksInfo, errors.New("'component' is required")
+		return configAndStacksInfo, nil, withExitCode(errors.New("'component' is required"), ExitCodeInvalidArguments)
+	}
+
+	if len(configAndStacksInfo.Stack) < 1 {
+		return configAndStacksInfo, nil, withExitCode(errors.New("stack must be specified"), ExitCodeInvalidArguments)
 	}
 
 	// Process and merge CLI configurations
-	err = c.InitConfig()
+	client, err := newClient(configAndStacksInfo)
 	if err != nil {
-		return configAndStacksInfo, err
+		return configAndStacksInfo, nil, err
 	}
 
-	err = c.ProcessConfig(configAndStacksInfo)
+	configAndStacksInfo, err = processComponentConfig(client, componentType, configAndStacksInfo)
 	if err != nil {
-		return configAndStacksInfo, err
+		return configAndStacksInfo, nil, err
 	}
 
-	// Process stack config file(s)
-	_, stacksMap, err := s.ProcessYAMLConfigFiles(
-		c.ProcessedConfig.StacksBaseAbsolutePath,
-		c.ProcessedConfig.StackConfigFilesAbsolutePaths,
-		false,
-		true)
-
+	// The sensitive values of the component are masked in the logs of the command
+	masker, err := newComponentMasker(client.Config(), configAndStacksInfo.ComponentSettingsSection,
+		configAndStacksInfo.ComponentVarsSection, configAndStacksInfo.ComponentEnvSection)
 	if err != nil {
-		return configAndStacksInfo, err
+		return configAndStacksInfo, nil, err
 	}
+	configAndStacksInfo.Masker = masker
 
-	// Print the stack config files
-	if g.LogVerbose {
-		fmt.Println()
-		var msg string
-		if c.ProcessedConfig.StackType == "Directory" {
-			msg = "Found the config file for the provided stack:"
-		} else {
-			msg = "Found config files:"
-		}
-		color.Cyan(msg)
-		err = utils.PrintAsYAML(c.ProcessedConfig.StackConfigFilesRelativePaths)
+	if l.IsLevelEnabled(l.LevelInfo) {
+		y, err := utils.ConvertToYAML(masker.Map(configAndStacksInfo.ComponentVarsSection))
 		if err != nil {
-			return configAndStacksInfo, err
+			return configAndStacksInfo, nil, err
 		}
+		l.Info("\nVariables for the component '%s' in the stack '%s':\n\n%s", configAndStacksInfo.ComponentFromArg, configAndStacksInfo.Stack, y)
 	}
 
-	if len(c.Config.Stacks.NamePattern) < 1 {
-		return configAndStacksInfo,
-			errors.New("stack name pattern must be provided in 'stacks.name_pattern' config or 'ATMOS_STACKS_NAME_PATTERN' ENV variable")
-	}
-
-	stackNamePatternParts := strings.Split(c.Config.Stacks.NamePattern, "-")
-
-	// Check and process stacks
-	if c.ProcessedConfig.StackType == "Directory" {
-		_, configAndStacksInfo.ComponentVarsSection,
-			configAndStacksInfo.ComponentEnvSection,
-			configAndStacksInfo.ComponentBackendSection,
-			configAndStacksInfo.ComponentBackendType,
-			configAndStacksInfo.BaseComponentPath,
-			configAndStacksInfo.Command,
-			configAndStacksInfo.ComponentInheritanceChain,
-			err = findComponentConfig(configAndStacksInfo.Stack, stacksMap, componentType, configAndStacksInfo.ComponentFromArg)
-		if err != nil {
-			return configAndStacksInfo, err
-		}
-
-		configAndStacksInfo.ComponentEnvList = convertEnvVars(configAndStacksInfo.ComponentEnvSection)
-	} else {
-		if g.LogVerbose {
-			color.Cyan("Searching for stack config where the component '%s' is defined\n", configAndStacksInfo.ComponentFromArg)
-		}
-
-		stackParts := strings.Split(configAndStacksInfo.Stack, "-")
-		if len(stackParts) != len(stackNamePatternParts) {
-			return configAndStacksInfo,
-				errors.New(fmt.Sprintf("Stack '%s' does not match the stack name pattern '%s'",
-					configAndStacksInfo.Stack,
-					c.Config.Stacks.NamePattern))
-		}
-
-		var tenant string
-		var environment string
-		var stage string
-		var tenantFound bool
-		var environmentFound bool
-		var stageFound bool
-
-		for i, part := range stackNamePatternParts {
-			if part == "{tenant}" {
-				tenant = stackParts[i]
-			} else if part == "{environment}" {
-				environment = stackParts[i]
-			} else if part == "{stage}" {
-				stage = stackParts[i]
-			}
-		}
-
-		for stackName := range stacksMap {
-			_, configAndStacksInfo.ComponentVarsSection,
-				configAndStacksInfo.ComponentEnvSection,
-				configAndStacksInfo.ComponentBackendSection,
-				configAndStacksInfo.ComponentBackendType,
-				configAndStacksInfo.BaseComponentPath,
-				configAndStacksInfo.Command,
-				configAndStacksInfo.ComponentInheritanceChain,
-				err = findComponentConfig(stackName, stacksMap, componentType, configAndStacksInfo.ComponentFromArg)
-			if err != nil {
-				continue
-			}
-
-			configAndStacksInfo.ComponentEnvList = convertEnvVars(configAndStacksInfo.ComponentEnvSection)
-
-			tenantFound = true
-			environmentFound = true
-			stageFound = true
+	return configAndStacksInfo, client, nil
+}
 
-			// Search for tenant in stack
-			if len(tenant) > 0 {
-				if tenantInStack, ok := configAndStacksInfo.ComponentVarsSection["tenant"].(string); !ok || tenantInStack != tenant {
-					tenantFound = false
-				}
-			}
+// processComponentConfig finds the component in the stack and adds the component config and context to the provided info
+func processComponentConfig(client *atmos.Client, componentType string, configAndStacksInfo c.ConfigAndStacksInfo) (c.ConfigAndStacksInfo, error) {
+	cliConfig := client.Config()
 
-			// Search for environment in stack
-			if len(environment) > 0 {
-				if environmentInStack, ok := configAndStacksInfo.ComponentVarsSection["environment"].(string); !ok || environmentInStack != environment {
-					environmentFound = false
-				}
-			}
+	if len(cliConfig.Stacks.NamePattern) < 1 {
+		return configAndStacksInfo, withExitCode(
+			errors.New("stack name pattern must be provided in 'stacks.name_pattern' config or 'ATMOS_STACKS_NAME_PATTERN' ENV variable"),
+			ExitCodeInvalidConfig)
+	}
 
-			// Search for stage in stack
-			if len(stage) > 0 {
-				if stageInStack, ok := configAndStacksInfo.ComponentVarsSection["stage"].(string); !ok || stageInStack != stage {
-					stageFound = false
-				}
-			}
+	// Find the component in the stacks
+	component, err := client.FindComponent(configAndStacksInfo.Stack, componentType, configAndStacksInfo.ComponentFromArg)
+	if err != nil {
+		return configAndStacksInfo, withExitCode(err, ExitCodeInvalidStacks)
+	}
 
-			if tenantFound == true && environmentFound == true && stageFound == true {
-				if g.LogVerbose {
-					color.Green("Found stack config for the component '%s' in the stack '%s'\n\n", configAndStacksInfo.ComponentFromArg, stackName)
-				}
-				configAndStacksInfo.Stack = stackName
-				break
-			}
-		}
+	if component.StackFile != configAndStacksInfo.Stack {
+		l.Debug("Found stack config for the component '%s' in the stack '%s'\n", configAndStacksInfo.ComponentFromArg, component.StackFile)
+	}
+	configAndStacksInfo.Stack = component.StackFile
 
-		if tenantFound == false || environmentFound == false || stageFound == false {
-			return configAndStacksInfo,
-				errors.New(fmt.Sprintf("\nCould not find config for the component '%s' in the stack '%s'.\n"+
-					"Check that all attributes in the stack name pattern '%s' are defined in the stack config files.\n"+
-					"Are the component and stack names correct? Did you forget an import?",
-					configAndStacksInfo.ComponentFromArg,
-					configAndStacksInfo.Stack,
-					c.Config.Stacks.NamePattern,
-				))
-		}
+	configAndStacksInfo.ComponentVarsSection = component.Config.Vars
+	if configAndStacksInfo.ComponentVarsSection == nil {
+		configAndStacksInfo.ComponentVarsSection = map[string]interface{}{}
 	}
+	configAndStacksInfo.ComponentEnvSection = component.Config.Env
+	configAndStacksInfo.ComponentSettingsSection = component.Config.Settings
+	configAndStacksInfo.ComponentBackendSection = component.Config.Backend
+	configAndStacksInfo.ComponentBackendType = component.Config.BackendType
+	configAndStacksInfo.ComponentProvidersSection = component.Config.Providers
+	configAndStacksInfo.TerraformWorkspace = component.Workspace
+	configAndStacksInfo.BaseComponentPath = component.Config.BaseComponent
+	configAndStacksInfo.Command = component.Config.Command
+	configAndStacksInfo.ComponentInheritanceChain = component.Config.Inheritance
+	if configAndStacksInfo.ComponentInheritanceChain == nil {
+		configAndStacksInfo.ComponentInheritanceChain = []string{}
+	}
+
+	configAndStacksInfo.ComponentEnvList = convertEnvVars(configAndStacksInfo.ComponentEnvSection)
 
 	if len(configAndStacksInfo.Command) == 0 {
 		configAndStacksInfo.Command = componentType
 	}
 
-	color.Cyan("\nVariables for the component '%s' in the stack '%s':\n\n", configAndStacksInfo.ComponentFromArg, configAndStacksInfo.Stack)
-	err = utils.PrintAsYAML(configAndStacksInfo.ComponentVarsSection)
-	if err != nil {
-		return configAndStacksInfo, err
-	}
-
 	configAndStacksInfo.ComponentFolderPrefix = ""
 	configAndStacksInfo.ComponentNamePrefix = ""
 
@@ -346,9 +253,9 @@ func processConfigAndStacks(componentType string, cmd *cobra.Command, args []str
 
 	// Process context
 	configAndStacksInfo.Context = c.GetContextFromVars(configAndStacksInfo.ComponentVarsSection)
-	configAndStacksInfo.ContextPrefix, err = c.GetContextPrefix(configAndStacksInfo.Stack, configAndStacksInfo.Context, c.Config.Stacks.NamePattern)
+	configAndStacksInfo.ContextPrefix, err = c.GetContextPrefix(configAndStacksInfo.Stack, configAndStacksInfo.Context, cliConfig.Stacks.NamePattern)
 	if err != nil {
-		return configAndStacksInfo, err
+		return configAndStacksInfo, withExitCode(err, ExitCodeInvalidStacks)
 	}
 
 	return configAndStacksInfo, nil
@@ -424,6 +331,19 @@ func processArgsAndFlags(inputArgsAndFlags []string) (c.ArgsAndFlagsInfo, error)
 			info.ConfigDir = stacksDirFlagParts[1]
 		}
 
+		if arg == g.StacksArchiveFlag {
+			if len(inputArgsAndFlags) <= (i + 1) {
+				return info, errors.New(fmt.Sprintf("invalid flag: %s", arg))
+			}
+			info.StacksArchive = inputArgsAndFlags[i+1]
+		} else if strings.HasPrefix(arg+"=", g.StacksArchiveFlag) {
+			var stacksArchiveFlagParts = strings.Split(arg, "=")
+			if len(stacksArchiveFlagParts) != 2 {
+				return info, errors.New(fmt.Sprintf("invalid flag: %s", arg))
+			}
+			info.StacksArchive = stacksArchiveFlagParts[1]
+		}
+
 		if arg == g.DeployRunInitFlag {
 			if len(inputArgsAndFlags) <= (i + 1) {
 				return info, errors.New(fmt.Sprintf("invalid flag: %s", arg))
@@ -450,10 +370,48 @@ func processArgsAndFlags(inputArgsAndFlags []string) (c.ArgsAndFlagsInfo, error)
 			info.AutoGenerateBackendFile = autoGenerateBackendFileFlagParts[1]
 		}
 
+		if arg == g.PlanSummaryFileFlag {
+			if len(inputArgsAndFlags) <= (i + 1) {
+				return info, errors.New(fmt.Sprintf("invalid flag: %s", arg))
+			}
+			info.PlanSummaryFile = inputArgsAndFlags[i+1]
+		} else if strings.HasPrefix(arg+"=", g.PlanSummaryFileFlag) {
+			var planSummaryFileFlagParts = strings.Split(arg, "=")
+			if len(planSummaryFileFlagParts) != 2 {
+				return info, errors.New(fmt.Sprintf("invalid flag: %s", arg))
+			}
+			info.PlanSummaryFile = planSummaryFileFlagParts[1]
+		}
+
+		if arg == g.LogLevelFlag {
+			if len(inputArgsAndFlags) <= (i + 1) {
+				return info, errors.New(fmt.Sprintf("invalid flag: %s", arg))
+			}
+			info.LogLevel = inputArgsAndFlags[i+1]
+		} else if strings.HasPrefix(arg+"=", g.LogLevelFlag) {
+			var logLevelFlagParts = strings.Split(arg, "=")
+			if len(logLevelFlagParts) != 2 {
+				return info, errors.New(fmt.Sprintf("invalid flag: %s", arg))
+			}
+			info.LogLevel = logLevelFlagParts[1]
+		}
+
 		if arg == g.FromPlanFlag {
 			info.UseTerraformPlan = true
 		}
 
+		if arg == g.PlanSummaryFlag {
+			info.PlanSummary = true
+		}
+
+		if arg == g.CIFlag {
+			info.CI = true
+		}
+
+		if arg == g.QuietFlag {
+			info.Quiet = true
+		}
+
 		for _, f := range commonFlags {
 			if arg == f {
 				indexesToRemove = append(indexesToRemove, i)
@@ -462,6 +420,10 @@ func processArgsAndFlags(inputArgsAndFlags []string) (c.ArgsAndFlagsInfo, error)
 				indexesToRemove = append(indexesToRemove, i)
 			}
 		}
+
+		if utils.SliceContainsString(commonBoolFlags, arg) {
+			indexesToRemove = append(indexesToRemove, i)
+		}
 	}
 
 	for i, arg := range inputArgsAndFlags {
@@ -496,21 +458,44 @@ func processArgsAndFlags(inputArgsAndFlags []string) (c.ArgsAndFlagsInfo, error)
 }
 
 // execCommand prints and executes the provided command with args and flags
-func execCommand(command string, args []string, dir string, env []string) error {
+func execCommand(log *l.Logger, ci bool, command string, args []string, dir string, env []string) error {
+	return execCommandWithIO(log, ci, command, args, dir, env, os.Stdin, os.Stdout, os.Stderr)
+}
+
+// execCommandWithIO executes the command with the provided stdin, stdout and stderr. The command is printed to the logger.
+// In the CI mode, the output of the command is printed in a `::group::` / `::endgroup::` block
+func execCommandWithIO(log *l.Logger, ci bool, command string, args []string, dir string, env []string, stdin io.Reader, out io.Writer, stderr io.Writer) error {
 	cmd := exec.Command(command, args...)
 	cmd.Env = append(os.Environ(), env...)
 	cmd.Dir = dir
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stdout
+	cmd.Stdin = stdin
+	cmd.Stdout = out
+	cmd.Stderr = stderr
+
+	log.Info("\nExecuting command:\n%s", cmd.String())
+
+	// Mark the output of the command for the CI systems (e.g. collapsible groups in the GitHub Actions logs)
+	if ci {
+		fmt.Fprintln(out, fmt.Sprintf("::group::%s", cmd.String()))
+		defer fmt.Fprintln(out, "::endgroup::")
+	}
 
-	fmt.Println()
-	color.Cyan("Executing command:\n")
-	fmt.Println(cmd.String())
 	return cmd.Run()
 }
 
-func generateComponentBackendConfig(backendType string, backendConfig map[interface{}]interface{}) map[string]interface{} {
+// execCommandOutput executes the command and returns its output. The command is printed to the logger
+func execCommandOutput(log *l.Logger, command string, args []string, dir string, env []string, stderr io.Writer) ([]byte, error) {
+	cmd := exec.Command(command, args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Dir = dir
+	cmd.Stderr = stderr
+
+	log.Info("\nExecuting command:\n%s", cmd.String())
+
+	return cmd.Output()
+}
+
+func generateComponentBackendConfig(backendType string, backendConfig map[string]interface{}) map[string]interface{} {
 	return map[string]interface{}{
 		"terraform": map[string]interface{}{
 			"backend": map[string]interface{}{
@@ -520,8 +505,15 @@ func generateComponentBackendConfig(backendType string, backendConfig map[interf
 	}
 }
 
+// generateComponentProviderOverrides returns the provider overrides in the terraform JSON format
+func generateComponentProviderOverrides(providers map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"provider": providers,
+	}
+}
+
 // Convert ENV vars from a map to a list of strings in the format ["key1=val1", "key2=val2", "key3=val3" ...]
-func convertEnvVars(envVarsMap map[interface{}]interface{}) []string {
+func convertEnvVars(envVarsMap map[string]interface{}) []string {
 	res := []string{}
 	if envVarsMap != nil {
 		for k, v := range envVarsMap {
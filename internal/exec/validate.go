@@ -0,0 +1,319 @@
+package exec
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/cloudposse/atmos/pkg/atmos"
+	c "github.com/cloudposse/atmos/pkg/config"
+	l "github.com/cloudposse/atmos/pkg/logger"
+	u "github.com/cloudposse/atmos/pkg/utils"
+	v "github.com/cloudposse/atmos/pkg/validate"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// ExecuteValidateComponent executes `validate component` command
+func ExecuteValidateComponent(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return withExitCode(errors.New("invalid arguments. The command requires one argument `component`"), ExitCodeInvalidArguments)
+	}
+	flags := cmd.Flags()
+
+	stack, err := flags.GetString("stack")
+	if err != nil {
+		return err
+	}
+
+	stacksArchive, err := flags.GetString("stacks-archive")
+	if err != nil {
+		return err
+	}
+
+	var configAndStacksInfo c.ConfigAndStacksInfo
+	configAndStacksInfo.CI = ciFlag(cmd)
+	configAndStacksInfo.Stack = stack
+	configAndStacksInfo.StacksArchive = stacksArchive
+
+	client, err := newClient(configAndStacksInfo)
+	if err != nil {
+		return err
+	}
+
+	component, err := client.Component(stack, args[0])
+	if err != nil {
+		return err
+	}
+
+	return validateComponents(client, []atmos.Component{component})
+}
+
+// ExecuteValidateStacks executes `validate stacks` command
+func ExecuteValidateStacks(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+
+	stacksArchive, err := flags.GetString("stacks-archive")
+	if err != nil {
+		return err
+	}
+
+	format, err := flags.GetString("format")
+	if err != nil {
+		return err
+	}
+	if format != "text" && format != "json" && format != "sarif" {
+		return errors.New(fmt.Sprintf("Invalid '--format' flag '%s'. Valid values are 'text', 'json' and 'sarif'", format))
+	}
+
+	file, err := flags.GetString("file")
+	if err != nil {
+		return err
+	}
+
+	var configAndStacksInfo c.ConfigAndStacksInfo
+	configAndStacksInfo.CI = ciFlag(cmd)
+	configAndStacksInfo.StacksArchive = stacksArchive
+
+	client, err := newClient(configAndStacksInfo)
+	if err != nil {
+		return err
+	}
+
+	issues, err := v.LintStacks(client)
+	if err != nil {
+		return err
+	}
+
+	var report interface{}
+	switch format {
+	case "json":
+		report = issues
+		if issues == nil {
+			report = []v.Issue{}
+		}
+	case "sarif":
+		report = v.ToSARIF(issues)
+	}
+
+	if len(file) > 0 {
+		if format == "text" {
+			return errors.New("'--file' flag requires '--format json' or '--format sarif'")
+		}
+		err = u.WriteToFileAsJSON(file, report, 0644)
+		if err != nil {
+			return err
+		}
+	} else if format != "text" {
+		err = u.PrintAsJSON(report)
+		if err != nil {
+			return err
+		}
+	}
+
+	errorsCount := 0
+	for _, issue := range issues {
+		if issue.Level == v.LevelError {
+			errorsCount++
+		}
+		if format == "text" {
+			if issue.Level == v.LevelError {
+				l.Error("%s", issue)
+			} else {
+				l.Warn("%s", issue)
+			}
+		}
+	}
+
+	if errorsCount > 0 {
+		return withExitCode(errors.New(fmt.Sprintf("Found %d errors and %d warnings in the stacks", errorsCount, len(issues)-errorsCount)),
+			ExitCodeValidationFailed)
+	}
+
+	if format == "text" {
+		l.Info("Validated %d stacks, found %d warnings", len(client.ProcessedConfig().StackConfigFilesAbsolutePaths), len(issues))
+	}
+	return nil
+}
+
+// validateComponents validates the components with the validations from their `settings.validation` sections and prints the validation errors
+func validateComponents(client *atmos.Client, components []atmos.Component) error {
+	validator := v.NewValidator(client.ProcessedConfig().SchemasDirAbsolutePath)
+
+	var validationErrors []v.Error
+	validated := 0
+
+	for _, component := range components {
+		validations, err := v.GetValidations(component)
+		if err != nil {
+			return err
+		}
+		if len(validations) == 0 {
+			continue
+		}
+
+		res, err := validator.ValidateComponent(component)
+		if err != nil {
+			return err
+		}
+
+		validated++
+		validationErrors = append(validationErrors, res...)
+	}
+
+	if len(validationErrors) > 0 {
+		for _, validationError := range validationErrors {
+			l.Error("%s", validationError)
+		}
+		return withExitCode(errors.New(fmt.Sprintf("Found %d validation errors", len(validationErrors))), ExitCodeValidationFailed)
+	}
+
+	l.Info("Validated %d components, no validation errors found", validated)
+	return nil
+}
+
+// ExecuteValidatePolicies executes `validate policies` command
+func ExecuteValidatePolicies(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+
+	stacksArchive, err := flags.GetString("stacks-archive")
+	if err != nil {
+		return err
+	}
+
+	var configAndStacksInfo c.ConfigAndStacksInfo
+	configAndStacksInfo.CI = ciFlag(cmd)
+	configAndStacksInfo.StacksArchive = stacksArchive
+
+	client, err := newClient(configAndStacksInfo)
+	if err != nil {
+		return err
+	}
+
+	policyEngine := v.NewPolicyEngine(client.ProcessedConfig().PoliciesDirAbsolutePath)
+
+	policies, err := policyEngine.Policies()
+	if err != nil {
+		return err
+	}
+	if len(policies) == 0 {
+		l.Warn("No policies found in '%s'", client.ProcessedConfig().PoliciesDirAbsolutePath)
+		return nil
+	}
+
+	stacks, err := client.Stacks()
+	if err != nil {
+		return err
+	}
+
+	var violations []v.PolicyViolation
+	evaluated := 0
+
+	for _, stack := range stacks {
+		for _, component := range stack.Components {
+			res, err := policyEngine.EvaluateComponent(component)
+			if err != nil {
+				return err
+			}
+			evaluated++
+			violations = append(violations, res...)
+		}
+	}
+
+	denied := printPolicyViolations(violations)
+	if denied > 0 {
+		return withExitCode(errors.New(fmt.Sprintf("Found %d policy violations", denied)), ExitCodeValidationFailed)
+	}
+
+	l.Info("Evaluated %d policies against %d components, no policy violations found", len(policies), evaluated)
+	return nil
+}
+
+// checkComponentPolicies evaluates the policies against the component and returns an error if the component violates a `deny` rule
+func checkComponentPolicies(client *atmos.Client, component atmos.Component) error {
+	policyEngine := v.NewPolicyEngine(client.ProcessedConfig().PoliciesDirAbsolutePath)
+
+	violations, err := policyEngine.EvaluateComponent(component)
+	if err != nil {
+		return err
+	}
+
+	denied := printPolicyViolations(violations)
+	if denied > 0 {
+		return errors.New(fmt.Sprintf("The component '%s' in the stack '%s' violates %d policies", component.Name, component.Stack, denied))
+	}
+
+	return nil
+}
+
+// printPolicyViolations prints the policy violations and returns the number of `deny` violations
+func printPolicyViolations(violations []v.PolicyViolation) int {
+	denied := 0
+	for _, violation := range violations {
+		if violation.Level == v.PolicyLevelDeny {
+			l.Error("%s", violation)
+			denied++
+		} else {
+			l.Warn("%s", violation)
+		}
+	}
+	return denied
+}
+
+// ExecuteTerraformValidateVars executes `terraform validate-vars` command
+func ExecuteTerraformValidateVars(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return withExitCode(errors.New("invalid arguments. The command requires one argument `component`"), ExitCodeInvalidArguments)
+	}
+	flags := cmd.Flags()
+
+	stack, err := flags.GetString("stack")
+	if err != nil {
+		return err
+	}
+
+	stacksArchive, err := flags.GetString("stacks-archive")
+	if err != nil {
+		return err
+	}
+
+	var configAndStacksInfo c.ConfigAndStacksInfo
+	configAndStacksInfo.CI = ciFlag(cmd)
+	configAndStacksInfo.Stack = stack
+	configAndStacksInfo.StacksArchive = stacksArchive
+
+	client, err := newClient(configAndStacksInfo)
+	if err != nil {
+		return err
+	}
+
+	component, err := client.FindComponent(stack, "terraform", args[0])
+	if err != nil {
+		return err
+	}
+
+	finalComponent := component.Name
+	if len(component.Config.BaseComponent) > 0 {
+		finalComponent = component.Config.BaseComponent
+	}
+	componentPath := path.Join(client.ProcessedConfig().TerraformDirAbsolutePath, finalComponent)
+
+	issues, err := v.ValidateComponentVars(component, componentPath)
+	if err != nil {
+		return err
+	}
+
+	for _, issue := range issues {
+		if issue.Level == v.LevelError {
+			l.Error("%s: %s", issue.Level, issue.Message)
+		} else {
+			l.Warn("%s: %s", issue.Level, issue.Message)
+		}
+	}
+	if v.HasErrors(issues) {
+		return withExitCode(errors.New(fmt.Sprintf("The vars of the component '%s' in the stack '%s' do not match the variables declared in '%s'",
+			component.Name, stack, componentPath)), ExitCodeValidationFailed)
+	}
+
+	l.Info("The vars of the component '%s' in the stack '%s' match the variables declared in '%s'", component.Name, stack, componentPath)
+	return nil
+}
@@ -4,13 +4,15 @@ import (
 	"os"
 
 	"github.com/cloudposse/atmos/cmd"
-	"github.com/fatih/color"
+	e "github.com/cloudposse/atmos/internal/exec"
+	l "github.com/cloudposse/atmos/pkg/logger"
 )
 
 func main() {
+	// The errors of the commands are handled in the commands. The errors returned here are the command-line parsing errors
 	err := cmd.Execute()
 	if err != nil {
-		color.Red("%s", err)
-		os.Exit(1)
+		l.Error("%s", err)
+		os.Exit(e.ExitCodeInvalidArguments)
 	}
 }
@@ -0,0 +1,559 @@
+package atmos
+
+import (
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"sync"
+
+	c "github.com/cloudposse/atmos/pkg/config"
+	s "github.com/cloudposse/atmos/pkg/stack"
+	u "github.com/cloudposse/atmos/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+var (
+	componentTypes = []string{"terraform", "helmfile"}
+)
+
+// Options holds the explicit settings used to construct a Client.
+// Empty fields fall back to the default CLI configuration
+type Options struct {
+	// FS is the filesystem to read the stack config files from (e.g. `embed.FS`, `fstest.MapFS`, or a stacks archive).
+	// If set, StacksBasePath is relative to the root of FS. If not set, the stack config files are read from the OS filesystem
+	FS                fs.FS
+	StacksBasePath    string
+	IncludedPaths     []string
+	ExcludedPaths     []string
+	StackNamePattern  string
+	ImportsCacheDir   string
+	TerraformBasePath string
+	HelmfileBasePath  string
+	SchemasBasePath   string
+	PoliciesBasePath  string
+	// TerraformWorkspacePattern is the pattern of the terraform workspace names (see `config.GetTerraformWorkspace`)
+	TerraformWorkspacePattern string
+	// CI enables the CI mode in the CLI configuration of the client (see `config.Configuration`)
+	CI bool
+}
+
+// Component is a component as defined in a stack
+type Component struct {
+	Name      string            `yaml:"component" json:"component"`
+	Type      string            `yaml:"component_type" json:"component_type"`
+	Stack     string            `yaml:"stack" json:"stack"`
+	StackFile string            `yaml:"stack_file" json:"stack_file"`
+	Workspace string            `yaml:"workspace" json:"workspace"`
+	Config    s.ComponentConfig `yaml:"config" json:"config"`
+}
+
+// Stack is a processed stack config file with all its components
+type Stack struct {
+	Name       string      `yaml:"stack" json:"stack"`
+	Imports    []string    `yaml:"imports" json:"imports"`
+	Components []Component `yaml:"components" json:"components"`
+}
+
+// Client loads and queries stacks. It does not use any package-level state,
+// so several clients (e.g. for different repositories) can be used concurrently
+type Client struct {
+	config          c.Configuration
+	processedConfig c.ProcessedConfiguration
+	fileSystem      *s.FileSystem
+
+	stacksOnce sync.Once
+	stacks     map[string]interface{}
+	stacksErr  error
+	// refErrors are the errors of the components with the `!ref` references which can't be resolved
+	refErrors map[refLocation]error
+
+	// outputs are the cached outputs of the terraform components (see `TerraformOutputs`)
+	outputs sync.Map
+}
+
+// NewClient creates a client from the provided options
+func NewClient(opts Options) (*Client, error) {
+	config := c.DefaultConfig()
+
+	if len(opts.StacksBasePath) > 0 {
+		config.Stacks.BasePath = opts.StacksBasePath
+	}
+	if len(opts.IncludedPaths) > 0 {
+		config.Stacks.IncludedPaths = opts.IncludedPaths
+	}
+	if len(opts.ExcludedPaths) > 0 {
+		config.Stacks.ExcludedPaths = opts.ExcludedPaths
+	}
+	if len(opts.StackNamePattern) > 0 {
+		config.Stacks.NamePattern = opts.StackNamePattern
+	}
+	if len(opts.ImportsCacheDir) > 0 {
+		config.Stacks.ImportsCacheDir = opts.ImportsCacheDir
+	}
+	if len(opts.TerraformBasePath) > 0 {
+		config.Components.Terraform.BasePath = opts.TerraformBasePath
+	}
+	if len(opts.TerraformWorkspacePattern) > 0 {
+		config.Components.Terraform.WorkspacePattern = opts.TerraformWorkspacePattern
+	}
+	if len(opts.HelmfileBasePath) > 0 {
+		config.Components.Helmfile.BasePath = opts.HelmfileBasePath
+	}
+	if len(opts.SchemasBasePath) > 0 {
+		config.Schemas.BasePath = opts.SchemasBasePath
+	}
+	if len(opts.PoliciesBasePath) > 0 {
+		config.Policies.BasePath = opts.PoliciesBasePath
+	}
+	config.CI = opts.CI
+
+	if opts.FS != nil {
+		if len(opts.StacksBasePath) == 0 {
+			config.Stacks.BasePath = "."
+		}
+		return NewClientFromConfigAndFS(config, opts.FS)
+	}
+
+	return NewClientFromConfig(config)
+}
+
+// NewClientFromConfig creates a client from a CLI configuration (e.g. the one returned by `config.InitConfig`)
+func NewClientFromConfig(config c.Configuration) (*Client, error) {
+	return newClient(config, s.NewFileSystem(nil))
+}
+
+// NewClientFromConfigAndFS creates a client from a CLI configuration which reads the stack config files from the filesystem.
+// `stacks.base_path` in the CLI configuration is relative to the root of the filesystem
+func NewClientFromConfigAndFS(config c.Configuration, fsys fs.FS) (*Client, error) {
+	if fsys == nil {
+		return nil, errors.New("filesystem must be provided")
+	}
+	return newClient(config, s.NewFileSystem(fsys))
+}
+
+func newClient(config c.Configuration, fileSystem *s.FileSystem) (*Client, error) {
+	fileSystem.SetImportsCacheDir(config.Stacks.ImportsCacheDir)
+	fileSystem.SetExecTagEnabled(config.Stacks.YAMLTags.ExecEnabled)
+
+	processedConfig, err := c.ProcessStacksConfigFS(config, fileSystem)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		config:          config,
+		processedConfig: processedConfig,
+		fileSystem:      fileSystem,
+	}, nil
+}
+
+// Config returns the CLI configuration used by the client
+func (cl *Client) Config() c.Configuration {
+	return cl.config
+}
+
+// ImportSources returns the git repositories and archives fetched for the imports in the stacks.
+// The stacks are processed if they have not been processed yet
+func (cl *Client) ImportSources() ([]s.ImportSource, error) {
+	_, err := cl.StacksMap()
+	if err != nil {
+		return nil, err
+	}
+	return cl.fileSystem.ImportSources(), nil
+}
+
+// Lock returns the resolved imports of all stacks with the checksums of the imported files.
+// The stacks are processed if they have not been processed yet
+func (cl *Client) Lock() (s.StacksLock, error) {
+	_, err := cl.StacksMap()
+	if err != nil {
+		return s.StacksLock{}, err
+	}
+	return cl.fileSystem.Lock(), nil
+}
+
+// FileSystem returns the filesystem the client reads the stack config files from
+func (cl *Client) FileSystem() *s.FileSystem {
+	return cl.fileSystem
+}
+
+// ProcessedConfig returns the absolute paths and the stack config files calculated from the CLI configuration
+func (cl *Client) ProcessedConfig() c.ProcessedConfiguration {
+	return cl.processedConfig
+}
+
+// StacksMap processes all stack config files (once per client) and returns the stack configs
+// keyed by the stack config file name relative to the stacks base path
+func (cl *Client) StacksMap() (map[string]interface{}, error) {
+	cl.stacksOnce.Do(func() {
+		_, cl.stacks, cl.stacksErr = cl.fileSystem.ProcessYAMLConfigFiles(
+			cl.processedConfig.StacksBaseAbsolutePath,
+			cl.processedConfig.StackConfigFilesAbsolutePaths,
+			true,
+			true)
+		if cl.stacksErr == nil {
+			cl.refErrors = cl.resolveRefs(cl.stacks)
+		}
+	})
+
+	return cl.stacks, cl.stacksErr
+}
+
+// Stacks returns all stacks sorted by the stack config file name
+func (cl *Client) Stacks() ([]Stack, error) {
+	stacksMap, err := cl.StacksMap()
+	if err != nil {
+		return nil, err
+	}
+
+	var res []Stack
+	for _, stackFile := range u.StringKeysFromMap(stacksMap) {
+		st, err := cl.stackFromFile(stacksMap, stackFile, nil)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, st)
+	}
+
+	return res, nil
+}
+
+// DescribeStack returns the stack with the provided name.
+// The name can be a stack config file name (e.g. `tenant1/ue2/dev`) or a logical stack name matching `stacks.name_pattern` (e.g. `tenant1-ue2-dev`)
+func (cl *Client) DescribeStack(stack string) (Stack, error) {
+	stacksMap, err := cl.StacksMap()
+	if err != nil {
+		return Stack{}, err
+	}
+
+	stackFiles, isLogical, err := cl.findStackFiles(stacksMap, stack)
+	if err != nil {
+		return Stack{}, err
+	}
+
+	if !isLogical {
+		return cl.stackFromFile(stacksMap, stackFiles[0], nil)
+	}
+
+	context, err := cl.contextFromStackName(stack)
+	if err != nil {
+		return Stack{}, err
+	}
+
+	res := Stack{Name: stack}
+	for _, stackFile := range stackFiles {
+		st, err := cl.stackFromFile(stacksMap, stackFile, &context)
+		if err != nil {
+			return Stack{}, err
+		}
+		if len(st.Components) == 0 {
+			continue
+		}
+		res.Imports = append(res.Imports, st.Imports...)
+		res.Components = append(res.Components, st.Components...)
+	}
+
+	if len(res.Components) == 0 {
+		return Stack{}, errors.New(fmt.Sprintf("Could not find any components in the stack '%s'", stack))
+	}
+
+	res.Imports = u.UniqueStrings(res.Imports)
+	sort.Strings(res.Imports)
+	return res, nil
+}
+
+// DescribeComponent returns the final config of the terraform or helmfile component in the stack
+// (the config printed by `atmos describe component`)
+func (cl *Client) DescribeComponent(stack string, component string) (s.ComponentConfig, error) {
+	res, err := cl.Component(stack, component)
+	if err != nil {
+		return s.ComponentConfig{}, err
+	}
+	return res.Config, nil
+}
+
+// Component returns the terraform or helmfile component with the provided name in the stack
+func (cl *Client) Component(stack string, component string) (Component, error) {
+	res, err := cl.FindComponent(stack, "terraform", component)
+	if _, ok := err.(refError); ok {
+		return Component{}, err
+	}
+	if err != nil {
+		res, err = cl.FindComponent(stack, "helmfile", component)
+		if err != nil {
+			return Component{}, err
+		}
+	}
+	return res, nil
+}
+
+// FindComponent returns the component of the provided type (`terraform` or `helmfile`) with the provided name in the stack
+func (cl *Client) FindComponent(stack string, componentType string, component string) (Component, error) {
+	if len(stack) == 0 {
+		return Component{}, errors.New("stack must be provided and must not be empty")
+	}
+	if len(component) == 0 {
+		return Component{}, errors.New("component must be provided and must not be empty")
+	}
+	if len(componentType) == 0 {
+		return Component{}, errors.New("component type must be provided and must not be empty")
+	}
+
+	stacksMap, err := cl.StacksMap()
+	if err != nil {
+		return Component{}, err
+	}
+
+	stackFile, componentConfig, err := cl.findComponentInStacks(stacksMap, stack, componentType, component)
+	if err != nil {
+		return Component{}, err
+	}
+
+	return cl.newComponent(stackFile, componentType, component, componentConfig)
+}
+
+// findComponentInStacks finds the component of the provided type in the stack (a stack config file name or a logical stack name)
+// and returns the stack config file and the component config
+func (cl *Client) findComponentInStacks(
+	stacksMap map[string]interface{},
+	stack string,
+	componentType string,
+	component string,
+) (string, s.ComponentConfig, error) {
+
+	stackFiles, isLogical, err := cl.findStackFiles(stacksMap, stack)
+	if err != nil {
+		return "", s.ComponentConfig{}, err
+	}
+
+	if !isLogical {
+		componentConfig, err := findComponentSection(stacksMap, stackFiles[0], componentType, component)
+		if err != nil {
+			return "", s.ComponentConfig{}, err
+		}
+		return stackFiles[0], componentConfig, nil
+	}
+
+	context, err := cl.contextFromStackName(stack)
+	if err != nil {
+		return "", s.ComponentConfig{}, err
+	}
+
+	for _, stackFile := range stackFiles {
+		componentConfig, err := findComponentSection(stacksMap, stackFile, componentType, component)
+		if err != nil {
+			continue
+		}
+		if matchesContext(componentConfig, context) {
+			return stackFile, componentConfig, nil
+		}
+	}
+
+	return "", s.ComponentConfig{}, errors.New(fmt.Sprintf("\nCould not find config for the component '%s' in the stack '%s'.\n"+
+		"Check that all attributes in the stack name pattern '%s' are defined in the stack config files.\n"+
+		"Are the component and stack names correct? Did you forget an import?",
+		component,
+		stack,
+		cl.config.Stacks.NamePattern,
+	))
+}
+
+// ComponentsByContext returns all components whose vars match the provided context.
+// Only the context attributes used in `stacks.name_pattern` are compared
+func (cl *Client) ComponentsByContext(context c.Context) ([]Component, error) {
+	if len(cl.config.Stacks.NamePattern) < 1 {
+		return nil, errors.New("stack name pattern must be provided in 'stacks.name_pattern' config or 'ATMOS_STACKS_NAME_PATTERN' ENV variable")
+	}
+
+	stack, err := c.GetContextPrefix("", context, cl.config.Stacks.NamePattern)
+	if err != nil {
+		return nil, err
+	}
+
+	st, err := cl.DescribeStack(stack)
+	if err != nil {
+		return nil, err
+	}
+
+	return st.Components, nil
+}
+
+// findStackFiles returns the stack config files that need to be searched for the provided stack,
+// and whether the stack is a logical name (as opposed to a stack config file name)
+func (cl *Client) findStackFiles(stacksMap map[string]interface{}, stack string) ([]string, bool, error) {
+	if _, ok := stacksMap[stack]; ok {
+		return []string{stack}, false, nil
+	}
+
+	if len(cl.config.Stacks.NamePattern) < 1 {
+		return nil, false, errors.New("stack name pattern must be provided in 'stacks.name_pattern' config or 'ATMOS_STACKS_NAME_PATTERN' ENV variable")
+	}
+
+	stackParts := strings.Split(stack, "-")
+	stackNamePatternParts := strings.Split(cl.config.Stacks.NamePattern, "-")
+
+	if len(stackParts) != len(stackNamePatternParts) {
+		return nil, false, errors.New(fmt.Sprintf("\nThe stack '%s' does not exist in the config directories, and it does not match the stack name pattern '%s'",
+			stack,
+			cl.config.Stacks.NamePattern,
+		))
+	}
+
+	return u.StringKeysFromMap(stacksMap), true, nil
+}
+
+// contextFromStackName parses a logical stack name using `stacks.name_pattern`
+func (cl *Client) contextFromStackName(stack string) (c.Context, error) {
+	var context c.Context
+
+	stackParts := strings.Split(stack, "-")
+	stackNamePatternParts := strings.Split(cl.config.Stacks.NamePattern, "-")
+
+	if len(stackParts) != len(stackNamePatternParts) {
+		return context, errors.New(fmt.Sprintf("Stack '%s' does not match the stack name pattern '%s'",
+			stack,
+			cl.config.Stacks.NamePattern))
+	}
+
+	for i, part := range stackNamePatternParts {
+		if part == "{tenant}" {
+			context.Tenant = stackParts[i]
+		} else if part == "{environment}" {
+			context.Environment = stackParts[i]
+		} else if part == "{stage}" {
+			context.Stage = stackParts[i]
+		}
+	}
+
+	return context, nil
+}
+
+// stackFromFile creates a stack from the stack config file, optionally keeping only the components matching the context
+func (cl *Client) stackFromFile(stacksMap map[string]interface{}, stackFile string, context *c.Context) (Stack, error) {
+	res := Stack{Name: stackFile}
+
+	stackSection, ok := stacksMap[stackFile].(map[interface{}]interface{})
+	if !ok {
+		return res, nil
+	}
+
+	if imports, ok := stackSection["imports"].([]string); ok {
+		res.Imports = imports
+	}
+
+	componentsSection, ok := stackSection["components"].(map[string]map[string]s.ComponentConfig)
+	if !ok {
+		return res, nil
+	}
+
+	for _, componentType := range componentTypes {
+		componentTypeSection, ok := componentsSection[componentType]
+		if !ok {
+			continue
+		}
+
+		var components []string
+		for component := range componentTypeSection {
+			components = append(components, component)
+		}
+		sort.Strings(components)
+
+		for _, component := range components {
+			componentConfig := componentTypeSection[component]
+			if context != nil && !matchesContext(componentConfig, *context) {
+				continue
+			}
+			comp, err := cl.newComponent(stackFile, componentType, component, componentConfig)
+			if err != nil {
+				return Stack{}, err
+			}
+			res.Components = append(res.Components, comp)
+		}
+	}
+
+	return res, nil
+}
+
+// newComponent creates a component from the component config found in the stack config file.
+// The terraform workspace is built from the logical stack name (see `config.GetTerraformWorkspace`).
+// It returns the error if the component has a `!ref` reference which can't be resolved
+func (cl *Client) newComponent(
+	stackFile string,
+	componentType string,
+	component string,
+	componentConfig s.ComponentConfig,
+) (Component, error) {
+
+	if err := cl.refErrors[refLocation{stackFile: stackFile, componentType: componentType, component: component}]; err != nil {
+		return Component{}, err
+	}
+
+	logicalStack := stackFile
+	if len(cl.config.Stacks.NamePattern) > 0 {
+		if contextPrefix, err := c.GetContextPrefix(stackFile, c.GetContextFromVars(componentConfig.Vars), cl.config.Stacks.NamePattern); err == nil {
+			logicalStack = contextPrefix
+		}
+	}
+
+	workspace, err := c.GetTerraformWorkspace(cl.config.Components.Terraform.WorkspacePattern, logicalStack, component, componentConfig)
+	if err != nil {
+		return Component{}, err
+	}
+
+	return Component{
+		Name:      component,
+		Type:      componentType,
+		Stack:     logicalStack,
+		StackFile: stackFile,
+		Workspace: workspace,
+		Config:    componentConfig,
+	}, nil
+}
+
+// findComponentSection finds the component config in the processed stack config file
+func findComponentSection(
+	stacksMap map[string]interface{},
+	stackFile string,
+	componentType string,
+	component string,
+) (s.ComponentConfig, error) {
+
+	var stackSection map[interface{}]interface{}
+	var componentsSection map[string]map[string]s.ComponentConfig
+	var componentTypeSection map[string]s.ComponentConfig
+	var componentConfig s.ComponentConfig
+	var ok bool
+
+	if stackSection, ok = stacksMap[stackFile].(map[interface{}]interface{}); !ok {
+		return componentConfig, errors.New(fmt.Sprintf("Stack '%s' does not exist", stackFile))
+	}
+	if componentsSection, ok = stackSection["components"].(map[string]map[string]s.ComponentConfig); !ok {
+		return componentConfig, errors.New(fmt.Sprintf("'components' section is missing in the stack '%s'", stackFile))
+	}
+	if componentTypeSection, ok = componentsSection[componentType]; !ok {
+		return componentConfig, errors.New(fmt.Sprintf("'components/%s' section is missing in the stack '%s'", componentType, stackFile))
+	}
+	if componentConfig, ok = componentTypeSection[component]; !ok {
+		return componentConfig, errors.New(fmt.Sprintf("Invalid or missing configuration for the component '%s' in the stack '%s'", component, stackFile))
+	}
+
+	return componentConfig, nil
+}
+
+// matchesContext checks if the component vars define the tenant, environment and stage from the context
+func matchesContext(componentConfig s.ComponentConfig, context c.Context) bool {
+	componentContext := c.GetContextFromVars(componentConfig.Vars)
+
+	if len(context.Tenant) > 0 && componentContext.Tenant != context.Tenant {
+		return false
+	}
+	if len(context.Environment) > 0 && componentContext.Environment != context.Environment {
+		return false
+	}
+	if len(context.Stage) > 0 && componentContext.Stage != context.Stage {
+		return false
+	}
+
+	return true
+}
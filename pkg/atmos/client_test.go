@@ -0,0 +1,456 @@
+package atmos
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	c "github.com/cloudposse/atmos/pkg/config"
+	"github.com/cloudposse/atmos/pkg/mask"
+	"github.com/cloudposse/atmos/pkg/terraform"
+	u "github.com/cloudposse/atmos/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func newTestClient(t *testing.T) *Client {
+	client, err := NewClient(Options{
+		StacksBasePath:    "../../examples/complete/stacks",
+		StackNamePattern:  "{tenant}-{environment}-{stage}",
+		TerraformBasePath: "../../examples/complete/components/terraform",
+		HelmfileBasePath:  "../../examples/complete/components/helmfile",
+	})
+	assert.Nil(t, err)
+	return client
+}
+
+func TestClient(t *testing.T) {
+	client := newTestClient(t)
+
+	stacks, err := client.Stacks()
+	assert.Nil(t, err)
+	assert.Equal(t, 6, len(stacks))
+	assert.Equal(t, "tenant1/ue2/dev", stacks[0].Name)
+	assert.Equal(t, "tenant2/ue2/staging", stacks[5].Name)
+
+	component, err := client.Component("tenant1-ue2-dev", "test/test-component-override")
+	assert.Nil(t, err)
+	assert.Equal(t, "terraform", component.Type)
+	assert.Equal(t, "tenant1-ue2-dev", component.Stack)
+	assert.Equal(t, "tenant1/ue2/dev", component.StackFile)
+	assert.Equal(t, "tenant1-ue2-dev-test-test-component-override", component.Workspace)
+	assert.Equal(t, "test/test-component", component.Config.BaseComponent)
+
+	component2, err := client.Component("tenant1/ue2/dev", "infra/infra-server")
+	assert.Nil(t, err)
+	assert.Equal(t, "helmfile", component2.Type)
+	assert.Equal(t, "tenant1-ue2-dev", component2.Stack)
+
+	_, err = client.Component("tenant1-ue2-dev", "does-not-exist")
+	assert.NotNil(t, err)
+
+	_, err = client.Component("tenant1-dev", "infra/vpc")
+	assert.NotNil(t, err)
+
+	components, err := client.ComponentsByContext(c.Context{Tenant: "tenant2", Environment: "ue2", Stage: "prod"})
+	assert.Nil(t, err)
+	assert.Equal(t, 8, len(components))
+	for _, cmp := range components {
+		assert.Equal(t, "tenant2-ue2-prod", cmp.Stack)
+		assert.Equal(t, "tenant2/ue2/prod", cmp.StackFile)
+	}
+
+	stack, err := client.DescribeStack("tenant1-ue2-staging")
+	assert.Nil(t, err)
+	assert.Equal(t, "tenant1-ue2-staging", stack.Name)
+	assert.Equal(t, len(components), len(stack.Components))
+
+	componentConfig, err := client.DescribeComponent("tenant1-ue2-dev", "test/test-component-override")
+	assert.Nil(t, err)
+	assert.Equal(t, component.Config, componentConfig)
+
+	_, err = client.DescribeComponent("tenant1-ue2-dev", "does-not-exist")
+	assert.NotNil(t, err)
+
+	yamlConfig, err := yaml.Marshal(component)
+	assert.Nil(t, err)
+	t.Log(string(yamlConfig))
+}
+
+func TestClientCI(t *testing.T) {
+	client := newTestClient(t)
+	assert.False(t, client.Config().CI)
+
+	// Each client has its own CI mode
+	ciClient, err := NewClient(Options{
+		StacksBasePath:   "../../examples/complete/stacks",
+		StackNamePattern: "{tenant}-{environment}-{stage}",
+		CI:               true,
+	})
+	assert.Nil(t, err)
+	assert.True(t, ciClient.Config().CI)
+	assert.False(t, client.Config().CI)
+}
+
+func TestClientTerraformOutputs(t *testing.T) {
+	client := newTestClient(t)
+
+	reads := 0
+	read := func() (map[string]terraform.Output, *mask.Masker, error) {
+		reads++
+		masker, err := mask.New()
+		return map[string]terraform.Output{"vpc_id": {Value: "vpc-0123456789"}}, masker, err
+	}
+
+	// The outputs are read once for each component in each stack
+	for i := 0; i < 2; i++ {
+		outputs, masker, err := client.TerraformOutputs("tenant1-ue2-dev", "vpc", read)
+		assert.Nil(t, err)
+		assert.NotNil(t, masker)
+		assert.Equal(t, "vpc-0123456789", outputs["vpc_id"].Value)
+	}
+	assert.Equal(t, 1, reads)
+
+	_, _, err := client.TerraformOutputs("tenant1-ue2-prod", "vpc", read)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, reads)
+
+	// The outputs are not shared across clients
+	_, _, err = newTestClient(t).TerraformOutputs("tenant1-ue2-dev", "vpc", read)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, reads)
+}
+
+func TestClientConcurrency(t *testing.T) {
+	clients := []*Client{newTestClient(t), newTestClient(t)}
+
+	var wg sync.WaitGroup
+	for _, client := range clients {
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func(client *Client) {
+				defer wg.Done()
+				component, err := client.Component("tenant1-ue2-prod", "infra/vpc")
+				assert.Nil(t, err)
+				assert.Equal(t, "tenant1/ue2/prod", component.StackFile)
+			}(client)
+		}
+	}
+	wg.Wait()
+}
+
+func TestClientFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"stacks/catalog/vpc.yaml": &fstest.MapFile{Data: []byte(`
+components:
+  terraform:
+    vpc:
+      backend:
+        s3:
+          workspace_key_prefix: vpc
+      vars:
+        cidr_block: 10.0.0.0/16
+`)},
+		"stacks/globals.yaml": &fstest.MapFile{Data: []byte(`
+terraform:
+  backend_type: s3
+vars:
+  namespace: eg
+`)},
+		"stacks/ue2/dev.yaml": &fstest.MapFile{Data: []byte(`
+import:
+  - globals
+  - catalog/*
+vars:
+  environment: ue2
+  stage: dev
+components:
+  terraform:
+    vpc:
+      vars:
+        cidr_block: 10.1.0.0/16
+`)},
+	}
+
+	client, err := NewClient(Options{
+		FS:                fsys,
+		StacksBasePath:    "stacks",
+		IncludedPaths:     []string{"**/*"},
+		ExcludedPaths:     []string{"globals.yaml", "catalog/**/*"},
+		StackNamePattern:  "{environment}-{stage}",
+		TerraformBasePath: "components/terraform",
+		HelmfileBasePath:  "components/helmfile",
+	})
+	assert.Nil(t, err)
+
+	stacks, err := client.Stacks()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(stacks))
+	assert.Equal(t, "ue2/dev", stacks[0].Name)
+	assert.Equal(t, []string{"catalog/vpc", "globals"}, stacks[0].Imports)
+
+	component, err := client.Component("ue2-dev", "vpc")
+	assert.Nil(t, err)
+	assert.Equal(t, "10.1.0.0/16", component.Config.Vars["cidr_block"])
+	assert.Equal(t, "eg", component.Config.Vars["namespace"])
+	assert.Equal(t, "s3", component.Config.BackendType)
+	assert.Equal(t, "vpc", component.Config.Backend["workspace_key_prefix"])
+
+	_, err = NewClient(Options{FS: fsys, StacksBasePath: "/stacks"})
+	assert.NotNil(t, err)
+}
+
+func TestClientWorkspacePattern(t *testing.T) {
+	fsys := fstest.MapFS{
+		"stacks/ue2/dev.yaml": &fstest.MapFile{Data: []byte(`
+vars:
+  namespace: eg
+  environment: ue2
+  stage: dev
+components:
+  terraform:
+    infra/vpc:
+      vars: {}
+    vpc-legacy:
+      component: infra/vpc
+      metadata:
+        terraform_workspace: legacy-vpc
+    vpc-pattern:
+      component: infra/vpc
+      metadata:
+        terraform_workspace_pattern: "{namespace}-{stage}-{base-component}"
+`)},
+	}
+
+	options := Options{
+		FS:               fsys,
+		StacksBasePath:   "stacks",
+		IncludedPaths:    []string{"**/*"},
+		StackNamePattern: "{environment}-{stage}",
+	}
+
+	client, err := NewClient(options)
+	assert.Nil(t, err)
+
+	// Without a pattern, the workspace is the stack name for the components without a base component
+	component, err := client.Component("ue2-dev", "infra/vpc")
+	assert.Nil(t, err)
+	assert.Equal(t, "ue2-dev", component.Workspace)
+
+	component, err = client.Component("ue2-dev", "vpc-legacy")
+	assert.Nil(t, err)
+	assert.Equal(t, "legacy-vpc", component.Workspace)
+
+	component, err = client.Component("ue2-dev", "vpc-pattern")
+	assert.Nil(t, err)
+	assert.Equal(t, "eg-dev-infra-vpc", component.Workspace)
+
+	options.TerraformWorkspacePattern = "{stack}-{component}"
+	client, err = NewClient(options)
+	assert.Nil(t, err)
+
+	component, err = client.Component("ue2-dev", "infra/vpc")
+	assert.Nil(t, err)
+	assert.Equal(t, "ue2-dev-infra-vpc", component.Workspace)
+
+	options.TerraformWorkspacePattern = "{tenant}-{component}"
+	client, err = NewClient(options)
+	assert.Nil(t, err)
+
+	_, err = client.Component("ue2-dev", "infra/vpc")
+	assert.NotNil(t, err)
+}
+
+func TestClientStacksArchive(t *testing.T) {
+	stacksBasePath := "../../examples/complete/stacks"
+	archivePath := filepath.Join(t.TempDir(), "stacks.tar.gz")
+
+	archive, err := os.Create(archivePath)
+	assert.Nil(t, err)
+	gzipWriter := gzip.NewWriter(archive)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	err = filepath.Walk(stacksBasePath, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		name, err := filepath.Rel(stacksBasePath, p)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		err = tarWriter.WriteHeader(&tar.Header{Name: filepath.ToSlash(name), Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		if err != nil {
+			return err
+		}
+		_, err = tarWriter.Write(content)
+		return err
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, tarWriter.Close())
+	assert.Nil(t, gzipWriter.Close())
+	assert.Nil(t, archive.Close())
+
+	fsys, err := u.ArchiveFS(archivePath)
+	assert.Nil(t, err)
+
+	client, err := NewClient(Options{
+		FS:               fsys,
+		StackNamePattern: "{tenant}-{environment}-{stage}",
+	})
+	assert.Nil(t, err)
+
+	stacks, err := client.Stacks()
+	assert.Nil(t, err)
+	assert.Equal(t, 6, len(stacks))
+
+	component, err := client.Component("tenant1-ue2-dev", "test/test-component-override")
+	assert.Nil(t, err)
+	assert.Equal(t, "tenant1/ue2/dev", component.StackFile)
+	assert.Equal(t, "test/test-component", component.Config.BaseComponent)
+
+	diskComponent, err := newTestClient(t).Component("tenant1-ue2-dev", "test/test-component-override")
+	assert.Nil(t, err)
+	assert.Equal(t, diskComponent.Config, component.Config)
+
+	_, err = u.ArchiveFS(filepath.Join(t.TempDir(), "stacks.rar"))
+	assert.NotNil(t, err)
+}
+
+func TestClientRefs(t *testing.T) {
+	newRefsTestClient := func(dev string) *Client {
+		client, err := NewClient(Options{
+			FS: fstest.MapFS{
+				"stacks/catalog/vpc.yaml": &fstest.MapFile{Data: []byte(`
+components:
+  terraform:
+    vpc:
+      vars:
+        cidr_block: 10.0.0.0/16
+        vpc_name: !ref vpc.vars.name
+`)},
+				"stacks/ue2/prod.yaml": &fstest.MapFile{Data: []byte(`
+import:
+  - catalog/vpc
+vars:
+  environment: ue2
+  stage: prod
+components:
+  terraform:
+    vpc:
+      vars:
+        name: prod-vpc
+`)},
+				"stacks/ue2/dev.yaml": &fstest.MapFile{Data: []byte(dev)},
+			},
+			StacksBasePath:   "stacks",
+			IncludedPaths:    []string{"**/*"},
+			ExcludedPaths:    []string{"catalog/**/*"},
+			StackNamePattern: "{environment}-{stage}",
+		})
+		assert.Nil(t, err)
+		return client
+	}
+
+	client := newRefsTestClient(`
+import:
+  - catalog/vpc
+vars:
+  environment: ue2
+  stage: dev
+components:
+  terraform:
+    vpc:
+      vars:
+        name: dev-vpc
+        cidr_block: 10.1.0.0/16
+    eks:
+      vars:
+        cluster_name: dev-eks
+        vpc_cidr: !ref vpc.vars.cidr_block
+        vpc_name: !ref vpc.vars.vpc_name
+        prod_vpc_name: !ref ue2-prod:vpc.vars.vpc_name
+        subnets:
+          - !ref ue2/prod:vpc.vars.cidr_block
+  helmfile:
+    echo-server:
+      vars:
+        cluster_name: !ref eks.vars.cluster_name
+`)
+
+	component, err := client.Component("ue2-dev", "eks")
+	assert.Nil(t, err)
+	assert.Equal(t, "10.1.0.0/16", component.Config.Vars["vpc_cidr"])
+	assert.Equal(t, "dev-vpc", component.Config.Vars["vpc_name"])
+	assert.Equal(t, "prod-vpc", component.Config.Vars["prod_vpc_name"])
+	assert.Equal(t, []interface{}{"10.0.0.0/16"}, component.Config.Vars["subnets"])
+
+	component, err = client.Component("ue2-dev", "echo-server")
+	assert.Nil(t, err)
+	assert.Equal(t, "dev-eks", component.Config.Vars["cluster_name"])
+
+	// The referenced values are resolved in the referenced components too
+	component, err = client.Component("ue2-prod", "vpc")
+	assert.Nil(t, err)
+	assert.Equal(t, "prod-vpc", component.Config.Vars["vpc_name"])
+
+	client = newRefsTestClient(`
+vars:
+  environment: ue2
+  stage: dev
+components:
+  terraform:
+    eks:
+      vars:
+        a: !ref eks.vars.b
+        b: !ref eks.vars.a
+`)
+	_, err = client.Stacks()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "circular reference")
+
+	// A reference which can't be resolved fails only the component with the reference
+	_, err = client.Component("ue2-dev", "eks")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "circular reference")
+	component, err = client.Component("ue2-prod", "vpc")
+	assert.Nil(t, err)
+	assert.Equal(t, "prod-vpc", component.Config.Vars["vpc_name"])
+	_, err = client.DescribeStack("ue2-prod")
+	assert.Nil(t, err)
+
+	client = newRefsTestClient(`
+vars:
+  environment: ue2
+  stage: dev
+components:
+  terraform:
+    eks:
+      vars:
+        vpc_cidr: !ref ue2-staging:vpc.vars.cidr_block
+`)
+	_, err = client.Stacks()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "the stack 'ue2-staging' does not exist")
+
+	client = newRefsTestClient(`
+vars:
+  environment: ue2
+  stage: dev
+components:
+  terraform:
+    eks:
+      vars:
+        vpc_cidr: !ref vpc.vars.cidr_block
+`)
+	_, err = client.Stacks()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "the component 'vpc' does not exist in the stack 'ue2/dev'")
+}
@@ -0,0 +1,41 @@
+package atmos
+
+import (
+	"sync"
+
+	"github.com/cloudposse/atmos/pkg/mask"
+	"github.com/cloudposse/atmos/pkg/terraform"
+)
+
+// outputsKey is the key of the cached outputs of a terraform component
+type outputsKey struct {
+	stack     string
+	component string
+}
+
+type outputsEntry struct {
+	once    sync.Once
+	outputs map[string]terraform.Output
+	// masker has the sensitive values of the component (e.g. the resolved secrets)
+	masker *mask.Masker
+	err    error
+}
+
+// TerraformOutputs returns the outputs of the terraform component in the stack.
+// The outputs are read with `read` on the first call for the component and are cached for the lifetime of the client
+// (concurrent calls for the same component wait for the first one). Create a new client to read the outputs again
+func (cl *Client) TerraformOutputs(
+	stack string,
+	component string,
+	read func() (map[string]terraform.Output, *mask.Masker, error),
+) (map[string]terraform.Output, *mask.Masker, error) {
+
+	value, _ := cl.outputs.LoadOrStore(outputsKey{stack: stack, component: component}, &outputsEntry{})
+	entry := value.(*outputsEntry)
+
+	entry.once.Do(func() {
+		entry.outputs, entry.masker, entry.err = read()
+	})
+
+	return entry.outputs, entry.masker, entry.err
+}
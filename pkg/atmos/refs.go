@@ -0,0 +1,344 @@
+package atmos
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	c "github.com/cloudposse/atmos/pkg/config"
+	s "github.com/cloudposse/atmos/pkg/stack"
+	u "github.com/cloudposse/atmos/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// refSections are the sections of the component configs which can use and can be referenced by the `!ref` YAML tag
+var refSections = []string{"vars", "settings", "env", "backend", "remote_state_backend", "providers", "metadata"}
+
+// refLocation is a value in a component config
+type refLocation struct {
+	stackFile     string
+	componentType string
+	component     string
+	key           string
+}
+
+func (loc refLocation) String() string {
+	return fmt.Sprintf("%s:%s.%s", loc.stackFile, loc.component, loc.key)
+}
+
+// refError is the error of a component with a `!ref` reference which can't be resolved
+type refError struct {
+	error
+}
+
+// refResolver resolves the `!ref` references against the processed stacks
+type refResolver struct {
+	cl        *Client
+	stacksMap map[string]interface{}
+	// chain is the chain of the values being resolved, to detect the circular references
+	chain []refLocation
+}
+
+// ResolveRefs replaces the `!ref` references in all components of the processed stacks (as returned by `stack.ProcessYAMLConfigFiles`).
+// The logical stack names in the references are parsed using `stackNamePattern`.
+// It returns the error of the first (by the stack config file and the component name) component with a reference which can't be resolved
+func ResolveRefs(stacksMap map[string]interface{}, stackNamePattern string) error {
+	config := c.DefaultConfig()
+	config.Stacks.NamePattern = stackNamePattern
+	cl := &Client{config: config}
+
+	refErrors := cl.resolveRefs(stacksMap)
+	if len(refErrors) == 0 {
+		return nil
+	}
+
+	var locs []refLocation
+	for loc := range refErrors {
+		locs = append(locs, loc)
+	}
+	sort.Slice(locs, func(i, j int) bool {
+		return locs[i].String() < locs[j].String()
+	})
+	return refErrors[locs[0]]
+}
+
+// resolveRefs replaces the `!ref` references in all components of all stacks with the referenced values.
+// The references are resolved against the processed component configs, so they see the final (deep-merged) values.
+// A reference which can't be resolved does not fail the other components: the errors are returned per component
+// (the key of a component is its location without the key), and the configs of these components are not changed
+func (cl *Client) resolveRefs(stacksMap map[string]interface{}) map[refLocation]error {
+	r := &refResolver{cl: cl, stacksMap: stacksMap}
+	resolved := map[refLocation]s.ComponentConfig{}
+	refErrors := map[refLocation]error{}
+
+	for _, stackFile := range u.StringKeysFromMap(stacksMap) {
+		componentsSection, ok := componentsSectionOf(stacksMap, stackFile)
+		if !ok {
+			continue
+		}
+
+		for _, componentType := range componentTypes {
+			for component, componentConfig := range componentsSection[componentType] {
+				componentLoc := refLocation{stackFile: stackFile, componentType: componentType, component: component}
+				sections := componentRefSections(componentConfig)
+				changed := false
+				var err error
+
+				for _, section := range refSections {
+					if sections[section] == nil {
+						continue
+					}
+					loc := componentLoc
+					loc.key = section
+					var v interface{}
+					var ch bool
+					v, ch, err = r.resolveValue(loc, sections[section])
+					if err != nil {
+						break
+					}
+					if ch {
+						sections[section] = v.(map[string]interface{})
+						changed = true
+					}
+				}
+
+				if err != nil {
+					refErrors[componentLoc] = refError{err}
+				} else if changed {
+					resolved[componentLoc] = withComponentRefSections(componentConfig, sections)
+				}
+			}
+		}
+	}
+
+	// Update the components only after all references are resolved, so that all references see the same stacks
+	for loc, componentConfig := range resolved {
+		componentsSection, _ := componentsSectionOf(stacksMap, loc.stackFile)
+		componentsSection[loc.componentType][loc.component] = componentConfig
+	}
+
+	return refErrors
+}
+
+// resolveValue returns a copy of the value with the references (at any level) replaced by the referenced values,
+// and whether any references were found
+func (r *refResolver) resolveValue(loc refLocation, v interface{}) (interface{}, bool, error) {
+	switch v := v.(type) {
+	case string:
+		if !strings.HasPrefix(v, s.TagRef+" ") {
+			return v, false, nil
+		}
+		res, err := r.resolveRef(loc, v)
+		if err != nil {
+			// The error is reported once, at the value which started the chain of the references
+			if len(r.chain) == 0 {
+				return nil, false, errors.New(fmt.Sprintf("Could not resolve '%s' in the component '%s' in the stack '%s' ('%s'): %s",
+					v, loc.component, loc.stackFile, loc.key, err))
+			}
+			return nil, false, err
+		}
+		return res, true, nil
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(v))
+		changed := false
+		for k, val := range v {
+			resolved, ch, err := r.resolveValue(loc.child(k), val)
+			if err != nil {
+				return nil, false, err
+			}
+			res[k] = resolved
+			changed = changed || ch
+		}
+		return res, changed, nil
+	case map[interface{}]interface{}:
+		res := make(map[interface{}]interface{}, len(v))
+		changed := false
+		for k, val := range v {
+			resolved, ch, err := r.resolveValue(loc.child(fmt.Sprintf("%v", k)), val)
+			if err != nil {
+				return nil, false, err
+			}
+			res[k] = resolved
+			changed = changed || ch
+		}
+		return res, changed, nil
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		changed := false
+		for i, val := range v {
+			resolved, ch, err := r.resolveValue(loc.child(fmt.Sprintf("[%d]", i)), val)
+			if err != nil {
+				return nil, false, err
+			}
+			res[i] = resolved
+			changed = changed || ch
+		}
+		return res, changed, nil
+	default:
+		return v, false, nil
+	}
+}
+
+// resolveRef returns the value referenced by `ref` (e.g. `!ref tenant1-ue2-prod:infra/vpc.vars.vpc_name`) from the value at `loc`
+func (r *refResolver) resolveRef(loc refLocation, ref string) (interface{}, error) {
+	for _, l := range r.chain {
+		if l == loc {
+			var chain []string
+			for _, l := range r.chain {
+				chain = append(chain, l.String())
+			}
+			return nil, errors.New(fmt.Sprintf("circular reference: %s -> %s", strings.Join(chain, " -> "), loc))
+		}
+	}
+	r.chain = append(r.chain, loc)
+	defer func() {
+		r.chain = r.chain[:len(r.chain)-1]
+	}()
+
+	target, err := r.findRefTarget(loc, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find the referenced value, resolving the references on the way
+	componentConfig, err := findComponentSection(r.stacksMap, target.stackFile, target.componentType, target.component)
+	if err != nil {
+		return nil, err
+	}
+	keys := strings.Split(target.key, ".")
+	var v interface{} = componentRefSections(componentConfig)[keys[0]]
+	valueLoc := refLocation{stackFile: target.stackFile, componentType: target.componentType, component: target.component, key: keys[0]}
+
+	for _, key := range keys[1:] {
+		if ref, ok := v.(string); ok && strings.HasPrefix(ref, s.TagRef+" ") {
+			if v, err = r.resolveRef(valueLoc, ref); err != nil {
+				return nil, err
+			}
+		}
+
+		var found bool
+		switch m := v.(type) {
+		case map[string]interface{}:
+			v, found = m[key]
+		case map[interface{}]interface{}:
+			v, found = m[key]
+		}
+		if !found {
+			return nil, errors.New(fmt.Sprintf("'%s' does not exist in the component '%s' in the stack '%s'",
+				target.key, target.component, target.stackFile))
+		}
+		valueLoc = valueLoc.child(key)
+	}
+
+	res, _, err := r.resolveValue(valueLoc, v)
+	return res, err
+}
+
+// findRefTarget parses the reference and finds the stack config file and the type of the referenced component.
+// If the stack is not specified in the reference, the component is in the same stack as the value at `loc`
+func (r *refResolver) findRefTarget(loc refLocation, ref string) (refLocation, error) {
+	target := strings.TrimSpace(strings.TrimPrefix(ref, s.TagRef+" "))
+
+	var stack string
+	if i := strings.Index(target, ":"); i >= 0 {
+		stack = target[:i]
+		target = target[i+1:]
+	}
+
+	parts := strings.SplitN(target, ".", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 || !u.SliceContainsString(refSections, strings.Split(parts[1], ".")[0]) {
+		return refLocation{}, errors.New(fmt.Sprintf("invalid reference. The format is '%s [<stack>:]<component>.<section>.<key>', "+
+			"where the section is one of: %s", s.TagRef, strings.Join(refSections, ", ")))
+	}
+	res := refLocation{component: parts[0], key: parts[1]}
+
+	var err error
+	for _, componentType := range componentTypes {
+		res.componentType = componentType
+		if len(stack) == 0 {
+			res.stackFile = loc.stackFile
+			_, err = findComponentSection(r.stacksMap, loc.stackFile, componentType, res.component)
+		} else {
+			res.stackFile, _, err = r.cl.findComponentInStacks(r.stacksMap, stack, componentType, res.component)
+		}
+		if err == nil {
+			return res, nil
+		}
+	}
+
+	if len(stack) == 0 {
+		stack = loc.stackFile
+	} else if !r.stackExists(stack) {
+		return refLocation{}, errors.New(fmt.Sprintf("the stack '%s' does not exist", stack))
+	}
+	return refLocation{}, errors.New(fmt.Sprintf("the component '%s' does not exist in the stack '%s'", res.component, stack))
+}
+
+// stackExists checks if the stack (a stack config file name or a logical stack name) has any components
+func (r *refResolver) stackExists(stack string) bool {
+	stackFiles, isLogical, err := r.cl.findStackFiles(r.stacksMap, stack)
+	if err != nil {
+		return false
+	}
+	if !isLogical {
+		return true
+	}
+
+	context, err := r.cl.contextFromStackName(stack)
+	if err != nil {
+		return false
+	}
+	for _, stackFile := range stackFiles {
+		componentsSection, _ := componentsSectionOf(r.stacksMap, stackFile)
+		for _, componentTypeSection := range componentsSection {
+			for _, componentConfig := range componentTypeSection {
+				if matchesContext(componentConfig, context) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (loc refLocation) child(key string) refLocation {
+	if strings.HasPrefix(key, "[") {
+		loc.key += key
+	} else {
+		loc.key += "." + key
+	}
+	return loc
+}
+
+// componentsSectionOf returns the `components` section of the processed stack config file
+func componentsSectionOf(stacksMap map[string]interface{}, stackFile string) (map[string]map[string]s.ComponentConfig, bool) {
+	stackSection, ok := stacksMap[stackFile].(map[interface{}]interface{})
+	if !ok {
+		return nil, false
+	}
+	componentsSection, ok := stackSection["components"].(map[string]map[string]s.ComponentConfig)
+	return componentsSection, ok
+}
+
+func componentRefSections(cc s.ComponentConfig) map[string]map[string]interface{} {
+	return map[string]map[string]interface{}{
+		"vars":                 cc.Vars,
+		"settings":             cc.Settings,
+		"env":                  cc.Env,
+		"backend":              cc.Backend,
+		"remote_state_backend": cc.RemoteStateBackend,
+		"providers":            cc.Providers,
+		"metadata":             cc.Metadata,
+	}
+}
+
+func withComponentRefSections(cc s.ComponentConfig, sections map[string]map[string]interface{}) s.ComponentConfig {
+	cc.Vars = sections["vars"]
+	cc.Settings = sections["settings"]
+	cc.Env = sections["env"]
+	cc.Backend = sections["backend"]
+	cc.RemoteStateBackend = sections["remote_state_backend"]
+	cc.Providers = sections["providers"]
+	cc.Metadata = sections["metadata"]
+	return cc
+}
@@ -101,3 +101,18 @@ func TestComponentProcessor(t *testing.T) {
 	assert.Nil(t, err)
 	t.Log(string(yamlConfig))
 }
+
+func TestComponentProcessorContext(t *testing.T) {
+	stack, err := stackNameFromContext("{tenant}-{environment}-{stage}", "tenant1", "ue2", "dev")
+	assert.Nil(t, err)
+	assert.Equal(t, "tenant1-ue2-dev", stack)
+
+	stack, err = stackNameFromContext("{environment}-{stage}", "", "ue2", "dev")
+	assert.Nil(t, err)
+	assert.Equal(t, "ue2-dev", stack)
+
+	// The error names the missing context, not the component
+	_, err = ProcessComponentFromContext("test/test-component-override", "tenant1", "", "dev")
+	assert.NotNil(t, err)
+	assert.Equal(t, "stack name pattern '{tenant}-{environment}-{stage}' includes '{environment}', but environment is not provided", err.Error())
+}
@@ -5,18 +5,19 @@ import (
 	"encoding/json"
 	"fmt"
 	g "github.com/cloudposse/atmos/pkg/globals"
+	l "github.com/cloudposse/atmos/pkg/logger"
+	s "github.com/cloudposse/atmos/pkg/stack"
 	u "github.com/cloudposse/atmos/pkg/utils"
-	"github.com/fatih/color"
 	"github.com/mitchellh/go-homedir"
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v2"
+	"io/fs"
 	"os"
 	"path"
 	"path/filepath"
 	"runtime"
 	"strconv"
-	"strings"
 )
 
 var (
@@ -28,6 +29,12 @@ var (
 				ApplyAutoApprove:        false,
 				DeployRunInit:           true,
 				AutoGenerateBackendFile: false,
+				WorkingDirs: WorkingDirs{
+					Enabled:  false,
+					BasePath: "./.atmos/terraform",
+					Mode:     "symlink",
+					Cleanup:  false,
+				},
 			},
 			Helmfile: Helmfile{
 				BasePath:              "./components/helmfile",
@@ -47,23 +54,41 @@ var (
 				"**/*globals*",
 			},
 		},
+		Schemas: Schemas{
+			BasePath: "./schemas",
+		},
+		Policies: Policies{
+			BasePath: "./policies",
+		},
 		Logs: Logs{
 			Verbose: false,
 			Colors:  true,
+			Level:   "info",
+			Format:  "text",
+		},
+		Masking: Masking{
+			KeyPatterns: []string{
+				"*_TOKEN",
+				"*PASSWORD*",
+				"*SECRET*",
+			},
 		},
 	}
-
-	// Config is the CLI configuration structure
-	Config Configuration
-
-	// ProcessedConfig holds all the calculated values
-	ProcessedConfig ProcessedConfiguration
 )
 
+// DefaultConfig returns a copy of the default CLI configuration
+func DefaultConfig() Configuration {
+	config := defaultConfig
+	config.Stacks.IncludedPaths = append([]string{}, defaultConfig.Stacks.IncludedPaths...)
+	config.Stacks.ExcludedPaths = append([]string{}, defaultConfig.Stacks.ExcludedPaths...)
+	config.Masking.KeyPatterns = append([]string{}, defaultConfig.Masking.KeyPatterns...)
+	return config
+}
+
 // InitConfig finds and merges CLI configurations in the following order: system dir, home dir, current dir, ENV vars, command-line arguments
 // https://dev.to/techschoolguru/load-config-from-file-environment-variables-in-golang-with-viper-2j2d
 // https://medium.com/@bnprashanth256/reading-configuration-files-and-environment-variables-in-go-golang-c2607f912b63
-func InitConfig() error {
+func InitConfig() (Configuration, error) {
 	// Config is loaded from the following locations (from lower to higher priority):
 	// system dir (`/usr/local/etc/atmos` on Linux, `%LOCALAPPDATA%/atmos` on Windows)
 	// home dir (~/.atmos)
@@ -71,17 +96,22 @@ func InitConfig() error {
 	// ENV vars
 	// Command-line arguments
 
-	err := processLogsConfig()
+	var config Configuration
+
+	// Configure the logger from the ENV vars first to log the processing of the config files
+	logs := defaultConfig.Logs
+	err := processLogsEnvVars(&logs)
 	if err != nil {
-		return err
+		return config, err
 	}
-
-	if g.LogVerbose {
-		color.Cyan("\nProcessing and merging configurations in the following order:\n")
-		fmt.Println("system dir, home dir, current dir, ENV vars, command-line arguments")
-		fmt.Println()
+	err = configureLogger(logs)
+	if err != nil {
+		return config, err
 	}
 
+	l.Debug("\nProcessing and merging configurations in the following order:\n" +
+		"system dir, home dir, current dir, ENV vars, command-line arguments\n")
+
 	v := viper.New()
 	v.SetConfigType("yaml")
 	v.SetTypeByDefaultValue(true)
@@ -89,12 +119,12 @@ func InitConfig() error {
 	// Add default config
 	j, err := json.Marshal(defaultConfig)
 	if err != nil {
-		return err
+		return config, err
 	}
 	reader := bytes.NewReader(j)
 	err = v.MergeConfig(reader)
 	if err != nil {
-		return err
+		return config, err
 	}
 
 	// Process config in system folder
@@ -117,266 +147,222 @@ func InitConfig() error {
 		configFile1 := path.Join(configFilePath1, g.ConfigFileName)
 		err = processConfigFile(configFile1, v)
 		if err != nil {
-			return err
+			return config, err
 		}
 	}
 
 	// Process config in user's HOME dir
 	configFilePath2, err := homedir.Dir()
 	if err != nil {
-		return err
+		return config, err
 	}
 	configFile2 := path.Join(configFilePath2, ".atmos", g.ConfigFileName)
 	err = processConfigFile(configFile2, v)
 	if err != nil {
-		return err
+		return config, err
 	}
 
 	// Process config in the current dir
 	configFilePath3, err := os.Getwd()
 	if err != nil {
-		return err
+		return config, err
 	}
 	configFile3 := path.Join(configFilePath3, g.ConfigFileName)
 	err = processConfigFile(configFile3, v)
 	if err != nil {
-		return err
+		return config, err
 	}
 
 	// https://gist.github.com/chazcheadle/45bf85b793dea2b71bd05ebaa3c28644
 	// https://sagikazarmark.hu/blog/decoding-custom-formats-with-viper/
-	err = v.Unmarshal(&Config)
+	err = v.Unmarshal(&config)
 	if err != nil {
-		return err
+		return config, err
 	}
 
-	return nil
+	// The ENV vars take precedence over the logs config in the config files
+	err = processLogsEnvVars(&config.Logs)
+	if err != nil {
+		return config, err
+	}
+	// No colors in the CI mode
+	if config.CI {
+		config.Logs.Colors = false
+	}
+	err = configureLogger(config.Logs)
+	if err != nil {
+		return config, err
+	}
+
+	return config, nil
 }
 
-// ProcessConfig processes and checks CLI configuration
-func ProcessConfig(configAndStacksInfo ConfigAndStacksInfo) error {
+// ProcessConfig applies ENV vars and command-line arguments to the provided CLI configuration and checks it
+func ProcessConfig(config *Configuration, configAndStacksInfo ConfigAndStacksInfo) error {
 	// Process ENV vars
-	err := processEnvVars()
+	err := processEnvVars(config)
 	if err != nil {
 		return err
 	}
 
 	// Process command-line args
 	if len(configAndStacksInfo.TerraformDir) > 0 {
-		Config.Components.Terraform.BasePath = configAndStacksInfo.TerraformDir
-		color.Cyan(fmt.Sprintf("Using command line argument '%s' as terraform directory", configAndStacksInfo.TerraformDir))
+		config.Components.Terraform.BasePath = configAndStacksInfo.TerraformDir
+		l.Debug(fmt.Sprintf("Using command line argument '%s' as terraform directory", configAndStacksInfo.TerraformDir))
 	}
 	if len(configAndStacksInfo.HelmfileDir) > 0 {
-		Config.Components.Helmfile.BasePath = configAndStacksInfo.HelmfileDir
-		color.Cyan(fmt.Sprintf("Using command line argument '%s' as helmfile directory", configAndStacksInfo.HelmfileDir))
+		config.Components.Helmfile.BasePath = configAndStacksInfo.HelmfileDir
+		l.Debug(fmt.Sprintf("Using command line argument '%s' as helmfile directory", configAndStacksInfo.HelmfileDir))
 	}
 	if len(configAndStacksInfo.ConfigDir) > 0 {
-		Config.Stacks.BasePath = configAndStacksInfo.ConfigDir
-		color.Cyan(fmt.Sprintf("Using command line argument '%s' as stacks directory", configAndStacksInfo.ConfigDir))
+		config.Stacks.BasePath = configAndStacksInfo.ConfigDir
+		l.Debug(fmt.Sprintf("Using command line argument '%s' as stacks directory", configAndStacksInfo.ConfigDir))
 	}
 	if len(configAndStacksInfo.StacksDir) > 0 {
-		Config.Stacks.BasePath = configAndStacksInfo.StacksDir
-		color.Cyan(fmt.Sprintf("Using command line argument '%s' as stacks directory", configAndStacksInfo.StacksDir))
+		config.Stacks.BasePath = configAndStacksInfo.StacksDir
+		l.Debug(fmt.Sprintf("Using command line argument '%s' as stacks directory", configAndStacksInfo.StacksDir))
+	}
+	if configAndStacksInfo.CI {
+		config.CI = true
+		l.Debug(fmt.Sprintf("Using command line argument '%s'", g.CIFlag))
 	}
 	if len(configAndStacksInfo.DeployRunInit) > 0 {
 		deployRunInitBool, err := strconv.ParseBool(configAndStacksInfo.DeployRunInit)
 		if err != nil {
 			return err
 		}
-		Config.Components.Terraform.DeployRunInit = deployRunInitBool
-		color.Cyan(fmt.Sprintf("Using command line argument '%s=%s'", g.DeployRunInitFlag, configAndStacksInfo.DeployRunInit))
+		config.Components.Terraform.DeployRunInit = deployRunInitBool
+		l.Debug(fmt.Sprintf("Using command line argument '%s=%s'", g.DeployRunInitFlag, configAndStacksInfo.DeployRunInit))
 	}
 	if len(configAndStacksInfo.AutoGenerateBackendFile) > 0 {
 		autoGenerateBackendFileBool, err := strconv.ParseBool(configAndStacksInfo.AutoGenerateBackendFile)
 		if err != nil {
 			return err
 		}
-		Config.Components.Terraform.AutoGenerateBackendFile = autoGenerateBackendFileBool
-		color.Cyan(fmt.Sprintf("Using command line argument '%s=%s'", g.AutoGenerateBackendFileFlag, configAndStacksInfo.AutoGenerateBackendFile))
+		config.Components.Terraform.AutoGenerateBackendFile = autoGenerateBackendFileBool
+		l.Debug(fmt.Sprintf("Using command line argument '%s=%s'", g.AutoGenerateBackendFileFlag, configAndStacksInfo.AutoGenerateBackendFile))
 	}
 
 	// Check config
-	err = checkConfig()
-	if err != nil {
-		return err
-	}
-
-	// Convert stacks base path to absolute path
-	stacksBaseAbsPath, err := filepath.Abs(Config.Stacks.BasePath)
+	err = checkConfig(*config)
 	if err != nil {
 		return err
 	}
-	ProcessedConfig.StacksBaseAbsolutePath = stacksBaseAbsPath
 
-	// Convert the included stack paths to absolute paths
-	includeStackAbsPaths, err := u.JoinAbsolutePathWithPaths(stacksBaseAbsPath, Config.Stacks.IncludedPaths)
-	if err != nil {
-		return err
-	}
-	ProcessedConfig.IncludeStackAbsolutePaths = includeStackAbsPaths
-
-	// Convert the excluded stack paths to absolute paths
-	excludeStackAbsPaths, err := u.JoinAbsolutePathWithPaths(stacksBaseAbsPath, Config.Stacks.ExcludedPaths)
-	if err != nil {
-		return err
+	if l.IsLevelEnabled(l.LevelDebug) {
+		y, err := u.ConvertToYAML(config)
+		if err != nil {
+			return err
+		}
+		l.Debug("\nFinal CLI configuration:\n%s", y)
 	}
-	ProcessedConfig.ExcludeStackAbsolutePaths = excludeStackAbsPaths
 
-	// Convert terraform dir to absolute path
-	terraformDirAbsPath, err := filepath.Abs(Config.Components.Terraform.BasePath)
-	if err != nil {
-		return err
-	}
-	ProcessedConfig.TerraformDirAbsolutePath = terraformDirAbsPath
+	return nil
+}
 
-	// Convert helmfile dir to absolute path
-	helmfileDirAbsPath, err := filepath.Abs(Config.Components.Helmfile.BasePath)
-	if err != nil {
-		return err
-	}
-	ProcessedConfig.HelmfileDirAbsolutePath = helmfileDirAbsPath
+// ProcessStacksConfig calculates the absolute paths from the provided CLI configuration
+// and finds all stack config files in the paths specified by `stacks.included_paths` and `stacks.excluded_paths`
+func ProcessStacksConfig(config Configuration) (ProcessedConfiguration, error) {
+	return ProcessStacksConfigFS(config, s.OSFileSystem())
+}
 
-	// If the specified stack name is a logical name, find all stack config files in the provided paths
-	stackConfigFilesAbsolutePaths, stackConfigFilesRelativePaths, stackIsPhysicalPath, err := findAllStackConfigsInPathsForStack(
-		configAndStacksInfo.Stack,
-		includeStackAbsPaths,
-		excludeStackAbsPaths,
-	)
+// ProcessStacksConfigFS calculates the paths from the provided CLI configuration
+// and finds all stack config files in the filesystem in the paths specified by `stacks.included_paths` and `stacks.excluded_paths`.
+// If the filesystem is not the OS filesystem, `stacks.base_path` is relative to the root of the filesystem,
+// and the stack config file paths are slash-separated paths in the filesystem
+func ProcessStacksConfigFS(config Configuration, fileSystem *s.FileSystem) (ProcessedConfiguration, error) {
+	var processedConfig ProcessedConfiguration
 
+	// Check config
+	err := checkConfig(config)
 	if err != nil {
-		return err
+		return processedConfig, err
 	}
 
-	if len(stackConfigFilesAbsolutePaths) < 1 {
-		j, err := yaml.Marshal(includeStackAbsPaths)
+	// Convert stacks base path to absolute path (or to a path in the filesystem)
+	var stacksBaseAbsPath string
+	if fileSystem.IsOS() {
+		stacksBaseAbsPath, err = filepath.Abs(config.Stacks.BasePath)
 		if err != nil {
-			return err
-		}
-		errorMessage := fmt.Sprintf("\nNo stack config files found in the provided "+
-			"paths:\n%s\n\nCheck if 'stacks.base_path', 'stacks.included_paths' and 'stacks.excluded_paths' are correctly set in CLI config "+
-			"files or ENV vars.", j)
-		return errors.New(errorMessage)
-	}
-
-	ProcessedConfig.StackConfigFilesAbsolutePaths = stackConfigFilesAbsolutePaths
-	ProcessedConfig.StackConfigFilesRelativePaths = stackConfigFilesRelativePaths
-
-	if stackIsPhysicalPath == true {
-		if g.LogVerbose {
-			color.Cyan(fmt.Sprintf("\nThe stack '%s' matches the stack config file %s\n",
-				configAndStacksInfo.Stack,
-				stackConfigFilesRelativePaths[0]),
-			)
+			return processedConfig, err
 		}
-		ProcessedConfig.StackType = "Directory"
 	} else {
-		// The stack is a logical name
-		// Check if it matches the pattern specified in 'StackNamePattern'
-		stackParts := strings.Split(configAndStacksInfo.Stack, "-")
-		stackNamePatternParts := strings.Split(Config.Stacks.NamePattern, "-")
-
-		if len(stackParts) == len(stackNamePatternParts) {
-			if g.LogVerbose {
-				color.Cyan(fmt.Sprintf("\nThe stack '%s' matches the stack name pattern '%s'",
-					configAndStacksInfo.Stack,
-					Config.Stacks.NamePattern),
-				)
-			}
-			ProcessedConfig.StackType = "Logical"
-		} else {
-			errorMessage := fmt.Sprintf("\nThe stack '%s' does not exist in the config directories, and it does not match the stack name pattern '%s'",
-				configAndStacksInfo.Stack,
-				Config.Stacks.NamePattern,
-			)
-			return errors.New(errorMessage)
+		stacksBaseAbsPath = path.Clean(filepath.ToSlash(config.Stacks.BasePath))
+		if !fs.ValidPath(stacksBaseAbsPath) {
+			return processedConfig, errors.New(fmt.Sprintf("\n'stacks.base_path' must be a relative path in the stacks filesystem, but it's '%s'", config.Stacks.BasePath))
 		}
 	}
+	processedConfig.StacksBaseAbsolutePath = stacksBaseAbsPath
 
-	if g.LogVerbose {
-		color.Cyan("\nFinal CLI configuration:")
-		err = u.PrintAsYAML(Config)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
-// ProcessConfigForSpacelift processes config for Spacelift
-func ProcessConfigForSpacelift() error {
-	// Process ENV vars
-	err := processEnvVars()
-	if err != nil {
-		return err
-	}
-
-	// Check config
-	err = checkConfig()
+	// Convert the included stack paths to absolute paths
+	includeStackAbsPaths, err := u.JoinAbsolutePathWithPaths(stacksBaseAbsPath, config.Stacks.IncludedPaths)
 	if err != nil {
-		return err
+		return processedConfig, err
 	}
+	processedConfig.IncludeStackAbsolutePaths = includeStackAbsPaths
 
-	// Convert stacks base path to absolute path
-	stacksBaseAbsPath, err := filepath.Abs(Config.Stacks.BasePath)
+	// Convert the excluded stack paths to absolute paths
+	excludeStackAbsPaths, err := u.JoinAbsolutePathWithPaths(stacksBaseAbsPath, config.Stacks.ExcludedPaths)
 	if err != nil {
-		return err
+		return processedConfig, err
 	}
-	ProcessedConfig.StacksBaseAbsolutePath = stacksBaseAbsPath
+	processedConfig.ExcludeStackAbsolutePaths = excludeStackAbsPaths
 
-	// Convert the included stack paths to absolute paths
-	includeStackAbsPaths, err := u.JoinAbsolutePathWithPaths(stacksBaseAbsPath, Config.Stacks.IncludedPaths)
+	// Convert terraform dir to absolute path
+	terraformDirAbsPath, err := filepath.Abs(config.Components.Terraform.BasePath)
 	if err != nil {
-		return err
+		return processedConfig, err
 	}
-	ProcessedConfig.IncludeStackAbsolutePaths = includeStackAbsPaths
+	processedConfig.TerraformDirAbsolutePath = terraformDirAbsPath
 
-	// Convert the excluded stack paths to absolute paths
-	excludeStackAbsPaths, err := u.JoinAbsolutePathWithPaths(stacksBaseAbsPath, Config.Stacks.ExcludedPaths)
+	// Convert helmfile dir to absolute path
+	helmfileDirAbsPath, err := filepath.Abs(config.Components.Helmfile.BasePath)
 	if err != nil {
-		return err
+		return processedConfig, err
 	}
-	ProcessedConfig.ExcludeStackAbsolutePaths = excludeStackAbsPaths
+	processedConfig.HelmfileDirAbsolutePath = helmfileDirAbsPath
 
-	// Convert terraform dir to absolute path
-	terraformDirAbsPath, err := filepath.Abs(Config.Components.Terraform.BasePath)
+	// Convert schemas dir to absolute path
+	schemasDirAbsPath, err := filepath.Abs(config.Schemas.BasePath)
 	if err != nil {
-		return err
+		return processedConfig, err
 	}
-	ProcessedConfig.TerraformDirAbsolutePath = terraformDirAbsPath
+	processedConfig.SchemasDirAbsolutePath = schemasDirAbsPath
 
-	// Convert helmfile dir to absolute path
-	helmfileDirAbsPath, err := filepath.Abs(Config.Components.Helmfile.BasePath)
+	// Convert policies dir to absolute path
+	policiesDirAbsPath, err := filepath.Abs(config.Policies.BasePath)
 	if err != nil {
-		return err
+		return processedConfig, err
 	}
-	ProcessedConfig.HelmfileDirAbsolutePath = helmfileDirAbsPath
+	processedConfig.PoliciesDirAbsolutePath = policiesDirAbsPath
 
-	// If the specified stack name is a logical name, find all stack config files in the provided paths
+	// Find all stack config files in the provided paths
 	stackConfigFilesAbsolutePaths, stackConfigFilesRelativePaths, err := findAllStackConfigsInPaths(
+		fileSystem,
+		stacksBaseAbsPath,
 		includeStackAbsPaths,
 		excludeStackAbsPaths,
 	)
 
 	if err != nil {
-		return err
+		return processedConfig, err
 	}
 
 	if len(stackConfigFilesAbsolutePaths) < 1 {
 		j, err := yaml.Marshal(includeStackAbsPaths)
 		if err != nil {
-			return err
+			return processedConfig, err
 		}
 		errorMessage := fmt.Sprintf("\nNo stack config files found in the provided "+
 			"paths:\n%s\n\nCheck if 'stacks.base_path', 'stacks.included_paths' and 'stacks.excluded_paths' are correctly set in CLI config "+
 			"files or ENV vars.", j)
-		return errors.New(errorMessage)
+		return processedConfig, errors.New(errorMessage)
 	}
 
-	ProcessedConfig.StackConfigFilesAbsolutePaths = stackConfigFilesAbsolutePaths
-	ProcessedConfig.StackConfigFilesRelativePaths = stackConfigFilesRelativePaths
+	processedConfig.StackConfigFilesAbsolutePaths = stackConfigFilesAbsolutePaths
+	processedConfig.StackConfigFilesRelativePaths = stackConfigFilesRelativePaths
 
-	return nil
+	return processedConfig, nil
 }
 
 // https://github.com/NCAR/go-figure
@@ -384,15 +370,11 @@ func ProcessConfigForSpacelift() error {
 // https://medium.com/@bnprashanth256/reading-configuration-files-and-environment-variables-in-go-golang-c2607f912b63
 func processConfigFile(path string, v *viper.Viper) error {
 	if !u.FileExists(path) {
-		if g.LogVerbose {
-			fmt.Println(fmt.Sprintf("No config found in %s", path))
-		}
+		l.Debug("No config found in %s", path)
 		return nil
 	}
 
-	if g.LogVerbose {
-		color.Green("Found config in %s", path)
-	}
+	l.Debug("Found config in %s", path)
 
 	reader, err := os.Open(path)
 	if err != nil {
@@ -402,7 +384,7 @@ func processConfigFile(path string, v *viper.Viper) error {
 	defer func(reader *os.File) {
 		err := reader.Close()
 		if err != nil {
-			color.Red("Error closing file " + path + ". " + err.Error())
+			l.Error("Error closing file %s. %s", path, err)
 		}
 	}(reader)
 
@@ -411,9 +393,7 @@ func processConfigFile(path string, v *viper.Viper) error {
 		return err
 	}
 
-	if g.LogVerbose {
-		color.Green("Processed config %s", path)
-	}
+	l.Debug("Processed config %s", path)
 
 	return nil
 }
@@ -1,10 +1,26 @@
 package config
 
+import (
+	"github.com/cloudposse/atmos/pkg/mask"
+)
+
 type Terraform struct {
 	BasePath                string `yaml:"base_path" json:"base_path" mapstructure:"base_path"`
 	ApplyAutoApprove        bool   `yaml:"apply_auto_approve" json:"apply_auto_approve" mapstructure:"apply_auto_approve"`
 	DeployRunInit           bool   `yaml:"deploy_run_init" json:"deploy_run_init" mapstructure:"deploy_run_init"`
 	AutoGenerateBackendFile bool   `yaml:"auto_generate_backend_file" json:"auto_generate_backend_file" mapstructure:"auto_generate_backend_file"`
+	WorkspacePattern        string `yaml:"workspace_pattern" json:"workspace_pattern" mapstructure:"workspace_pattern"`
+	// WorkingDirs configures running terraform in a separate working directory for each component in each stack
+	WorkingDirs WorkingDirs `yaml:"working_dirs" json:"working_dirs" mapstructure:"working_dirs"`
+}
+
+type WorkingDirs struct {
+	Enabled  bool   `yaml:"enabled" json:"enabled" mapstructure:"enabled"`
+	BasePath string `yaml:"base_path" json:"base_path" mapstructure:"base_path"`
+	// Mode is `symlink` (link the files of the component) or `copy` (copy the files of the component)
+	Mode string `yaml:"mode" json:"mode" mapstructure:"mode"`
+	// Cleanup deletes the working directory after the terraform command completes
+	Cleanup bool `yaml:"cleanup" json:"cleanup" mapstructure:"cleanup"`
 }
 
 type Helmfile struct {
@@ -24,17 +40,47 @@ type Stacks struct {
 	IncludedPaths []string `yaml:"included_paths" json:"included_paths" mapstructure:"included_paths"`
 	ExcludedPaths []string `yaml:"excluded_paths" json:"excluded_paths" mapstructure:"excluded_paths"`
 	NamePattern   string   `yaml:"name_pattern" json:"name_pattern" mapstructure:"name_pattern"`
+	// ImportsCacheDir is the local directory to fetch the imports from git repositories and archives into
+	ImportsCacheDir string `yaml:"imports_cache_dir" json:"imports_cache_dir" mapstructure:"imports_cache_dir"`
+	// YAMLTags configures the custom YAML tags in the stack config files
+	YAMLTags YAMLTags `yaml:"yaml_tags" json:"yaml_tags" mapstructure:"yaml_tags"`
+}
+
+type YAMLTags struct {
+	// ExecEnabled allows the `!exec` YAML tag, which executes commands when the stack config files are processed
+	ExecEnabled bool `yaml:"exec_enabled" json:"exec_enabled" mapstructure:"exec_enabled"`
+}
+
+type Schemas struct {
+	BasePath string `yaml:"base_path" json:"base_path" mapstructure:"base_path"`
+}
+
+type Policies struct {
+	BasePath string `yaml:"base_path" json:"base_path" mapstructure:"base_path"`
 }
 
 type Logs struct {
-	Verbose bool `yaml:"verbose" json:"verbose" mapstructure:"verbose"`
-	Colors  bool `yaml:"colors" json:"colors" mapstructure:"colors"`
+	Verbose bool   `yaml:"verbose" json:"verbose" mapstructure:"verbose"`
+	Colors  bool   `yaml:"colors" json:"colors" mapstructure:"colors"`
+	Level   string `yaml:"level" json:"level" mapstructure:"level"`
+	Format  string `yaml:"format" json:"format" mapstructure:"format"`
+	File    string `yaml:"file" json:"file" mapstructure:"file"`
+}
+
+type Masking struct {
+	KeyPatterns []string `yaml:"key_patterns" json:"key_patterns" mapstructure:"key_patterns"`
 }
 
 type Configuration struct {
 	Components Components
 	Stacks     Stacks
+	Schemas    Schemas
+	Policies   Policies
 	Logs       Logs
+	Masking    Masking
+	// CI enables the CI mode: the commands which require a user interaction fail, terraform runs with `-input=false`,
+	// and the output of the executed commands is printed in the `::group::` / `::endgroup::` blocks
+	CI bool `yaml:"ci" json:"ci" mapstructure:"ci"`
 }
 
 type ProcessedConfiguration struct {
@@ -43,6 +89,8 @@ type ProcessedConfiguration struct {
 	ExcludeStackAbsolutePaths     []string `yaml:"ExcludeStackAbsolutePaths" json:"ExcludeStackAbsolutePaths"`
 	TerraformDirAbsolutePath      string   `yaml:"TerraformDirAbsolutePath" json:"TerraformDirAbsolutePath"`
 	HelmfileDirAbsolutePath       string   `yaml:"HelmfileDirAbsolutePath" json:"HelmfileDirAbsolutePath"`
+	SchemasDirAbsolutePath        string   `yaml:"SchemasDirAbsolutePath" json:"SchemasDirAbsolutePath"`
+	PoliciesDirAbsolutePath       string   `yaml:"PoliciesDirAbsolutePath" json:"PoliciesDirAbsolutePath"`
 	StackConfigFilesRelativePaths []string `yaml:"StackConfigFilesRelativePaths" json:"StackConfigFilesRelativePaths"`
 	StackConfigFilesAbsolutePaths []string `yaml:"StackConfigFilesAbsolutePaths" json:"StackConfigFilesAbsolutePaths"`
 	StackType                     string   `yaml:"StackType" json:"StackType"`
@@ -65,9 +113,15 @@ type ArgsAndFlagsInfo struct {
 	HelmfileDir             string
 	ConfigDir               string
 	StacksDir               string
+	StacksArchive           string
 	DeployRunInit           string
 	AutoGenerateBackendFile string
 	UseTerraformPlan        bool
+	PlanSummary             bool
+	PlanSummaryFile         string
+	CI                      bool
+	LogLevel                string
+	Quiet                   bool
 }
 
 type ConfigAndStacksInfo struct {
@@ -80,21 +134,31 @@ type ConfigAndStacksInfo struct {
 	BaseComponent             string
 	Command                   string
 	SubCommand                string
-	ComponentVarsSection      map[interface{}]interface{}
-	ComponentEnvSection       map[interface{}]interface{}
+	ComponentVarsSection      map[string]interface{}
+	ComponentEnvSection       map[string]interface{}
 	ComponentEnvList          []string
-	ComponentBackendSection   map[interface{}]interface{}
+	ComponentSettingsSection  map[string]interface{}
+	ComponentBackendSection   map[string]interface{}
 	ComponentBackendType      string
+	ComponentProvidersSection map[string]interface{}
 	AdditionalArgsAndFlags    []string
 	GlobalOptions             []string
 	TerraformDir              string
 	HelmfileDir               string
 	ConfigDir                 string
 	StacksDir                 string
+	StacksArchive             string
 	Context                   Context
 	ContextPrefix             string
+	TerraformWorkspace        string
 	DeployRunInit             string
 	AutoGenerateBackendFile   string
 	UseTerraformPlan          bool
+	PlanSummary               bool
+	PlanSummaryFile           string
 	ComponentInheritanceChain []string
+	// Masker masks the sensitive values of the component (including the resolved secrets) in the logs
+	Masker *mask.Masker
+	// CI is set by the `--ci` flag
+	CI bool
 }
@@ -3,93 +3,20 @@ package config
 import (
 	"errors"
 	"fmt"
-	"github.com/bmatcuk/doublestar/v4"
 	g "github.com/cloudposse/atmos/pkg/globals"
+	l "github.com/cloudposse/atmos/pkg/logger"
 	s "github.com/cloudposse/atmos/pkg/stack"
 	u "github.com/cloudposse/atmos/pkg/utils"
-	"github.com/fatih/color"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 )
 
-// findAllStackConfigsInPathsForStack finds all stack config files in the paths specified by globs for the provided stack
-func findAllStackConfigsInPathsForStack(
-	stack string,
-	includeStackPaths []string,
-	excludeStackPaths []string,
-) ([]string, []string, bool, error) {
-
-	var absolutePaths []string
-	var relativePaths []string
-
-	for _, p := range includeStackPaths {
-		pathWithExt := p
-
-		ext := filepath.Ext(p)
-		if ext == "" {
-			ext = g.DefaultStackConfigFileExtension
-			pathWithExt = p + ext
-		}
-
-		// Find all matches in the glob
-		matches, err := s.GetGlobMatches(pathWithExt)
-		if err != nil {
-			return nil, nil, false, err
-		}
-
-		// Exclude files that match any of the excludePaths
-		if matches != nil && len(matches) > 0 {
-			for _, matchedFileAbsolutePath := range matches {
-				matchedFileRelativePath := u.TrimBasePathFromPath(ProcessedConfig.StacksBaseAbsolutePath+"/", matchedFileAbsolutePath)
-
-				// Check if the provided stack matches a file in the config folders (excluding the files from `excludeStackPaths`)
-				stackMatch := strings.HasSuffix(matchedFileAbsolutePath, stack+g.DefaultStackConfigFileExtension)
-				if stackMatch == true {
-					allExcluded := true
-					for _, excludePath := range excludeStackPaths {
-						excludeMatch, err := doublestar.PathMatch(excludePath, matchedFileAbsolutePath)
-						if err != nil {
-							color.Red("%s", err)
-							continue
-						} else if excludeMatch == true {
-							allExcluded = false
-							break
-						}
-					}
-					if allExcluded == true {
-						return []string{matchedFileAbsolutePath}, []string{matchedFileRelativePath}, true, nil
-					}
-				}
-
-				include := true
-
-				for _, excludePath := range excludeStackPaths {
-					excludeMatch, err := doublestar.PathMatch(excludePath, matchedFileAbsolutePath)
-					if err != nil {
-						color.Red("%s", err)
-						include = false
-						continue
-					} else if excludeMatch {
-						include = false
-						continue
-					}
-				}
-
-				if include == true {
-					absolutePaths = append(absolutePaths, matchedFileAbsolutePath)
-					relativePaths = append(relativePaths, matchedFileRelativePath)
-				}
-			}
-		}
-	}
-
-	return absolutePaths, relativePaths, false, nil
-}
-
-// findAllStackConfigsInPaths finds all stack config files in the paths specified by globs
+// findAllStackConfigsInPaths finds all stack config files in the filesystem in the paths specified by globs
 func findAllStackConfigsInPaths(
+	fileSystem *s.FileSystem,
+	stacksBaseAbsolutePath string,
 	includeStackPaths []string,
 	excludeStackPaths []string,
 ) ([]string, []string, error) {
@@ -107,7 +34,7 @@ func findAllStackConfigsInPaths(
 		}
 
 		// Find all matches in the glob
-		matches, err := s.GetGlobMatches(pathWithExt)
+		matches, err := fileSystem.GetGlobMatches(pathWithExt)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -115,13 +42,13 @@ func findAllStackConfigsInPaths(
 		// Exclude files that match any of the excludePaths
 		if matches != nil && len(matches) > 0 {
 			for _, matchedFileAbsolutePath := range matches {
-				matchedFileRelativePath := u.TrimBasePathFromPath(ProcessedConfig.StacksBaseAbsolutePath+"/", matchedFileAbsolutePath)
+				matchedFileRelativePath := u.TrimBasePathFromPath(stacksBaseAbsolutePath+"/", matchedFileAbsolutePath)
 				include := true
 
 				for _, excludePath := range excludeStackPaths {
-					excludeMatch, err := doublestar.PathMatch(excludePath, matchedFileAbsolutePath)
+					excludeMatch, err := fileSystem.PathMatch(excludePath, matchedFileAbsolutePath)
 					if err != nil {
-						color.Red("%s", err)
+						l.Error("%s", err)
 						include = false
 						continue
 					} else if excludeMatch {
@@ -141,122 +68,259 @@ func findAllStackConfigsInPaths(
 	return absolutePaths, relativePaths, nil
 }
 
-func processEnvVars() error {
+func processEnvVars(config *Configuration) error {
 	stacksBasePath := os.Getenv("ATMOS_STACKS_BASE_PATH")
 	if len(stacksBasePath) > 0 {
-		color.Cyan("Found ENV var ATMOS_STACKS_BASE_PATH=%s", stacksBasePath)
-		Config.Stacks.BasePath = stacksBasePath
+		l.Debug("Found ENV var ATMOS_STACKS_BASE_PATH=%s", stacksBasePath)
+		config.Stacks.BasePath = stacksBasePath
 	}
 
 	stacksIncludedPaths := os.Getenv("ATMOS_STACKS_INCLUDED_PATHS")
 	if len(stacksIncludedPaths) > 0 {
-		color.Cyan("Found ENV var ATMOS_STACKS_INCLUDED_PATHS=%s", stacksIncludedPaths)
-		Config.Stacks.IncludedPaths = strings.Split(stacksIncludedPaths, ",")
+		l.Debug("Found ENV var ATMOS_STACKS_INCLUDED_PATHS=%s", stacksIncludedPaths)
+		config.Stacks.IncludedPaths = strings.Split(stacksIncludedPaths, ",")
 	}
 
 	stacksExcludedPaths := os.Getenv("ATMOS_STACKS_EXCLUDED_PATHS")
 	if len(stacksExcludedPaths) > 0 {
-		color.Cyan("Found ENV var ATMOS_STACKS_EXCLUDED_PATHS=%s", stacksExcludedPaths)
-		Config.Stacks.ExcludedPaths = strings.Split(stacksExcludedPaths, ",")
+		l.Debug("Found ENV var ATMOS_STACKS_EXCLUDED_PATHS=%s", stacksExcludedPaths)
+		config.Stacks.ExcludedPaths = strings.Split(stacksExcludedPaths, ",")
 	}
 
 	stacksNamePattern := os.Getenv("ATMOS_STACKS_NAME_PATTERN")
 	if len(stacksNamePattern) > 0 {
-		color.Cyan("Found ENV var ATMOS_STACKS_NAME_PATTERN=%s", stacksNamePattern)
-		Config.Stacks.NamePattern = stacksNamePattern
+		l.Debug("Found ENV var ATMOS_STACKS_NAME_PATTERN=%s", stacksNamePattern)
+		config.Stacks.NamePattern = stacksNamePattern
+	}
+
+	stacksImportsCacheDir := os.Getenv("ATMOS_STACKS_IMPORTS_CACHE_DIR")
+	if len(stacksImportsCacheDir) > 0 {
+		l.Debug("Found ENV var ATMOS_STACKS_IMPORTS_CACHE_DIR=%s", stacksImportsCacheDir)
+		config.Stacks.ImportsCacheDir = stacksImportsCacheDir
+	}
+
+	stacksYAMLTagsExecEnabled := os.Getenv("ATMOS_STACKS_YAML_TAGS_EXEC_ENABLED")
+	if len(stacksYAMLTagsExecEnabled) > 0 {
+		l.Debug("Found ENV var ATMOS_STACKS_YAML_TAGS_EXEC_ENABLED=%s", stacksYAMLTagsExecEnabled)
+		execEnabledBool, err := strconv.ParseBool(stacksYAMLTagsExecEnabled)
+		if err != nil {
+			return err
+		}
+		config.Stacks.YAMLTags.ExecEnabled = execEnabledBool
+	}
+
+	schemasBasePath := os.Getenv("ATMOS_SCHEMAS_BASE_PATH")
+	if len(schemasBasePath) > 0 {
+		l.Debug("Found ENV var ATMOS_SCHEMAS_BASE_PATH=%s", schemasBasePath)
+		config.Schemas.BasePath = schemasBasePath
+	}
+
+	policiesBasePath := os.Getenv("ATMOS_POLICIES_BASE_PATH")
+	if len(policiesBasePath) > 0 {
+		l.Debug("Found ENV var ATMOS_POLICIES_BASE_PATH=%s", policiesBasePath)
+		config.Policies.BasePath = policiesBasePath
 	}
 
 	componentsTerraformBasePath := os.Getenv("ATMOS_COMPONENTS_TERRAFORM_BASE_PATH")
 	if len(componentsTerraformBasePath) > 0 {
-		color.Cyan("Found ENV var ATMOS_COMPONENTS_TERRAFORM_BASE_PATH=%s", componentsTerraformBasePath)
-		Config.Components.Terraform.BasePath = componentsTerraformBasePath
+		l.Debug("Found ENV var ATMOS_COMPONENTS_TERRAFORM_BASE_PATH=%s", componentsTerraformBasePath)
+		config.Components.Terraform.BasePath = componentsTerraformBasePath
 	}
 
 	componentsTerraformApplyAutoApprove := os.Getenv("ATMOS_COMPONENTS_TERRAFORM_APPLY_AUTO_APPROVE")
 	if len(componentsTerraformApplyAutoApprove) > 0 {
-		color.Cyan("Found ENV var ATMOS_COMPONENTS_TERRAFORM_APPLY_AUTO_APPROVE=%s", componentsTerraformApplyAutoApprove)
+		l.Debug("Found ENV var ATMOS_COMPONENTS_TERRAFORM_APPLY_AUTO_APPROVE=%s", componentsTerraformApplyAutoApprove)
 		applyAutoApproveBool, err := strconv.ParseBool(componentsTerraformApplyAutoApprove)
 		if err != nil {
 			return err
 		}
-		Config.Components.Terraform.ApplyAutoApprove = applyAutoApproveBool
+		config.Components.Terraform.ApplyAutoApprove = applyAutoApproveBool
+	}
+
+	ci := os.Getenv("ATMOS_CI")
+	if len(ci) > 0 {
+		l.Debug("Found ENV var ATMOS_CI=%s", ci)
+		ciBool, err := strconv.ParseBool(ci)
+		if err != nil {
+			return errors.New(fmt.Sprintf("invalid ENV var ATMOS_CI=%s: %s", ci, err))
+		}
+		config.CI = ciBool
 	}
 
 	componentsTerraformDeployRunInit := os.Getenv("ATMOS_COMPONENTS_TERRAFORM_DEPLOY_RUN_INIT")
 	if len(componentsTerraformDeployRunInit) > 0 {
-		color.Cyan("Found ENV var ATMOS_COMPONENTS_TERRAFORM_DEPLOY_RUN_INIT=%s", componentsTerraformDeployRunInit)
+		l.Debug("Found ENV var ATMOS_COMPONENTS_TERRAFORM_DEPLOY_RUN_INIT=%s", componentsTerraformDeployRunInit)
 		deployRunInitBool, err := strconv.ParseBool(componentsTerraformDeployRunInit)
 		if err != nil {
 			return err
 		}
-		Config.Components.Terraform.DeployRunInit = deployRunInitBool
+		config.Components.Terraform.DeployRunInit = deployRunInitBool
 	}
 
 	componentsTerraformAutoGenerateBackendFile := os.Getenv("ATMOS_COMPONENTS_TERRAFORM_AUTO_GENERATE_BACKEND_FILE")
 	if len(componentsTerraformAutoGenerateBackendFile) > 0 {
-		color.Cyan("Found ENV var ATMOS_COMPONENTS_TERRAFORM_AUTO_GENERATE_BACKEND_FILE=%s", componentsTerraformAutoGenerateBackendFile)
+		l.Debug("Found ENV var ATMOS_COMPONENTS_TERRAFORM_AUTO_GENERATE_BACKEND_FILE=%s", componentsTerraformAutoGenerateBackendFile)
 		componentsTerraformAutoGenerateBackendFileBool, err := strconv.ParseBool(componentsTerraformAutoGenerateBackendFile)
 		if err != nil {
 			return err
 		}
-		Config.Components.Terraform.AutoGenerateBackendFile = componentsTerraformAutoGenerateBackendFileBool
+		config.Components.Terraform.AutoGenerateBackendFile = componentsTerraformAutoGenerateBackendFileBool
+	}
+
+	componentsTerraformWorkingDirsEnabled := os.Getenv("ATMOS_COMPONENTS_TERRAFORM_WORKING_DIRS_ENABLED")
+	if len(componentsTerraformWorkingDirsEnabled) > 0 {
+		l.Debug("Found ENV var ATMOS_COMPONENTS_TERRAFORM_WORKING_DIRS_ENABLED=%s", componentsTerraformWorkingDirsEnabled)
+		workingDirsEnabledBool, err := strconv.ParseBool(componentsTerraformWorkingDirsEnabled)
+		if err != nil {
+			return err
+		}
+		config.Components.Terraform.WorkingDirs.Enabled = workingDirsEnabledBool
+	}
+
+	componentsTerraformWorkingDirsBasePath := os.Getenv("ATMOS_COMPONENTS_TERRAFORM_WORKING_DIRS_BASE_PATH")
+	if len(componentsTerraformWorkingDirsBasePath) > 0 {
+		l.Debug("Found ENV var ATMOS_COMPONENTS_TERRAFORM_WORKING_DIRS_BASE_PATH=%s", componentsTerraformWorkingDirsBasePath)
+		config.Components.Terraform.WorkingDirs.BasePath = componentsTerraformWorkingDirsBasePath
+	}
+
+	componentsTerraformWorkingDirsMode := os.Getenv("ATMOS_COMPONENTS_TERRAFORM_WORKING_DIRS_MODE")
+	if len(componentsTerraformWorkingDirsMode) > 0 {
+		l.Debug("Found ENV var ATMOS_COMPONENTS_TERRAFORM_WORKING_DIRS_MODE=%s", componentsTerraformWorkingDirsMode)
+		config.Components.Terraform.WorkingDirs.Mode = componentsTerraformWorkingDirsMode
+	}
+
+	componentsTerraformWorkingDirsCleanup := os.Getenv("ATMOS_COMPONENTS_TERRAFORM_WORKING_DIRS_CLEANUP")
+	if len(componentsTerraformWorkingDirsCleanup) > 0 {
+		l.Debug("Found ENV var ATMOS_COMPONENTS_TERRAFORM_WORKING_DIRS_CLEANUP=%s", componentsTerraformWorkingDirsCleanup)
+		workingDirsCleanupBool, err := strconv.ParseBool(componentsTerraformWorkingDirsCleanup)
+		if err != nil {
+			return err
+		}
+		config.Components.Terraform.WorkingDirs.Cleanup = workingDirsCleanupBool
+	}
+
+	componentsTerraformWorkspacePattern := os.Getenv("ATMOS_COMPONENTS_TERRAFORM_WORKSPACE_PATTERN")
+	if len(componentsTerraformWorkspacePattern) > 0 {
+		l.Debug("Found ENV var ATMOS_COMPONENTS_TERRAFORM_WORKSPACE_PATTERN=%s", componentsTerraformWorkspacePattern)
+		config.Components.Terraform.WorkspacePattern = componentsTerraformWorkspacePattern
 	}
 
 	componentsHelmfileBasePath := os.Getenv("ATMOS_COMPONENTS_HELMFILE_BASE_PATH")
 	if len(componentsHelmfileBasePath) > 0 {
-		color.Cyan("Found ENV var ATMOS_COMPONENTS_HELMFILE_BASE_PATH=%s", componentsHelmfileBasePath)
-		Config.Components.Helmfile.BasePath = componentsHelmfileBasePath
+		l.Debug("Found ENV var ATMOS_COMPONENTS_HELMFILE_BASE_PATH=%s", componentsHelmfileBasePath)
+		config.Components.Helmfile.BasePath = componentsHelmfileBasePath
 	}
 
 	componentsHelmfileKubeconfigPath := os.Getenv("ATMOS_COMPONENTS_HELMFILE_KUBECONFIG_PATH")
 	if len(componentsHelmfileKubeconfigPath) > 0 {
-		color.Cyan("Found ENV var ATMOS_COMPONENTS_HELMFILE_KUBECONFIG_PATH=%s", componentsHelmfileKubeconfigPath)
-		Config.Components.Helmfile.KubeconfigPath = componentsHelmfileKubeconfigPath
+		l.Debug("Found ENV var ATMOS_COMPONENTS_HELMFILE_KUBECONFIG_PATH=%s", componentsHelmfileKubeconfigPath)
+		config.Components.Helmfile.KubeconfigPath = componentsHelmfileKubeconfigPath
 	}
 
 	componentsHelmfileHelmAwsProfilePattern := os.Getenv("ATMOS_COMPONENTS_HELMFILE_HELM_AWS_PROFILE_PATTERN")
 	if len(componentsHelmfileHelmAwsProfilePattern) > 0 {
-		color.Cyan("Found ENV var ATMOS_COMPONENTS_HELMFILE_HELM_AWS_PROFILE_PATTERN=%s", componentsHelmfileHelmAwsProfilePattern)
-		Config.Components.Helmfile.HelmAwsProfilePattern = componentsHelmfileHelmAwsProfilePattern
+		l.Debug("Found ENV var ATMOS_COMPONENTS_HELMFILE_HELM_AWS_PROFILE_PATTERN=%s", componentsHelmfileHelmAwsProfilePattern)
+		config.Components.Helmfile.HelmAwsProfilePattern = componentsHelmfileHelmAwsProfilePattern
 	}
 
 	componentsHelmfileClusterNamePattern := os.Getenv("ATMOS_COMPONENTS_HELMFILE_CLUSTER_NAME_PATTERN")
 	if len(componentsHelmfileClusterNamePattern) > 0 {
-		color.Cyan("Found ENV var ATMOS_COMPONENTS_HELMFILE_CLUSTER_NAME_PATTERN=%s", componentsHelmfileClusterNamePattern)
-		Config.Components.Helmfile.ClusterNamePattern = componentsHelmfileClusterNamePattern
+		l.Debug("Found ENV var ATMOS_COMPONENTS_HELMFILE_CLUSTER_NAME_PATTERN=%s", componentsHelmfileClusterNamePattern)
+		config.Components.Helmfile.ClusterNamePattern = componentsHelmfileClusterNamePattern
+	}
+
+	maskingKeyPatterns := os.Getenv("ATMOS_MASKING_KEY_PATTERNS")
+	if len(maskingKeyPatterns) > 0 {
+		l.Debug("Found ENV var ATMOS_MASKING_KEY_PATTERNS=%s", maskingKeyPatterns)
+		config.Masking.KeyPatterns = strings.Split(maskingKeyPatterns, ",")
 	}
 
 	return nil
 }
 
-func checkConfig() error {
-	if len(Config.Stacks.BasePath) < 1 {
+func checkConfig(config Configuration) error {
+	if len(config.Stacks.BasePath) < 1 {
 		return errors.New("stack base path must be provided in 'stacks.base_path' config or ATMOS_STACKS_BASE_PATH' ENV variable")
 	}
 
-	if len(Config.Stacks.IncludedPaths) < 1 {
+	if len(config.Stacks.IncludedPaths) < 1 {
 		return errors.New("at least one path must be provided in 'stacks.included_paths' config or ATMOS_STACKS_INCLUDED_PATHS' ENV variable")
 	}
 
 	return nil
 }
 
-func processLogsConfig() error {
-	logVerbose := os.Getenv("ATMOS_LOGS_VERBOSE")
-	if len(logVerbose) > 0 {
-		color.Cyan("Found ENV var ATMOS_LOGS_VERBOSE=%s", logVerbose)
-		logVerboseBool, err := strconv.ParseBool(logVerbose)
+// processLogsEnvVars applies the `ATMOS_LOGS_*` ENV vars to the logs config
+func processLogsEnvVars(logs *Logs) error {
+	logsVerbose := os.Getenv("ATMOS_LOGS_VERBOSE")
+	if len(logsVerbose) > 0 {
+		logsVerboseBool, err := strconv.ParseBool(logsVerbose)
 		if err != nil {
-			return err
+			return errors.New(fmt.Sprintf("invalid ENV var ATMOS_LOGS_VERBOSE=%s: %s", logsVerbose, err))
+		}
+		logs.Verbose = logsVerboseBool
+	}
+
+	logsLevel := os.Getenv("ATMOS_LOGS_LEVEL")
+	if len(logsLevel) > 0 {
+		logs.Level = logsLevel
+	}
+
+	logsFormat := os.Getenv("ATMOS_LOGS_FORMAT")
+	if len(logsFormat) > 0 {
+		logs.Format = logsFormat
+	}
+
+	logsFile := os.Getenv("ATMOS_LOGS_FILE")
+	if len(logsFile) > 0 {
+		logs.File = logsFile
+	}
+
+	logsColors := os.Getenv("ATMOS_LOGS_COLORS")
+	if len(logsColors) > 0 {
+		logsColorsBool, err := strconv.ParseBool(logsColors)
+		if err != nil {
+			return errors.New(fmt.Sprintf("invalid ENV var ATMOS_LOGS_COLORS=%s: %s", logsColors, err))
 		}
-		Config.Logs.Verbose = logVerboseBool
-		g.LogVerbose = logVerboseBool
+		logs.Colors = logsColorsBool
 	}
+
 	return nil
 }
 
+// configureLogger applies the logs config to the logger. `logs.verbose` is the same as the `debug` level
+func configureLogger(logs Logs) error {
+	level := l.LevelInfo
+	if len(logs.Level) > 0 {
+		var err error
+		level, err = l.ParseLevel(logs.Level)
+		if err != nil {
+			return errors.New(fmt.Sprintf("invalid 'logs.level' config: %s", err))
+		}
+	}
+	if logs.Verbose && level > l.LevelDebug {
+		level = l.LevelDebug
+	}
+
+	format := logs.Format
+	if len(format) == 0 {
+		format = l.FormatText
+	}
+
+	if format != l.FormatText && format != l.FormatJSON {
+		return errors.New(fmt.Sprintf("invalid 'logs.format' config '%s'. Valid values are '%s' and '%s'", format, l.FormatText, l.FormatJSON))
+	}
+
+	return l.Configure(l.Options{
+		Level:  level,
+		Format: format,
+		File:   logs.File,
+		Colors: logs.Colors,
+	})
+}
+
 // GetContextFromVars creates a context object from the provided variables
-func GetContextFromVars(vars map[interface{}]interface{}) Context {
+func GetContextFromVars(vars map[string]interface{}) Context {
 	var context Context
 
 	if namespace, ok := vars["namespace"].(string); ok {
@@ -323,7 +387,7 @@ func GetContextPrefix(stack string, context Context, stackNamePattern string) (s
 			if len(context.Stage) == 0 {
 				return "",
 					errors.New(fmt.Sprintf("The stack name pattern '%s' specifies 'stage`, but the stack %s does not have a stage defined",
-						Config.Stacks.NamePattern,
+						stackNamePattern,
 						stack,
 					))
 			}
@@ -349,3 +413,55 @@ func ReplaceContextTokens(context Context, pattern string) string {
 			"{tenant}", context.Tenant, 1),
 		"{stage}", context.Stage, 1)
 }
+
+// GetTerraformWorkspace returns the terraform workspace of the component in the stack (the logical stack name).
+// The workspace name is taken from `metadata.terraform_workspace`, or built from `metadata.terraform_workspace_pattern`
+// or the `components.terraform.workspace_pattern` CLI config. If no pattern is set, the workspace is the stack name
+// for the components without a base component, and `<stack>-<component>` otherwise. `/` in the workspace name are replaced by `-`
+func GetTerraformWorkspace(workspacePattern string, stack string, component string, componentConfig s.ComponentConfig) (string, error) {
+	if workspace, ok := componentConfig.Metadata["terraform_workspace"].(string); ok && len(workspace) > 0 {
+		return strings.Replace(workspace, "/", "-", -1), nil
+	}
+
+	if pattern, ok := componentConfig.Metadata["terraform_workspace_pattern"].(string); ok && len(pattern) > 0 {
+		workspacePattern = pattern
+	}
+
+	if len(workspacePattern) == 0 {
+		if len(componentConfig.BaseComponent) == 0 {
+			workspacePattern = "{stack}"
+		} else {
+			workspacePattern = "{stack}-{component}"
+		}
+	}
+
+	baseComponent := componentConfig.BaseComponent
+	if len(baseComponent) == 0 {
+		baseComponent = component
+	}
+
+	context := GetContextFromVars(componentConfig.Vars)
+	tokens := map[string]string{
+		"{namespace}":      context.Namespace,
+		"{tenant}":         context.Tenant,
+		"{environment}":    context.Environment,
+		"{stage}":          context.Stage,
+		"{stack}":          stack,
+		"{component}":      component,
+		"{base-component}": baseComponent,
+	}
+
+	workspace := workspacePattern
+	for token, value := range tokens {
+		if !strings.Contains(workspace, token) {
+			continue
+		}
+		if len(value) == 0 {
+			return "", errors.New(fmt.Sprintf("The terraform workspace pattern '%s' specifies '%s', but the component '%s' in the stack '%s' does not have it defined",
+				workspacePattern, token, component, stack))
+		}
+		workspace = strings.Replace(workspace, token, value, -1)
+	}
+
+	return strings.Replace(workspace, "/", "-", -1), nil
+}
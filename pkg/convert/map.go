@@ -1,5 +1,7 @@
 package convert
 
+import "fmt"
+
 // MapsOfStringsToMapsOfInterfaces takes map[string]interface{} and returns map[interface{}]interface{}
 func MapsOfStringsToMapsOfInterfaces(input map[string]interface{}) map[interface{}]interface{} {
 	output := map[interface{}]interface{}{}
@@ -17,3 +19,73 @@ func MapsOfInterfacesToMapsOfStrings(input map[interface{}]interface{}) map[stri
 	}
 	return output
 }
+
+// MapsOfInterfacesToMapsOfStringsRecursive takes map[interface{}]interface{} and returns map[string]interface{},
+// converting all nested maps (including the maps in nested slices) as well.
+// The result can be encoded to JSON
+func MapsOfInterfacesToMapsOfStringsRecursive(input map[interface{}]interface{}) map[string]interface{} {
+	if input == nil {
+		return nil
+	}
+	output := map[string]interface{}{}
+	for k, v := range input {
+		output[fmt.Sprintf("%v", k)] = convertValueRecursive(v)
+	}
+	return output
+}
+
+func convertValueRecursive(input interface{}) interface{} {
+	switch v := input.(type) {
+	case map[interface{}]interface{}:
+		return MapsOfInterfacesToMapsOfStringsRecursive(v)
+	case map[string]interface{}:
+		output := map[string]interface{}{}
+		for k, v2 := range v {
+			output[k] = convertValueRecursive(v2)
+		}
+		return output
+	case []interface{}:
+		output := make([]interface{}, len(v))
+		for i, v2 := range v {
+			output[i] = convertValueRecursive(v2)
+		}
+		return output
+	default:
+		return v
+	}
+}
+
+// MapsOfStringsToMapsOfInterfacesRecursive takes map[string]interface{} and returns map[interface{}]interface{},
+// converting all nested maps (including the maps in nested slices) as well.
+// The result has the same types as the maps decoded by `yaml.v2`
+func MapsOfStringsToMapsOfInterfacesRecursive(input map[string]interface{}) map[interface{}]interface{} {
+	if input == nil {
+		return nil
+	}
+	output := map[interface{}]interface{}{}
+	for k, v := range input {
+		output[k] = convertValueToInterfaceKeysRecursive(v)
+	}
+	return output
+}
+
+func convertValueToInterfaceKeysRecursive(input interface{}) interface{} {
+	switch v := input.(type) {
+	case map[string]interface{}:
+		return MapsOfStringsToMapsOfInterfacesRecursive(v)
+	case map[interface{}]interface{}:
+		output := map[interface{}]interface{}{}
+		for k, v2 := range v {
+			output[k] = convertValueToInterfaceKeysRecursive(v2)
+		}
+		return output
+	case []interface{}:
+		output := make([]interface{}, len(v))
+		for i, v2 := range v {
+			output[i] = convertValueToInterfaceKeysRecursive(v2)
+		}
+		return output
+	default:
+		return v
+	}
+}
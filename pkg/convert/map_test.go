@@ -0,0 +1,42 @@
+package convert
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapsOfInterfacesToMapsOfStringsRecursive(t *testing.T) {
+	input := map[interface{}]interface{}{
+		"a": map[interface{}]interface{}{
+			"b": []interface{}{
+				map[interface{}]interface{}{"c": 1},
+			},
+		},
+		1: "one",
+	}
+
+	result := MapsOfInterfacesToMapsOfStringsRecursive(input)
+	assert.Equal(t, "one", result["1"])
+	assert.Equal(t, 1, result["a"].(map[string]interface{})["b"].([]interface{})[0].(map[string]interface{})["c"])
+
+	_, err := json.Marshal(result)
+	assert.Nil(t, err)
+}
+
+func TestMapsOfStringsToMapsOfInterfacesRecursive(t *testing.T) {
+	input := map[string]interface{}{
+		"a": map[string]interface{}{
+			"b": []interface{}{
+				map[string]interface{}{"c": 1},
+			},
+		},
+		"d": "one",
+	}
+
+	result := MapsOfStringsToMapsOfInterfacesRecursive(input)
+	assert.Equal(t, "one", result["d"])
+	assert.Equal(t, 1, result["a"].(map[interface{}]interface{})["b"].([]interface{})[0].(map[interface{}]interface{})["c"])
+	assert.Nil(t, MapsOfStringsToMapsOfInterfacesRecursive(nil))
+}
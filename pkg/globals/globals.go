@@ -14,12 +14,20 @@ const (
 	HelmfileDirFlag             = "--helmfile-dir"
 	ConfigDirFlag               = "--config-dir"
 	StackDirFlag                = "--stacks-dir"
+	StacksArchiveFlag           = "--stacks-archive"
 	DeployRunInitFlag           = "--deploy-run-init"
 	AutoGenerateBackendFileFlag = "--auto-generate-backend-file"
 
 	FromPlanFlag = "--from-plan"
-)
 
-var (
-	LogVerbose = false
+	// Flags to print the summary of the terraform plan and to write it to a JSON or Markdown file
+	PlanSummaryFlag     = "--summary"
+	PlanSummaryFileFlag = "--summary-file"
+
+	// CIFlag enables the CI mode (non-interactive, no colors, group markers around the output of the executed commands)
+	CIFlag = "--ci"
+
+	// Flags to set the level of the atmos logs. `--quiet` prints only the errors
+	LogLevelFlag = "--log-level"
+	QuietFlag    = "--quiet"
 )
@@ -0,0 +1,300 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+	"github.com/pkg/errors"
+)
+
+// Level is the level of the log messages
+type Level int
+
+const (
+	LevelTrace Level = iota
+	LevelDebug
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+var levelNames = map[Level]string{
+	LevelTrace: "trace",
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+// The colors of the messages in the text format
+var levelColors = map[Level]*color.Color{
+	LevelTrace: color.New(color.FgHiBlack),
+	LevelDebug: color.New(color.FgHiBlack),
+	LevelInfo:  color.New(color.FgCyan),
+	LevelWarn:  color.New(color.FgYellow),
+	LevelError: color.New(color.FgRed),
+}
+
+func (level Level) String() string {
+	return levelNames[level]
+}
+
+// ParseLevel returns the level with the provided name (`trace`, `debug`, `info`, `warn` or `error`)
+func ParseLevel(name string) (Level, error) {
+	for level, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return level, nil
+		}
+	}
+	if strings.EqualFold(name, "warning") {
+		return LevelWarn, nil
+	}
+	return LevelInfo, errors.New(fmt.Sprintf("invalid log level '%s'. Valid values are 'trace', 'debug', 'info', 'warn' and 'error'", name))
+}
+
+// Options are the settings of the logger
+type Options struct {
+	Level  Level
+	Format string
+	// File is the file to write the logs to. The logs are written to stderr if it's empty
+	File   string
+	Colors bool
+}
+
+// Logger writes the log messages with the level not lower than the logger level to the output in the text or JSON format
+type Logger struct {
+	mu     sync.Mutex
+	out    io.Writer
+	level  Level
+	format string
+	colors bool
+	mask   func(string) string
+}
+
+// New creates a logger which writes the messages to the provided output
+func New(out io.Writer, level Level, format string, colors bool) *Logger {
+	return &Logger{out: out, level: level, format: format, colors: colors}
+}
+
+var (
+	std           = New(os.Stderr, LevelInfo, FormatText, isTerminal(os.Stderr) && len(os.Getenv("NO_COLOR")) == 0)
+	levelOverride *Level
+	noColors      bool
+	logFile       *os.File
+)
+
+// Default returns the logger used by atmos
+func Default() *Logger {
+	return std
+}
+
+// SetLevelOverride sets the level from the command-line flags (e.g. `--log-level` or `--quiet`).
+// It takes precedence over the level in the CLI config and ENV vars
+func SetLevelOverride(level Level) {
+	levelOverride = &level
+	std.mu.Lock()
+	std.level = level
+	std.mu.Unlock()
+}
+
+// DisableColorsOverride disables the colors of the default logger (e.g. in the CI mode) regardless of the CLI config and ENV vars
+func DisableColorsOverride() {
+	noColors = true
+	std.DisableColors()
+}
+
+// Configure applies the settings from the CLI config and ENV vars to the default logger.
+// The colors are disabled if the `NO_COLOR` ENV var is set, in the CI mode (see `DisableColorsOverride`), or if the logs are not written to a terminal
+func Configure(opts Options) error {
+	if opts.Format != FormatText && opts.Format != FormatJSON {
+		return errors.New(fmt.Sprintf("invalid log format '%s'. Valid values are '%s' and '%s'", opts.Format, FormatText, FormatJSON))
+	}
+
+	var out io.Writer = os.Stderr
+	colors := opts.Colors && len(os.Getenv("NO_COLOR")) == 0 && !noColors
+
+	if len(opts.File) > 0 && opts.File != "/dev/stderr" {
+		if opts.File == "/dev/stdout" {
+			out = os.Stdout
+		} else if logFile == nil || logFile.Name() != opts.File {
+			f, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				return errors.New(fmt.Sprintf("could not open the log file '%s': %s", opts.File, err))
+			}
+			if logFile != nil {
+				_ = logFile.Close()
+			}
+			logFile = f
+			out = f
+		} else {
+			out = logFile
+		}
+	}
+
+	if f, ok := out.(*os.File); ok && !isTerminal(f) {
+		colors = false
+	}
+
+	// The colors of the other output (e.g. the validation issues) follow the logs settings
+	if !opts.Colors || len(os.Getenv("NO_COLOR")) > 0 {
+		color.NoColor = true
+	}
+
+	level := opts.Level
+	if levelOverride != nil {
+		level = *levelOverride
+	}
+
+	std.mu.Lock()
+	defer std.mu.Unlock()
+	std.out = out
+	std.level = level
+	std.format = opts.Format
+	std.colors = colors
+	return nil
+}
+
+// DisableColors disables the colors in the text format
+func (l *Logger) DisableColors() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.colors = false
+}
+
+// SetMask sets the function to mask the sensitive values (e.g. the tokens in the ENV vars of a component) in the messages
+func (l *Logger) SetMask(mask func(string) string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.mask = mask
+}
+
+// WithMask returns a logger which writes the messages to the same output with the same settings,
+// and also masks them with the provided function (e.g. to mask the secrets of another component)
+func (l *Logger) WithMask(mask func(string) string) *Logger {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	res := &Logger{out: l.out, level: l.level, format: l.format, colors: l.colors, mask: mask}
+	if l.mask != nil {
+		parent := l.mask
+		res.mask = func(s string) string {
+			return mask(parent(s))
+		}
+	}
+	return res
+}
+
+// IsLevelEnabled checks if the messages with the level are written (e.g. to not prepare the expensive debug messages)
+func (l *Logger) IsLevelEnabled(level Level) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return level >= l.level
+}
+
+func (l *Logger) Trace(format string, a ...interface{}) {
+	l.log(LevelTrace, format, a...)
+}
+
+func (l *Logger) Debug(format string, a ...interface{}) {
+	l.log(LevelDebug, format, a...)
+}
+
+func (l *Logger) Info(format string, a ...interface{}) {
+	l.log(LevelInfo, format, a...)
+}
+
+func (l *Logger) Warn(format string, a ...interface{}) {
+	l.log(LevelWarn, format, a...)
+}
+
+func (l *Logger) Error(format string, a ...interface{}) {
+	l.log(LevelError, format, a...)
+}
+
+// log writes the message. In the text format, the message is written as is (with the color of the level),
+// in the JSON format, each message is written on a separate line as an object with the `time`, `level` and `msg` fields
+func (l *Logger) log(level Level, format string, a ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if level < l.level {
+		return
+	}
+
+	msg := format
+	if len(a) > 0 {
+		msg = fmt.Sprintf(format, a...)
+	}
+	if l.mask != nil {
+		msg = l.mask(msg)
+	}
+
+	if l.format == FormatJSON {
+		entry, err := json.Marshal(struct {
+			Time  string `json:"time"`
+			Level string `json:"level"`
+			Msg   string `json:"msg"`
+		}{
+			Time:  time.Now().UTC().Format(time.RFC3339),
+			Level: level.String(),
+			Msg:   strings.TrimSpace(msg),
+		})
+		if err != nil {
+			return
+		}
+		_, _ = fmt.Fprintln(l.out, string(entry))
+		return
+	}
+
+	if !strings.HasSuffix(msg, "\n") {
+		msg += "\n"
+	}
+	if l.colors {
+		// Color the message without the trailing new line, so the next output is not colored
+		c := *levelColors[level]
+		c.EnableColor()
+		_, _ = fmt.Fprint(l.out, c.Sprint(strings.TrimSuffix(msg, "\n"))+"\n")
+		return
+	}
+	_, _ = fmt.Fprint(l.out, msg)
+}
+
+func Trace(format string, a ...interface{}) {
+	std.Trace(format, a...)
+}
+
+func Debug(format string, a ...interface{}) {
+	std.Debug(format, a...)
+}
+
+func Info(format string, a ...interface{}) {
+	std.Info(format, a...)
+}
+
+func Warn(format string, a ...interface{}) {
+	std.Warn(format, a...)
+}
+
+func Error(format string, a ...interface{}) {
+	std.Error(format, a...)
+}
+
+// IsLevelEnabled checks if the default logger writes the messages with the level
+func IsLevelEnabled(level Level) bool {
+	return std.IsLevelEnabled(level)
+}
+
+func isTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggerLevels(t *testing.T) {
+	var out bytes.Buffer
+	log := New(&out, LevelWarn, FormatText, false)
+
+	log.Debug("debug message")
+	log.Info("info message")
+	log.Warn("warn message")
+	log.Error("error message: %s", "failed")
+
+	assert.Equal(t, "warn message\nerror message: failed\n", out.String())
+	assert.False(t, log.IsLevelEnabled(LevelInfo))
+	assert.True(t, log.IsLevelEnabled(LevelError))
+}
+
+func TestLoggerJSONFormat(t *testing.T) {
+	var out bytes.Buffer
+	log := New(&out, LevelInfo, FormatJSON, true)
+
+	log.Info("\nWriting variables to file:\n%s\n", "vpc.terraform.tfvars.json")
+
+	var entry map[string]string
+	err := json.Unmarshal(out.Bytes(), &entry)
+	assert.Nil(t, err)
+	assert.Equal(t, "info", entry["level"])
+	assert.Equal(t, "Writing variables to file:\nvpc.terraform.tfvars.json", entry["msg"])
+	assert.NotEmpty(t, entry["time"])
+}
+
+func TestLoggerWithMask(t *testing.T) {
+	var out bytes.Buffer
+	log := New(&out, LevelInfo, FormatText, false)
+	log.SetMask(func(s string) string { return strings.Replace(s, "hunter22", "***", -1) })
+
+	componentLog := log.WithMask(func(s string) string { return strings.Replace(s, "ghp_abc123", "***", -1) })
+	componentLog.Debug("debug message")
+	componentLog.Info("password=hunter22 token=ghp_abc123")
+	log.Info("token=ghp_abc123")
+
+	assert.Equal(t, "password=*** token=***\ntoken=ghp_abc123\n", out.String())
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("DEBUG")
+	assert.Nil(t, err)
+	assert.Equal(t, LevelDebug, level)
+
+	level, err = ParseLevel("warning")
+	assert.Nil(t, err)
+	assert.Equal(t, LevelWarn, level)
+
+	_, err = ParseLevel("verbose")
+	assert.NotNil(t, err)
+}
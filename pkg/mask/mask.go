@@ -0,0 +1,194 @@
+package mask
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Masked replaces the values of the sensitive keys in the output
+const Masked = "***"
+
+// SensitiveSettingsKey is the key in the `settings` section of a component with the list of its sensitive keys
+const SensitiveSettingsKey = "sensitive"
+
+// Masker masks the values of the keys matching the sensitive key patterns (e.g. `*_TOKEN`, `*PASSWORD*`).
+// The patterns are matched case-insensitively against the names of the variables, ENV vars and map keys.
+// The masked values are remembered, and are also replaced in any text (e.g. the executed commands)
+type Masker struct {
+	patterns []string
+	values   map[string]bool
+}
+
+// New creates a masker with the sensitive key patterns
+func New(patterns ...string) (*Masker, error) {
+	m := &Masker{values: map[string]bool{}}
+	err := m.AddPatterns(patterns...)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// AddPatterns adds the sensitive key patterns (or the exact key names)
+func (m *Masker) AddPatterns(patterns ...string) error {
+	for _, pattern := range patterns {
+		if len(pattern) == 0 {
+			continue
+		}
+		pattern = strings.ToUpper(pattern)
+		if _, err := path.Match(pattern, ""); err != nil {
+			return errors.New(fmt.Sprintf("invalid sensitive key pattern '%s': %s", pattern, err))
+		}
+		m.patterns = append(m.patterns, pattern)
+	}
+	return nil
+}
+
+// IsSensitive checks if the key matches any of the sensitive key patterns
+func (m *Masker) IsSensitive(key string) bool {
+	key = strings.ToUpper(key)
+	for _, pattern := range m.patterns {
+		if match, _ := path.Match(pattern, key); match {
+			return true
+		}
+	}
+	return false
+}
+
+// Map returns a copy of the map with the values of the sensitive keys (at any level) replaced by `***`
+func (m *Masker) Map(data map[string]interface{}) map[string]interface{} {
+	if data == nil {
+		return nil
+	}
+	res := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		res[k] = m.value(k, v)
+	}
+	return res
+}
+
+// EnvList returns a copy of the ENV vars in the `KEY=value` format with the values of the sensitive ENV vars replaced by `***`
+func (m *Masker) EnvList(env []string) []string {
+	res := make([]string, 0, len(env))
+	for _, v := range env {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) == 2 && m.IsSensitive(parts[0]) {
+			m.addValue(parts[1])
+			v = parts[0] + "=" + Masked
+		}
+		res = append(res, v)
+	}
+	return res
+}
+
+// AddValues remembers the values (e.g. the resolved secrets) to mask them in the text and in the maps regardless of their keys
+func (m *Masker) AddValues(values ...string) {
+	for _, v := range values {
+		m.addValue(v)
+	}
+}
+
+// AddValuesFrom remembers the values masked by the other masker (e.g. the secrets of a referenced component)
+func (m *Masker) AddValuesFrom(other *Masker) {
+	for v := range other.values {
+		m.addValue(v)
+	}
+}
+
+// Text replaces the masked values in the text by `***`
+func (m *Masker) Text(text string) string {
+	if len(m.values) == 0 {
+		return text
+	}
+
+	// Replace the longer values first, in case a value contains another one
+	values := make([]string, 0, len(m.values))
+	for v := range m.values {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i]) != len(values[j]) {
+			return len(values[i]) > len(values[j])
+		}
+		return values[i] < values[j]
+	})
+
+	for _, v := range values {
+		text = strings.Replace(text, v, Masked, -1)
+	}
+	return text
+}
+
+func (m *Masker) value(key string, v interface{}) interface{} {
+	if m.IsSensitive(key) {
+		m.addValues(v)
+		return Masked
+	}
+
+	// The values added with `AddValues` (e.g. the resolved secrets) are masked regardless of their keys
+	if s, ok := v.(string); ok && m.values[s] {
+		return Masked
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return m.Map(v)
+	case map[interface{}]interface{}:
+		res := make(map[interface{}]interface{}, len(v))
+		for k, val := range v {
+			res[k] = m.value(fmt.Sprintf("%v", k), val)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, val := range v {
+			res[i] = m.value("", val)
+		}
+		return res
+	default:
+		return v
+	}
+}
+
+// addValues remembers the string values (including the values in the nested maps and lists) to mask them in the text
+func (m *Masker) addValues(v interface{}) {
+	switch v := v.(type) {
+	case string:
+		m.addValue(v)
+	case map[string]interface{}:
+		for _, val := range v {
+			m.addValues(val)
+		}
+	case map[interface{}]interface{}:
+		for _, val := range v {
+			m.addValues(val)
+		}
+	case []interface{}:
+		for _, val := range v {
+			m.addValues(val)
+		}
+	}
+}
+
+func (m *Masker) addValue(v string) {
+	if len(v) > 0 && v != Masked {
+		m.values[v] = true
+	}
+}
+
+// SensitiveKeys returns the keys from the `sensitive` list in the `settings` section of a component
+func SensitiveKeys(settings map[string]interface{}) []string {
+	var res []string
+	if keys, ok := settings[SensitiveSettingsKey].([]interface{}); ok {
+		for _, key := range keys {
+			if k, ok := key.(string); ok {
+				res = append(res, k)
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,74 @@
+package mask
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskerMap(t *testing.T) {
+	masker, err := New("*_TOKEN", "*PASSWORD*", "*SECRET*")
+	assert.Nil(t, err)
+
+	err = masker.AddPatterns(SensitiveKeys(map[string]interface{}{
+		"sensitive": []interface{}{"datadog_api_key"},
+	})...)
+	assert.Nil(t, err)
+
+	vars := map[string]interface{}{
+		"name":            "vpc",
+		"db_password":     "hunter22",
+		"datadog_api_key": "dd-key-1234",
+		"auth": map[interface{}]interface{}{
+			"client_secret": "s3cr3t",
+			"client_id":     "atmos",
+		},
+	}
+
+	res := masker.Map(vars)
+	assert.Equal(t, "vpc", res["name"])
+	assert.Equal(t, Masked, res["db_password"])
+	assert.Equal(t, Masked, res["datadog_api_key"])
+	assert.Equal(t, Masked, res["auth"].(map[interface{}]interface{})["client_secret"])
+	assert.Equal(t, "atmos", res["auth"].(map[interface{}]interface{})["client_id"])
+
+	// The original map is not changed
+	assert.Equal(t, "hunter22", vars["db_password"])
+
+	env := masker.EnvList([]string{"GITHUB_TOKEN=ghp_abc123", "AWS_PROFILE=dev"})
+	assert.Equal(t, []string{"GITHUB_TOKEN=***", "AWS_PROFILE=dev"}, env)
+
+	assert.Equal(t, "terraform apply -var password=*** -var token=*** -var key=***",
+		masker.Text("terraform apply -var password=hunter22 -var token=ghp_abc123 -var key=dd-key-1234"))
+}
+
+func TestMaskerAddValues(t *testing.T) {
+	masker, err := New()
+	assert.Nil(t, err)
+
+	masker.AddValues("ghp_abc123")
+
+	res := masker.Map(map[string]interface{}{"github": "ghp_abc123", "name": "vpc"})
+	assert.Equal(t, Masked, res["github"])
+	assert.Equal(t, "vpc", res["name"])
+	assert.Equal(t, "token=***", masker.Text("token=ghp_abc123"))
+}
+
+func TestMaskerInvalidPattern(t *testing.T) {
+	_, err := New("[TOKEN")
+	assert.NotNil(t, err)
+}
+
+func TestMaskerAddValuesFrom(t *testing.T) {
+	masker, err := New()
+	assert.Nil(t, err)
+
+	other, err := New("*_TOKEN")
+	assert.Nil(t, err)
+	other.Map(map[string]interface{}{"GITHUB_TOKEN": "ghp_abc123"})
+	other.AddValues("hunter22")
+
+	masker.AddValuesFrom(other)
+	assert.Equal(t, "token=*** password=***", masker.Text("token=ghp_abc123 password=hunter22"))
+	assert.False(t, masker.IsSensitive("GITHUB_TOKEN"))
+}
@@ -0,0 +1,201 @@
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Tag is the YAML tag of the secret references in the stack config files, e.g. `GITHUB_TOKEN: !secret env:GITHUB_TOKEN`.
+// The stack processor keeps the tagged values as strings with the tag (`!secret env:GITHUB_TOKEN`),
+// and the references are resolved only when the component is executed
+const Tag = "!secret"
+
+// The providers of the secrets
+const (
+	// ProviderEnv reads the secret from an ENV var, e.g. `!secret env:GITHUB_TOKEN`
+	ProviderEnv = "env"
+	// ProviderFile reads the secret from a file, e.g. `!secret file:/run/secrets/db_pw`
+	ProviderFile = "file"
+	// ProviderExec reads the secret from the output of a command, e.g. `!secret exec:pass show db/prod`
+	ProviderExec = "exec"
+	// ProviderSops decrypts a SOPS-encrypted file with `sops` (using the local age keys), e.g. `!secret sops:secrets/prod.enc.yaml#db.password`.
+	// The path to the value in the decrypted file is specified after `#`. Without the path, the whole decrypted file is the secret
+	ProviderSops = "sops"
+)
+
+// IsReference checks if the value is a secret reference
+func IsReference(v interface{}) bool {
+	s, ok := v.(string)
+	return ok && strings.HasPrefix(s, Tag+" ")
+}
+
+// Resolve returns the value of the secret reference (e.g. `!secret env:GITHUB_TOKEN`)
+func Resolve(ref string) (string, error) {
+	if !IsReference(ref) {
+		return "", errors.New(fmt.Sprintf("invalid secret reference '%s'", ref))
+	}
+
+	parts := strings.SplitN(strings.TrimSpace(strings.TrimPrefix(ref, Tag+" ")), ":", 2)
+	if len(parts) != 2 || len(strings.TrimSpace(parts[1])) == 0 {
+		return "", errors.New(fmt.Sprintf("invalid secret reference '%s'. The format is '%s <provider>:<value>', "+
+			"where the provider is '%s', '%s', '%s' or '%s'", ref, Tag, ProviderEnv, ProviderFile, ProviderExec, ProviderSops))
+	}
+	provider := parts[0]
+	value := strings.TrimSpace(parts[1])
+
+	switch provider {
+	case ProviderEnv:
+		v, ok := os.LookupEnv(value)
+		if !ok {
+			return "", errors.New(fmt.Sprintf("could not resolve the secret '%s': the ENV var '%s' is not set", ref, value))
+		}
+		return v, nil
+
+	case ProviderFile:
+		content, err := ioutil.ReadFile(value)
+		if err != nil {
+			return "", errors.New(fmt.Sprintf("could not resolve the secret '%s': %s", ref, err))
+		}
+		return strings.TrimRight(string(content), "\r\n"), nil
+
+	case ProviderExec:
+		args := strings.Fields(value)
+		return execCommand(ref, args[0], args[1:]...)
+
+	case ProviderSops:
+		file := value
+		var extract string
+		if i := strings.Index(value, "#"); i >= 0 {
+			file = value[:i]
+			extract = sopsExtractPath(value[i+1:])
+		}
+		args := []string{"--decrypt"}
+		if len(extract) > 0 {
+			args = append(args, "--extract", extract)
+		}
+		args = append(args, file)
+		return execCommand(ref, "sops", args...)
+
+	default:
+		return "", errors.New(fmt.Sprintf("could not resolve the secret '%s': unknown provider '%s'. Valid providers are '%s', '%s', '%s' and '%s'",
+			ref, provider, ProviderEnv, ProviderFile, ProviderExec, ProviderSops))
+	}
+}
+
+// ResolveMap returns a copy of the map with the secret references (at any level) replaced by their values,
+// and the list of the resolved values (to mask them in the output)
+func ResolveMap(data map[string]interface{}) (map[string]interface{}, []string, error) {
+	if data == nil {
+		return nil, nil, nil
+	}
+
+	var values []string
+	res := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		resolved, err := resolveValue(v, &values)
+		if err != nil {
+			return nil, nil, err
+		}
+		res[k] = resolved
+	}
+	return res, values, nil
+}
+
+// ResolveEnvList returns a copy of the ENV vars in the `KEY=value` format with the secret references replaced by their values,
+// and the list of the resolved values
+func ResolveEnvList(env []string) ([]string, []string, error) {
+	var values []string
+	res := make([]string, 0, len(env))
+	for _, v := range env {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) == 2 && IsReference(parts[1]) {
+			secret, err := Resolve(parts[1])
+			if err != nil {
+				return nil, nil, err
+			}
+			values = append(values, secret)
+			v = parts[0] + "=" + secret
+		}
+		res = append(res, v)
+	}
+	return res, values, nil
+}
+
+func resolveValue(v interface{}, values *[]string) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		if !IsReference(v) {
+			return v, nil
+		}
+		secret, err := Resolve(v)
+		if err != nil {
+			return nil, err
+		}
+		*values = append(*values, secret)
+		return secret, nil
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			resolved, err := resolveValue(val, values)
+			if err != nil {
+				return nil, err
+			}
+			res[k] = resolved
+		}
+		return res, nil
+	case map[interface{}]interface{}:
+		res := make(map[interface{}]interface{}, len(v))
+		for k, val := range v {
+			resolved, err := resolveValue(val, values)
+			if err != nil {
+				return nil, err
+			}
+			res[k] = resolved
+		}
+		return res, nil
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, val := range v {
+			resolved, err := resolveValue(val, values)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = resolved
+		}
+		return res, nil
+	default:
+		return v, nil
+	}
+}
+
+// execCommand executes the command and returns its output without the trailing new line
+func execCommand(ref string, command string, args ...string) (string, error) {
+	cmd := exec.Command(command, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("could not resolve the secret '%s': '%s' failed: %s %s",
+			ref, command, err, strings.TrimSpace(stderr.String())))
+	}
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
+
+// sopsExtractPath converts a dot-separated path (e.g. `db.password`) to the `sops --extract` format (`["db"]["password"]`)
+func sopsExtractPath(p string) string {
+	var res string
+	for _, key := range strings.Split(p, ".") {
+		if len(key) > 0 {
+			res += fmt.Sprintf("[%q]", key)
+		}
+	}
+	return res
+}
@@ -0,0 +1,74 @@
+package secrets
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	err := os.Setenv("ATMOS_TEST_SECRET", "ghp_abc123")
+	assert.Nil(t, err)
+	defer os.Unsetenv("ATMOS_TEST_SECRET")
+
+	secret, err := Resolve("!secret env:ATMOS_TEST_SECRET")
+	assert.Nil(t, err)
+	assert.Equal(t, "ghp_abc123", secret)
+
+	_, err = Resolve("!secret env:ATMOS_TEST_SECRET_NOT_SET")
+	assert.NotNil(t, err)
+
+	dir, err := ioutil.TempDir("", "atmos-secrets")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	file := path.Join(dir, "db_pw")
+	err = ioutil.WriteFile(file, []byte("hunter22\n"), 0600)
+	assert.Nil(t, err)
+
+	secret, err = Resolve("!secret file:" + file)
+	assert.Nil(t, err)
+	assert.Equal(t, "hunter22", secret)
+
+	secret, err = Resolve("!secret exec:echo s3cr3t")
+	assert.Nil(t, err)
+	assert.Equal(t, "s3cr3t", secret)
+
+	_, err = Resolve("!secret vault:db/prod")
+	assert.NotNil(t, err)
+
+	_, err = Resolve("!secret env")
+	assert.NotNil(t, err)
+
+	assert.Equal(t, `["db"]["password"]`, sopsExtractPath("db.password"))
+}
+
+func TestResolveMap(t *testing.T) {
+	err := os.Setenv("ATMOS_TEST_SECRET", "ghp_abc123")
+	assert.Nil(t, err)
+	defer os.Unsetenv("ATMOS_TEST_SECRET")
+
+	vars := map[string]interface{}{
+		"name": "vpc",
+		"auth": map[interface{}]interface{}{
+			"token": "!secret env:ATMOS_TEST_SECRET",
+		},
+	}
+
+	res, values, err := ResolveMap(vars)
+	assert.Nil(t, err)
+	assert.Equal(t, "vpc", res["name"])
+	assert.Equal(t, "ghp_abc123", res["auth"].(map[interface{}]interface{})["token"])
+	assert.Equal(t, []string{"ghp_abc123"}, values)
+
+	// The original map is not changed
+	assert.Equal(t, "!secret env:ATMOS_TEST_SECRET", vars["auth"].(map[interface{}]interface{})["token"])
+
+	env, values, err := ResolveEnvList([]string{"GITHUB_TOKEN=!secret env:ATMOS_TEST_SECRET", "AWS_PROFILE=dev"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"GITHUB_TOKEN=ghp_abc123", "AWS_PROFILE=dev"}, env)
+	assert.Equal(t, []string{"ghp_abc123"}, values)
+}
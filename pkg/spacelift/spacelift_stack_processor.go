@@ -2,10 +2,14 @@ package spacelift
 
 import (
 	"fmt"
+	"github.com/cloudposse/atmos/pkg/atmos"
 	c "github.com/cloudposse/atmos/pkg/config"
 	s "github.com/cloudposse/atmos/pkg/stack"
 	u "github.com/cloudposse/atmos/pkg/utils"
 	"github.com/pkg/errors"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -25,22 +29,39 @@ func CreateSpaceliftStacks(
 			return nil, err
 		}
 
-		return LegacyTransformStackConfigToSpaceliftStacks(stacks, stackConfigPathTemplate, processImports)
+		// The stack config files are provided explicitly, the CLI config (if found) is used only for the terraform workspace pattern
+		cliConfig, err := c.InitConfig()
+		if err != nil {
+			return nil, err
+		}
+		workspacePattern := cliConfig.Components.Terraform.WorkspacePattern
+		if pattern := os.Getenv("ATMOS_COMPONENTS_TERRAFORM_WORKSPACE_PATTERN"); len(pattern) > 0 {
+			workspacePattern = pattern
+		}
+
+		return LegacyTransformStackConfigToSpaceliftStacks(stacks, stackConfigPathTemplate, workspacePattern, processImports)
 	} else {
-		err := c.InitConfig()
+		cliConfig, err := c.InitConfig()
 		if err != nil {
 			return nil, err
 		}
-		err = c.ProcessConfigForSpacelift()
+		err = c.ProcessConfig(&cliConfig, c.ConfigAndStacksInfo{})
 		if err != nil {
 			return nil, err
 		}
-		_, stacks, err := s.ProcessYAMLConfigFiles(c.ProcessedConfig.StacksBaseAbsolutePath, c.ProcessedConfig.StackConfigFilesAbsolutePaths, processStackDeps, processComponentDeps)
+		processedConfig, err := c.ProcessStacksConfig(cliConfig)
+		if err != nil {
+			return nil, err
+		}
+		fileSystem := s.NewFileSystem(nil)
+		fileSystem.SetImportsCacheDir(cliConfig.Stacks.ImportsCacheDir)
+		fileSystem.SetExecTagEnabled(cliConfig.Stacks.YAMLTags.ExecEnabled)
+		_, stacks, err := fileSystem.ProcessYAMLConfigFiles(processedConfig.StacksBaseAbsolutePath, processedConfig.StackConfigFilesAbsolutePaths, processStackDeps, processComponentDeps)
 		if err != nil {
 			return nil, err
 		}
 
-		return TransformStackConfigToSpaceliftStacks(stacks, stackConfigPathTemplate, c.Config.Stacks.NamePattern, processImports)
+		return TransformStackConfigToSpaceliftStacks(stacks, stackConfigPathTemplate, cliConfig.Stacks.NamePattern, cliConfig.Components.Terraform.WorkspacePattern, processImports)
 	}
 }
 
@@ -48,8 +69,15 @@ func CreateSpaceliftStacks(
 func LegacyTransformStackConfigToSpaceliftStacks(
 	stacks map[string]interface{},
 	stackConfigPathTemplate string,
+	workspacePattern string,
 	processImports bool) (map[string]interface{}, error) {
 
+	// The stacks are referenced by the stack config file names (no stack name pattern)
+	err := atmos.ResolveRefs(stacks, "")
+	if err != nil {
+		return nil, err
+	}
+
 	res := map[string]interface{}{}
 
 	var allStackNames []string
@@ -57,10 +85,9 @@ func LegacyTransformStackConfigToSpaceliftStacks(
 		config := stackConfig.(map[interface{}]interface{})
 
 		if i, ok := config["components"]; ok {
-			componentsSection := i.(map[string]interface{})
+			componentsSection := i.(map[string]map[string]s.ComponentConfig)
 
-			if terraformComponents, ok := componentsSection["terraform"]; ok {
-				terraformComponentsMap := terraformComponents.(map[string]interface{})
+			if terraformComponentsMap, ok := componentsSection["terraform"]; ok {
 
 				for component, _ := range terraformComponentsMap {
 					allStackNames = append(allStackNames, fmt.Sprintf("%s-%s", stack, component))
@@ -80,26 +107,27 @@ func LegacyTransformStackConfigToSpaceliftStacks(
 		}
 
 		if i, ok := config["components"]; ok {
-			componentsSection := i.(map[string]interface{})
-
-			if terraformComponents, ok := componentsSection["terraform"]; ok {
-				terraformComponentsMap := terraformComponents.(map[string]interface{})
+			componentsSection := i.(map[string]map[string]s.ComponentConfig)
 
-				terraformComponentNamesInCurrentStack := u.StringKeysFromMap(terraformComponentsMap)
+			if terraformComponentsMap, ok := componentsSection["terraform"]; ok {
 
-				for component, v := range terraformComponentsMap {
-					componentMap := v.(map[string]interface{})
+				var terraformComponentNamesInCurrentStack []string
+				for v := range terraformComponentsMap {
+					terraformComponentNamesInCurrentStack = append(terraformComponentNamesInCurrentStack, v)
+				}
+				sort.Strings(terraformComponentNamesInCurrentStack)
 
-					componentSettings := map[interface{}]interface{}{}
-					if i, ok2 := componentMap["settings"]; ok2 {
-						componentSettings = i.(map[interface{}]interface{})
+				for component, componentConfig := range terraformComponentsMap {
+					componentSettings := componentConfig.Settings
+					if componentSettings == nil {
+						componentSettings = map[string]interface{}{}
 					}
 
-					spaceliftSettings := map[interface{}]interface{}{}
+					spaceliftSettings := map[string]interface{}{}
 					spaceliftWorkspaceEnabled := false
 
 					if i, ok2 := componentSettings["spacelift"]; ok2 {
-						spaceliftSettings = i.(map[interface{}]interface{})
+						spaceliftSettings = i.(map[string]interface{})
 
 						if i3, ok3 := spaceliftSettings["workspace_enabled"]; ok3 {
 							spaceliftWorkspaceEnabled = i3.(bool)
@@ -124,25 +152,10 @@ func LegacyTransformStackConfigToSpaceliftStacks(
 					spaceliftConfig := map[string]interface{}{}
 					spaceliftConfig["enabled"] = spaceliftWorkspaceEnabled
 
-					componentVars := map[interface{}]interface{}{}
-					if i, ok2 := componentMap["vars"]; ok2 {
-						componentVars = i.(map[interface{}]interface{})
-					}
-
-					componentEnv := map[interface{}]interface{}{}
-					if i, ok2 := componentMap["env"]; ok2 {
-						componentEnv = i.(map[interface{}]interface{})
-					}
-
-					componentDeps := []string{}
-					if i, ok2 := componentMap["deps"]; ok2 {
-						componentDeps = i.([]string)
-					}
-
+					componentVars := componentConfig.Vars
+					componentEnv := componentConfig.Env
+					componentDeps := componentConfig.Deps
 					componentStacks := []string{}
-					if i, ok2 := componentMap["stacks"]; ok2 {
-						componentStacks = i.([]string)
-					}
 
 					spaceliftConfig["component"] = component
 					spaceliftConfig["stack"] = stackName
@@ -153,33 +166,25 @@ func LegacyTransformStackConfigToSpaceliftStacks(
 					spaceliftConfig["deps"] = componentDeps
 					spaceliftConfig["stacks"] = componentStacks
 
-					baseComponentName := ""
-					if baseComponent, baseComponentExist := componentMap["component"]; baseComponentExist {
-						baseComponentName = baseComponent.(string)
-					}
+					baseComponentName := componentConfig.BaseComponent
 					spaceliftConfig["base_component"] = baseComponentName
 
 					// backend
-					backendTypeName := ""
-					if backendType, backendTypeExist := componentMap["backend_type"]; backendTypeExist {
-						backendTypeName = backendType.(string)
-					}
+					backendTypeName := componentConfig.BackendType
 					spaceliftConfig["backend_type"] = backendTypeName
 
-					componentBackend := map[interface{}]interface{}{}
-					if i, ok2 := componentMap["backend"]; ok2 {
-						componentBackend = i.(map[interface{}]interface{})
+					componentBackend := componentConfig.Backend
+					if componentBackend == nil {
+						componentBackend = map[string]interface{}{}
 					}
 					spaceliftConfig["backend"] = componentBackend
 
 					// workspace
-					var workspace string
-					if backendTypeName == "s3" && baseComponentName == "" {
-						workspace = stackName
-					} else {
-						workspace = fmt.Sprintf("%s-%s", stackName, component)
+					workspace, err := c.GetTerraformWorkspace(workspacePattern, stackName, component, componentConfig)
+					if err != nil {
+						return nil, err
 					}
-					spaceliftConfig["workspace"] = strings.Replace(workspace, "/", "-", -1)
+					spaceliftConfig["workspace"] = workspace
 
 					// labels
 					var labels []string
@@ -190,7 +195,7 @@ func LegacyTransformStackConfigToSpaceliftStacks(
 						labels = append(labels, fmt.Sprintf("stack:"+stackConfigPathTemplate, v))
 					}
 					for _, v := range componentDeps {
-						labels = append(labels, fmt.Sprintf("deps:"+stackConfigPathTemplate, v))
+						labels = append(labels, buildSpaceliftDepsLabel(stackConfigPathTemplate, v, stackName, config))
 					}
 					for _, v := range spaceliftExplicitLabels {
 						labels = append(labels, v.(string))
@@ -235,8 +240,14 @@ func TransformStackConfigToSpaceliftStacks(
 	stacks map[string]interface{},
 	stackConfigPathTemplate string,
 	stackNamePattern string,
+	workspacePattern string,
 	processImports bool) (map[string]interface{}, error) {
 
+	err := atmos.ResolveRefs(stacks, stackNamePattern)
+	if err != nil {
+		return nil, err
+	}
+
 	res := map[string]interface{}{}
 
 	var allStackNames []string
@@ -244,18 +255,12 @@ func TransformStackConfigToSpaceliftStacks(
 		config := stackConfig.(map[interface{}]interface{})
 
 		if i, ok := config["components"]; ok {
-			componentsSection := i.(map[string]interface{})
+			componentsSection := i.(map[string]map[string]s.ComponentConfig)
 
-			if terraformComponents, ok := componentsSection["terraform"]; ok {
-				terraformComponentsMap := terraformComponents.(map[string]interface{})
+			if terraformComponentsMap, ok := componentsSection["terraform"]; ok {
 
-				for component, v := range terraformComponentsMap {
-					componentMap := v.(map[string]interface{})
-					componentVars := map[interface{}]interface{}{}
-					if i, ok2 := componentMap["vars"]; ok2 {
-						componentVars = i.(map[interface{}]interface{})
-					}
-					context := c.GetContextFromVars(componentVars)
+				for component, componentConfig := range terraformComponentsMap {
+					context := c.GetContextFromVars(componentConfig.Vars)
 					contextPrefix, err := c.GetContextPrefix(stackName, context, stackNamePattern)
 					if err != nil {
 						return nil, err
@@ -279,24 +284,21 @@ func TransformStackConfigToSpaceliftStacks(
 		}
 
 		if i, ok := config["components"]; ok {
-			componentsSection := i.(map[string]interface{})
-
-			if terraformComponents, ok := componentsSection["terraform"]; ok {
-				terraformComponentsMap := terraformComponents.(map[string]interface{})
+			componentsSection := i.(map[string]map[string]s.ComponentConfig)
 
-				for component, v := range terraformComponentsMap {
-					componentMap := v.(map[string]interface{})
+			if terraformComponentsMap, ok := componentsSection["terraform"]; ok {
 
-					componentSettings := map[interface{}]interface{}{}
-					if i, ok2 := componentMap["settings"]; ok2 {
-						componentSettings = i.(map[interface{}]interface{})
+				for component, componentConfig := range terraformComponentsMap {
+					componentSettings := componentConfig.Settings
+					if componentSettings == nil {
+						componentSettings = map[string]interface{}{}
 					}
 
-					spaceliftSettings := map[interface{}]interface{}{}
+					spaceliftSettings := map[string]interface{}{}
 					spaceliftWorkspaceEnabled := false
 
 					if i, ok2 := componentSettings["spacelift"]; ok2 {
-						spaceliftSettings = i.(map[interface{}]interface{})
+						spaceliftSettings = i.(map[string]interface{})
 
 						if i3, ok3 := spaceliftSettings["workspace_enabled"]; ok3 {
 							spaceliftWorkspaceEnabled = i3.(bool)
@@ -321,30 +323,12 @@ func TransformStackConfigToSpaceliftStacks(
 					spaceliftConfig := map[string]interface{}{}
 					spaceliftConfig["enabled"] = spaceliftWorkspaceEnabled
 
-					componentVars := map[interface{}]interface{}{}
-					if i, ok2 := componentMap["vars"]; ok2 {
-						componentVars = i.(map[interface{}]interface{})
-					}
-
-					componentEnv := map[interface{}]interface{}{}
-					if i, ok2 := componentMap["env"]; ok2 {
-						componentEnv = i.(map[interface{}]interface{})
-					}
-
-					componentDeps := []string{}
-					if i, ok2 := componentMap["deps"]; ok2 {
-						componentDeps = i.([]string)
-					}
-
+					componentVars := componentConfig.Vars
+					componentEnv := componentConfig.Env
+					componentDeps := componentConfig.Deps
 					componentStacks := []string{}
-					if i, ok2 := componentMap["stacks"]; ok2 {
-						componentStacks = i.([]string)
-					}
 
-					componentInheritance := []string{}
-					if i, ok2 := componentMap["inheritance"]; ok2 {
-						componentInheritance = i.([]string)
-					}
+					componentInheritance := componentConfig.Inheritance
 
 					context := c.GetContextFromVars(componentVars)
 					contextPrefix, err := c.GetContextPrefix(stackName, context, stackNamePattern)
@@ -362,33 +346,25 @@ func TransformStackConfigToSpaceliftStacks(
 					spaceliftConfig["stacks"] = componentStacks
 					spaceliftConfig["inheritance"] = componentInheritance
 
-					baseComponentName := ""
-					if baseComponent, baseComponentExist := componentMap["component"]; baseComponentExist {
-						baseComponentName = baseComponent.(string)
-					}
+					baseComponentName := componentConfig.BaseComponent
 					spaceliftConfig["base_component"] = baseComponentName
 
 					// backend
-					backendTypeName := ""
-					if backendType, backendTypeExist := componentMap["backend_type"]; backendTypeExist {
-						backendTypeName = backendType.(string)
-					}
+					backendTypeName := componentConfig.BackendType
 					spaceliftConfig["backend_type"] = backendTypeName
 
-					componentBackend := map[interface{}]interface{}{}
-					if i, ok2 := componentMap["backend"]; ok2 {
-						componentBackend = i.(map[interface{}]interface{})
+					componentBackend := componentConfig.Backend
+					if componentBackend == nil {
+						componentBackend = map[string]interface{}{}
 					}
 					spaceliftConfig["backend"] = componentBackend
 
 					// workspace
-					var workspace string
-					if backendTypeName == "s3" && baseComponentName == "" {
-						workspace = contextPrefix
-					} else {
-						workspace = fmt.Sprintf("%s-%s", contextPrefix, component)
+					workspace, err := c.GetTerraformWorkspace(workspacePattern, contextPrefix, component, componentConfig)
+					if err != nil {
+						return nil, err
 					}
-					spaceliftConfig["workspace"] = strings.Replace(workspace, "/", "-", -1)
+					spaceliftConfig["workspace"] = workspace
 
 					// labels
 					labels := []string{}
@@ -399,7 +375,7 @@ func TransformStackConfigToSpaceliftStacks(
 						labels = append(labels, fmt.Sprintf("stack:"+stackConfigPathTemplate, v))
 					}
 					for _, v := range componentDeps {
-						labels = append(labels, fmt.Sprintf("deps:"+stackConfigPathTemplate, v))
+						labels = append(labels, buildSpaceliftDepsLabel(stackConfigPathTemplate, v, stackName, config))
 					}
 					for _, v := range spaceliftExplicitLabels {
 						labels = append(labels, v.(string))
@@ -440,6 +416,20 @@ func TransformStackConfigToSpaceliftStacks(
 	return res, nil
 }
 
+// buildSpaceliftDepsLabel returns the `deps` label for the dependency of the component.
+// The stack config files are referenced by the names without the extension (as in `stackConfigPathTemplate`),
+// while the files included with `!include` keep their own extension
+func buildSpaceliftDepsLabel(stackConfigPathTemplate string, dep string, stackName string, config map[interface{}]interface{}) string {
+	stackImports, _ := config["imports"].([]string)
+	if dep == stackName || u.SliceContainsString(stackImports, dep) || s.IsExternalImport(dep) {
+		return fmt.Sprintf("deps:"+stackConfigPathTemplate, dep)
+	}
+
+	ext := filepath.Ext(dep)
+	label := fmt.Sprintf("deps:"+stackConfigPathTemplate, strings.TrimSuffix(dep, ext))
+	return strings.TrimSuffix(label, filepath.Ext(stackConfigPathTemplate)) + ext
+}
+
 func buildSpaceliftDependsOnStackName(
 	dependsOn string,
 	allStackNames []string,
@@ -2,7 +2,11 @@ package spacelift
 
 import (
 	"gopkg.in/yaml.v2"
+	"os"
 	"testing"
+	"testing/fstest"
+
+	s "github.com/cloudposse/atmos/pkg/stack"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -19,14 +23,14 @@ func TestSpaceliftStackProcessor(t *testing.T) {
 
 	tenant1Ue2DevInfraVpcStack := spaceliftStacks["tenant1-ue2-dev-infra-vpc"].(map[string]interface{})
 	tenant1Ue2DevInfraVpcStackInfrastructureStackName := tenant1Ue2DevInfraVpcStack["stack"].(string)
-	tenant1Ue2DevInfraVpcStackBackend := tenant1Ue2DevInfraVpcStack["backend"].(map[interface{}]interface{})
+	tenant1Ue2DevInfraVpcStackBackend := tenant1Ue2DevInfraVpcStack["backend"].(map[string]interface{})
 	tenant1Ue2DevInfraVpcStackBackendWorkspaceKeyPrefix := tenant1Ue2DevInfraVpcStackBackend["workspace_key_prefix"].(string)
 	assert.Equal(t, "tenant1-ue2-dev", tenant1Ue2DevInfraVpcStackInfrastructureStackName)
 	assert.Equal(t, "infra-vpc", tenant1Ue2DevInfraVpcStackBackendWorkspaceKeyPrefix)
 
 	tenant1Ue2DevTestTestComponentOverrideComponent := spaceliftStacks["tenant1-ue2-dev-test-test-component-override"].(map[string]interface{})
 	tenant1Ue2DevTestTestComponentOverrideComponentInfrastructureStackName := tenant1Ue2DevTestTestComponentOverrideComponent["stack"].(string)
-	tenant1Ue2DevTestTestComponentOverrideComponentBackend := tenant1Ue2DevTestTestComponentOverrideComponent["backend"].(map[interface{}]interface{})
+	tenant1Ue2DevTestTestComponentOverrideComponentBackend := tenant1Ue2DevTestTestComponentOverrideComponent["backend"].(map[string]interface{})
 	tenant1Ue2DevTestTestComponentOverrideComponentBaseComponent := tenant1Ue2DevTestTestComponentOverrideComponent["base_component"].(string)
 	tenant1Ue2DevTestTestComponentOverrideComponentBackendWorkspaceKeyPrefix := tenant1Ue2DevTestTestComponentOverrideComponentBackend["workspace_key_prefix"].(string)
 	tenant1Ue2DevTestTestComponentOverrideComponentDeps := tenant1Ue2DevTestTestComponentOverrideComponent["deps"].([]string)
@@ -86,12 +90,12 @@ func TestLegacySpaceliftStackProcessor(t *testing.T) {
 	assert.Equal(t, 30, len(spaceliftStacks))
 
 	tenant1Ue2DevInfraVpcStack := spaceliftStacks["tenant1-ue2-dev-infra-vpc"].(map[string]interface{})
-	tenant1Ue2DevInfraVpcStackBackend := tenant1Ue2DevInfraVpcStack["backend"].(map[interface{}]interface{})
+	tenant1Ue2DevInfraVpcStackBackend := tenant1Ue2DevInfraVpcStack["backend"].(map[string]interface{})
 	tenant1Ue2DevInfraVpcStackBackendWorkspaceKeyPrefix := tenant1Ue2DevInfraVpcStackBackend["workspace_key_prefix"].(string)
 	assert.Equal(t, "infra-vpc", tenant1Ue2DevInfraVpcStackBackendWorkspaceKeyPrefix)
 
 	tenant1Ue2DevTestTestComponentOverrideComponent := spaceliftStacks["tenant1-ue2-dev-test-test-component-override"].(map[string]interface{})
-	tenant1Ue2DevTestTestComponentOverrideComponentBackend := tenant1Ue2DevTestTestComponentOverrideComponent["backend"].(map[interface{}]interface{})
+	tenant1Ue2DevTestTestComponentOverrideComponentBackend := tenant1Ue2DevTestTestComponentOverrideComponent["backend"].(map[string]interface{})
 	tenant1Ue2DevTestTestComponentOverrideComponentBaseComponent := tenant1Ue2DevTestTestComponentOverrideComponent["base_component"].(string)
 	tenant1Ue2DevTestTestComponentOverrideComponentBackendWorkspaceKeyPrefix := tenant1Ue2DevTestTestComponentOverrideComponentBackend["workspace_key_prefix"].(string)
 	tenant1Ue2DevTestTestComponentOverrideComponentDeps := tenant1Ue2DevTestTestComponentOverrideComponent["deps"].([]string)
@@ -118,8 +122,66 @@ func TestLegacySpaceliftStackProcessor(t *testing.T) {
 	assert.Equal(t, "deps:stacks/globals/ue2-globals.yaml", tenant1Ue2DevTestTestComponentOverrideComponentLabels[29])
 	assert.Equal(t, "deps:stacks/tenant1/ue2/dev.yaml", tenant1Ue2DevTestTestComponentOverrideComponentLabels[30])
 	assert.Equal(t, "folder:component/test/test-component-override", tenant1Ue2DevTestTestComponentOverrideComponentLabels[31])
+	assert.Equal(t, "tenant1-ue2-dev-test-test-component-override", tenant1Ue2DevTestTestComponentOverrideComponent["workspace"])
+
+	// The terraform workspace pattern from the CLI config or the ENV var is used
+	assert.Nil(t, os.Setenv("ATMOS_COMPONENTS_TERRAFORM_WORKSPACE_PATTERN", "{tenant}-{stage}-{component}"))
+	defer os.Unsetenv("ATMOS_COMPONENTS_TERRAFORM_WORKSPACE_PATTERN")
+	spaceliftStacks, err = CreateSpaceliftStacks(basePath, filePaths, processStackDeps, processComponentDeps, processImports, stackConfigPathTemplate)
+	assert.Nil(t, err)
+	assert.Equal(t, "tenant1-dev-infra-vpc", spaceliftStacks["tenant1-ue2-dev-infra-vpc"].(map[string]interface{})["workspace"])
 
 	yamlSpaceliftStacks, err := yaml.Marshal(spaceliftStacks)
 	assert.Nil(t, err)
 	t.Log(string(yamlSpaceliftStacks))
 }
+
+func TestSpaceliftStackProcessorRefs(t *testing.T) {
+	fileSystem := s.NewFileSystem(fstest.MapFS{
+		"stacks/dev.yaml": &fstest.MapFile{Data: []byte(`
+vars:
+  stage: dev
+components:
+  terraform:
+    vpc:
+      vars:
+        cidr_block: 10.1.0.0/16
+    eks:
+      settings:
+        spacelift:
+          workspace_enabled: true
+          labels:
+            - !ref vpc.vars.cidr_block
+      vars:
+        vpc_cidr: !ref vpc.vars.cidr_block
+        prod_vpc_cidr: !ref prod:vpc.vars.cidr_block
+`)},
+		"stacks/prod.yaml": &fstest.MapFile{Data: []byte(`
+vars:
+  stage: prod
+components:
+  terraform:
+    vpc:
+      vars:
+        cidr_block: 10.0.0.0/16
+`)},
+	})
+
+	_, stacks, err := fileSystem.ProcessYAMLConfigFiles("stacks", []string{"stacks/dev.yaml", "stacks/prod.yaml"}, false, true)
+	assert.Nil(t, err)
+
+	spaceliftStacks, err := LegacyTransformStackConfigToSpaceliftStacks(stacks, "stacks/%s.yaml", "", false)
+	assert.Nil(t, err)
+
+	eksStack := spaceliftStacks["dev-eks"].(map[string]interface{})
+	assert.Equal(t, "10.1.0.0/16", eksStack["vars"].(map[string]interface{})["vpc_cidr"])
+	assert.Equal(t, "10.0.0.0/16", eksStack["vars"].(map[string]interface{})["prod_vpc_cidr"])
+	assert.Contains(t, eksStack["labels"], "10.1.0.0/16")
+
+	// The references which can't be resolved fail the Spacelift stacks
+	_, stacks, err = fileSystem.ProcessYAMLConfigFiles("stacks", []string{"stacks/dev.yaml"}, false, true)
+	assert.Nil(t, err)
+	_, err = LegacyTransformStackConfigToSpaceliftStacks(stacks, "stacks/%s.yaml", "", false)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "the stack 'prod' does not exist")
+}
@@ -0,0 +1,51 @@
+package stack
+
+// ComponentConfig is the final (deep-merged) configuration of a terraform or helmfile component in a stack.
+// The YAML and JSON field names are the same as the sections in the stack config files
+type ComponentConfig struct {
+	Vars                   map[string]interface{} `yaml:"vars" json:"vars" mapstructure:"vars"`
+	Settings               map[string]interface{} `yaml:"settings" json:"settings" mapstructure:"settings"`
+	Env                    map[string]interface{} `yaml:"env" json:"env" mapstructure:"env"`
+	BackendType            string                 `yaml:"backend_type,omitempty" json:"backend_type,omitempty" mapstructure:"backend_type"`
+	Backend                map[string]interface{} `yaml:"backend,omitempty" json:"backend,omitempty" mapstructure:"backend"`
+	RemoteStateBackendType string                 `yaml:"remote_state_backend_type,omitempty" json:"remote_state_backend_type,omitempty" mapstructure:"remote_state_backend_type"`
+	RemoteStateBackend     map[string]interface{} `yaml:"remote_state_backend,omitempty" json:"remote_state_backend,omitempty" mapstructure:"remote_state_backend"`
+	Providers              map[string]interface{} `yaml:"providers,omitempty" json:"providers,omitempty" mapstructure:"providers"`
+	Command                string                 `yaml:"command" json:"command" mapstructure:"command"`
+	BaseComponent          string                 `yaml:"component,omitempty" json:"component,omitempty" mapstructure:"component"`
+	Inheritance            []string               `yaml:"inheritance" json:"inheritance" mapstructure:"inheritance"`
+	Deps                   []string               `yaml:"deps" json:"deps" mapstructure:"deps"`
+	Metadata               map[string]interface{} `yaml:"metadata,omitempty" json:"metadata,omitempty" mapstructure:"metadata"`
+}
+
+// ToMap returns the component config as a map with the same keys as the YAML and JSON representations
+func (cc ComponentConfig) ToMap() map[string]interface{} {
+	res := map[string]interface{}{
+		"vars":        cc.Vars,
+		"settings":    cc.Settings,
+		"env":         cc.Env,
+		"command":     cc.Command,
+		"inheritance": cc.Inheritance,
+		"deps":        cc.Deps,
+	}
+
+	if len(cc.BackendType) > 0 || cc.Backend != nil {
+		res["backend_type"] = cc.BackendType
+		res["backend"] = cc.Backend
+	}
+	if len(cc.RemoteStateBackendType) > 0 || cc.RemoteStateBackend != nil {
+		res["remote_state_backend_type"] = cc.RemoteStateBackendType
+		res["remote_state_backend"] = cc.RemoteStateBackend
+	}
+	if len(cc.Providers) > 0 {
+		res["providers"] = cc.Providers
+	}
+	if len(cc.BaseComponent) > 0 {
+		res["component"] = cc.BaseComponent
+	}
+	if len(cc.Metadata) > 0 {
+		res["metadata"] = cc.Metadata
+	}
+
+	return res
+}
@@ -0,0 +1,149 @@
+package stack
+
+import (
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/bmatcuk/doublestar/v4"
+	l "github.com/cloudposse/atmos/pkg/logger"
+)
+
+var (
+	// osFileSystem reads the stack config files from the OS filesystem using OS paths
+	osFileSystem = NewFileSystem(nil)
+)
+
+// FileSystem reads the stack config files and finds the imports in a filesystem.
+// It can use any `fs.FS` (e.g. `os.DirFS`, `embed.FS`, `fstest.MapFS`, or a `zip.Reader` for a stacks archive).
+// The file contents, the Glob matches and the external import sources are cached per FileSystem, so the same FileSystem
+// should be used for all stack config files from the same filesystem.
+// The external import sources are always fetched into the OS filesystem and read using absolute OS paths
+type FileSystem struct {
+	fsys                  fs.FS
+	getFileContentSyncMap sync.Map
+	getGlobMatchesSyncMap sync.Map
+
+	// resolvedImportsSyncMap holds the resolved imports of each processed stack
+	resolvedImportsSyncMap sync.Map
+	// includedFilesSyncMap holds the files included with `!include` in each stack config file (by the name of the file as an import)
+	includedFilesSyncMap sync.Map
+
+	importSourcesLock sync.Mutex
+	importSources     map[string]*ImportSource
+	importsCacheDir   string
+
+	// execTagEnabled allows the `!exec` YAML tag, and execTagSyncMap caches the outputs of the executed commands
+	execTagEnabled bool
+	execTagSyncMap sync.Map
+}
+
+// NewFileSystem creates a FileSystem to read the stack config files from `fsys`.
+// The paths in `fsys` are slash-separated and relative to the root of `fsys` (see `fs.ValidPath`).
+// If `fsys` is nil, the stack config files are read from the OS filesystem using OS paths
+func NewFileSystem(fsys fs.FS) *FileSystem {
+	return &FileSystem{fsys: fsys}
+}
+
+// OSFileSystem returns the FileSystem shared by all the functions that read the stack config files from the OS filesystem
+func OSFileSystem() *FileSystem {
+	return osFileSystem
+}
+
+// IsOS returns true if the FileSystem reads the stack config files from the OS filesystem
+func (f *FileSystem) IsOS() bool {
+	return f.fsys == nil
+}
+
+// isOSPath checks if the path is in the OS filesystem
+func (f *FileSystem) isOSPath(p string) bool {
+	return f.IsOS() || filepath.IsAbs(p)
+}
+
+// getFileContent tries to read and return the file content from the sync map if it exists in the map,
+// otherwise it reads the file, stores its content in the map and returns the content
+func (f *FileSystem) getFileContent(filePath string) (string, error) {
+	existingContent, found := f.getFileContentSyncMap.Load(filePath)
+	if found == true && existingContent != nil {
+		return fmt.Sprintf("%s", existingContent), nil
+	}
+
+	var content []byte
+	var err error
+
+	if f.isOSPath(filePath) {
+		content, err = ioutil.ReadFile(filePath)
+	} else {
+		content, err = fs.ReadFile(f.fsys, filePath)
+	}
+	if err != nil {
+		return "", err
+	}
+	f.getFileContentSyncMap.Store(filePath, content)
+
+	return string(content), nil
+}
+
+// ReadFile returns the content of the file
+func (f *FileSystem) ReadFile(filePath string) (string, error) {
+	return f.getFileContent(filePath)
+}
+
+// GetGlobMatches tries to read and return the Glob matches content from the sync map if it exists in the map,
+// otherwise it finds and returns all files matching the pattern, stores the files in the map and returns the files
+func (f *FileSystem) GetGlobMatches(pattern string) ([]string, error) {
+	existingMatches, found := f.getGlobMatchesSyncMap.Load(pattern)
+	if found == true && existingMatches != nil {
+		return strings.Split(fmt.Sprintf("%s", existingMatches), ","), nil
+	}
+
+	base, cleanPattern := doublestar.SplitPattern(pattern)
+
+	var dir fs.FS
+	if f.isOSPath(base) {
+		dir = os.DirFS(base)
+	} else {
+		sub, err := fs.Sub(f.fsys, base)
+		if err != nil {
+			return nil, err
+		}
+		dir = sub
+	}
+
+	matches, err := doublestar.Glob(dir, cleanPattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if matches == nil {
+		l.Warn("Import of %s (-> %s + %s) failed to find a match.", pattern, base, cleanPattern)
+		return nil, nil
+	}
+
+	var fullMatches []string
+	for _, match := range matches {
+		fullMatches = append(fullMatches, path.Join(base, match))
+	}
+
+	f.getGlobMatchesSyncMap.Store(pattern, strings.Join(fullMatches, ","))
+
+	return fullMatches, nil
+}
+
+// PathMatch checks if the path matches the Glob pattern
+func (f *FileSystem) PathMatch(pattern string, name string) (bool, error) {
+	if f.IsOS() {
+		return doublestar.PathMatch(pattern, name)
+	}
+	return doublestar.Match(pattern, name)
+}
+
+// GetGlobMatches finds all files in the OS filesystem matching the pattern
+func GetGlobMatches(pattern string) ([]string, error) {
+	return osFileSystem.GetGlobMatches(pattern)
+}
@@ -0,0 +1,420 @@
+package stack
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	g "github.com/cloudposse/atmos/pkg/globals"
+	l "github.com/cloudposse/atmos/pkg/logger"
+	"github.com/cloudposse/atmos/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+const (
+	// GitImportPrefix is the prefix of the imports from git repositories, e.g. `git::file:///srv/catalog.git//catalog/vpc?ref=v1.4.0`
+	GitImportPrefix = "git::"
+
+	// ArchiveImportPrefix is the prefix of the imports from local archives, e.g. `archive:../catalog-1.4.0.tgz//vpc`
+	ArchiveImportPrefix = "archive:"
+
+	// ImportSourceTypeGit is the type of the import sources in git repositories
+	ImportSourceTypeGit = "git"
+
+	// ImportSourceTypeArchive is the type of the import sources in local archives
+	ImportSourceTypeArchive = "archive"
+)
+
+var (
+	gitCommitRegexp = regexp.MustCompile("^[0-9a-f]{40}$")
+)
+
+// ImportSource is an external source of stack config files (a git repository or a local archive)
+// referenced in the `import` sections and fetched into the local cache
+type ImportSource struct {
+	Type string `yaml:"type" json:"type"`
+	// URL is the git repository URL or the archive path as specified in the import
+	URL string `yaml:"url" json:"url"`
+	// Ref is the git ref (tag, branch or commit) as specified in the import
+	Ref string `yaml:"ref,omitempty" json:"ref,omitempty"`
+	// Commit is the git commit SHA the ref was resolved to
+	Commit string `yaml:"commit,omitempty" json:"commit,omitempty"`
+	// SHA256 is the SHA256 checksum of the archive
+	SHA256 string `yaml:"sha256,omitempty" json:"sha256,omitempty"`
+	// Dir is the local cache directory with the files of the source
+	Dir string `yaml:"dir" json:"dir"`
+}
+
+// externalImport is a parsed import from an external source
+type externalImport struct {
+	sourceType string
+	url        string
+	ref        string
+	subPath    string
+}
+
+// IsExternalImport checks if the import references a git repository or a local archive
+func IsExternalImport(imp string) bool {
+	return strings.HasPrefix(imp, GitImportPrefix) || strings.HasPrefix(imp, ArchiveImportPrefix)
+}
+
+// importKey returns the name of an imported file from the source.
+// It includes the resolved git commit SHA or the archive checksum, so the imports of a stack are reproducible
+func (s *ImportSource) importKey(relativePath string) string {
+	if s.Type == ImportSourceTypeGit {
+		return fmt.Sprintf("%s%s//%s?ref=%s", GitImportPrefix, s.URL, relativePath, s.Commit)
+	}
+	return fmt.Sprintf("%s%s//%s?sha256=%s", ArchiveImportPrefix, s.URL, relativePath, s.SHA256)
+}
+
+// parseExternalImport parses an import from a git repository (`git::<url>//<path>?ref=<ref>`)
+// or from a local archive (`archive:<path to archive>//<path>`)
+func parseExternalImport(imp string) (externalImport, error) {
+	var res externalImport
+	var source string
+
+	if strings.HasPrefix(imp, GitImportPrefix) {
+		res.sourceType = ImportSourceTypeGit
+		source = strings.TrimPrefix(imp, GitImportPrefix)
+
+		if i := strings.LastIndex(source, "?"); i >= 0 {
+			query, err := url.ParseQuery(source[i+1:])
+			if err != nil {
+				return res, errors.New(fmt.Sprintf("Invalid import '%s': %s", imp, err))
+			}
+			res.ref = query.Get("ref")
+			source = source[:i]
+		}
+	} else {
+		res.sourceType = ImportSourceTypeArchive
+		source = strings.TrimPrefix(imp, ArchiveImportPrefix)
+	}
+
+	// The path in the source is separated from the source with `//` (after the URL scheme if present)
+	start := 0
+	if i := strings.Index(source, "://"); i >= 0 {
+		start = i + 3
+	}
+	i := strings.Index(source[start:], "//")
+	if i < 0 {
+		return res, errors.New(fmt.Sprintf("Invalid import '%s'. The path to the stack config files in the source must be specified after '//'", imp))
+	}
+
+	res.url = source[:start+i]
+	res.subPath = strings.Trim(source[start+i+2:], "/")
+
+	if len(res.url) == 0 || len(res.subPath) == 0 {
+		return res, errors.New(fmt.Sprintf("Invalid import '%s'. Both the source and the path to the stack config files in the source must be specified", imp))
+	}
+
+	// The path to the stack config files must not point outside of the source
+	res.subPath = path.Clean(res.subPath)
+	if res.subPath == ".." || strings.HasPrefix(res.subPath, "../") {
+		return res, errors.New(fmt.Sprintf("Invalid import '%s'. The path to the stack config files must be inside the source", imp))
+	}
+
+	// The URL and the ref are passed to git, they must not be interpreted as git options
+	if res.sourceType == ImportSourceTypeGit && (strings.HasPrefix(res.url, "-") || strings.HasPrefix(res.ref, "-")) {
+		return res, errors.New(fmt.Sprintf("Invalid import '%s'. The URL and the ref must not start with '-'", imp))
+	}
+
+	return res, nil
+}
+
+// SetImportsCacheDir sets the local directory to fetch the external import sources into.
+// If not set, the `atmos/imports` directory in the user cache directory is used
+func (f *FileSystem) SetImportsCacheDir(dir string) {
+	f.importSourcesLock.Lock()
+	defer f.importSourcesLock.Unlock()
+	f.importsCacheDir = dir
+}
+
+// ImportSources returns all external import sources fetched by the FileSystem, sorted by type, URL and ref
+func (f *FileSystem) ImportSources() []ImportSource {
+	f.importSourcesLock.Lock()
+	defer f.importSourcesLock.Unlock()
+
+	var keys []string
+	for k := range f.importSources {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var res []ImportSource
+	for _, k := range keys {
+		res = append(res, *f.importSources[k])
+	}
+	return res
+}
+
+// processExternalImport fetches the source of the import, and processes all stack config files in the source matching the import.
+// The imports in the stack config files from the source are relative to the root of the source
+func (f *FileSystem) processExternalImport(
+	basePath string,
+	filePath string,
+	imp string,
+	importsConfig map[string]map[interface{}]interface{},
+	resolvedImports *[]ResolvedImport) ([]map[interface{}]interface{}, error) {
+
+	externalImp, err := parseExternalImport(imp)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid import in the config file %s.\n%s", filePath, err))
+	}
+
+	source, err := f.resolveImportSource(basePath, externalImp)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid import in the config file %s.\nFailed to fetch the import '%s': %s", filePath, imp, err))
+	}
+
+	// If the import file is specified without extension, use `.yaml` as default
+	subPathWithExt := externalImp.subPath
+	if filepath.Ext(subPathWithExt) == "" {
+		subPathWithExt = subPathWithExt + g.DefaultStackConfigFileExtension
+	}
+
+	importMatches, err := f.GetGlobMatches(path.Join(filepath.ToSlash(source.Dir), subPathWithExt))
+	if err != nil {
+		return nil, err
+	}
+	if importMatches == nil {
+		return nil, errors.New(fmt.Sprintf("Invalid import in the config file %s.\nNo matches found for the import '%s' in '%s'",
+			filePath,
+			imp,
+			source.Dir))
+	}
+
+	var configs []map[interface{}]interface{}
+
+	for _, importFile := range importMatches {
+		yamlConfig, _, err := f.processYAMLConfigFile(filepath.ToSlash(source.Dir), importFile, importsConfig, source, resolvedImports)
+		if err != nil {
+			return nil, err
+		}
+
+		configs = append(configs, yamlConfig)
+		importRelativePathWithExt := utils.TrimBasePathFromPath(filepath.ToSlash(source.Dir)+"/", importFile)
+		importRelativePathWithoutExt := strings.TrimSuffix(importRelativePathWithExt, filepath.Ext(importRelativePathWithExt))
+		importKey := source.importKey(importRelativePathWithoutExt)
+		importsConfig[importKey] = yamlConfig
+
+		err = f.appendResolvedImport(resolvedImports, importKey, importFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return configs, nil
+}
+
+// resolveImportSource fetches the source of the import into the local cache (once per FileSystem) and returns it
+func (f *FileSystem) resolveImportSource(basePath string, imp externalImport) (*ImportSource, error) {
+	f.importSourcesLock.Lock()
+	defer f.importSourcesLock.Unlock()
+
+	key := fmt.Sprintf("%s::%s?ref=%s", imp.sourceType, imp.url, imp.ref)
+	if imp.sourceType == ImportSourceTypeArchive && !filepath.IsAbs(imp.url) {
+		// Relative archive paths are relative to the stacks base path
+		key = fmt.Sprintf("%s::%s", imp.sourceType, path.Join(basePath, imp.url))
+	}
+
+	if source, ok := f.importSources[key]; ok {
+		return source, nil
+	}
+
+	cacheDir := f.importsCacheDir
+	if len(cacheDir) == 0 {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		cacheDir = filepath.Join(userCacheDir, "atmos", "imports")
+	}
+
+	var source *ImportSource
+	var err error
+
+	if imp.sourceType == ImportSourceTypeGit {
+		source, err = fetchGitSource(cacheDir, imp)
+	} else {
+		source, err = f.fetchArchiveSource(cacheDir, basePath, imp)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if f.importSources == nil {
+		f.importSources = map[string]*ImportSource{}
+	}
+	f.importSources[key] = source
+
+	return source, nil
+}
+
+// fetchGitSource clones (or fetches) the git repository into the local cache,
+// resolves the ref to a commit, and checks out the files of the commit into the cache
+func fetchGitSource(cacheDir string, imp externalImport) (*ImportSource, error) {
+	ref := imp.ref
+	if len(ref) == 0 {
+		ref = "HEAD"
+	}
+
+	sourceDir := filepath.Join(cacheDir, ImportSourceTypeGit, hashString(imp.url))
+	repoDir := filepath.Join(sourceDir, "repo.git")
+
+	if _, err := os.Stat(repoDir); os.IsNotExist(err) {
+		err = os.MkdirAll(sourceDir, 0755)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = runGit("clone", "--bare", "--quiet", "--", imp.url, repoDir); err != nil {
+			return nil, err
+		}
+	} else if !gitCommitRegexp.MatchString(ref) || !gitCommitExists(repoDir, ref) {
+		// Branches and tags can move, fetch them unless the ref is a commit which is already in the cache
+		if _, err = runGit("--git-dir", repoDir, "fetch", "--quiet", "--tags", "--force", "origin", "+refs/heads/*:refs/heads/*"); err != nil {
+			return nil, err
+		}
+	}
+
+	out, err := runGit("--git-dir", repoDir, "rev-parse", "--verify", "--quiet", "--end-of-options", ref+"^{commit}")
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("ref '%s' does not exist in the repository '%s'", ref, imp.url))
+	}
+	commit := strings.TrimSpace(string(out))
+
+	dir := filepath.Join(sourceDir, commit)
+
+	if _, err = os.Stat(dir); os.IsNotExist(err) {
+		archive, err := runGit("--git-dir", repoDir, "archive", "--format=tar", commit)
+		if err != nil {
+			return nil, err
+		}
+		err = extractArchive(commit+".tar", archive, dir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &ImportSource{
+		Type:   ImportSourceTypeGit,
+		URL:    imp.url,
+		Ref:    imp.ref,
+		Commit: commit,
+		Dir:    dir,
+	}, nil
+}
+
+// fetchArchiveSource extracts the archive into the local cache.
+// The archive path is relative to the stacks base path (unless it's an absolute path)
+func (f *FileSystem) fetchArchiveSource(cacheDir string, basePath string, imp externalImport) (*ImportSource, error) {
+	var content []byte
+	var err error
+
+	if filepath.IsAbs(imp.url) {
+		content, err = ioutil.ReadFile(imp.url)
+	} else if f.isOSPath(basePath) {
+		content, err = ioutil.ReadFile(filepath.Join(basePath, imp.url))
+	} else {
+		content, err = fs.ReadFile(f.fsys, path.Join(basePath, imp.url))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	checksum := sha256.Sum256(content)
+	sha := hex.EncodeToString(checksum[:])
+	dir := filepath.Join(cacheDir, ImportSourceTypeArchive, sha)
+
+	if _, err = os.Stat(dir); os.IsNotExist(err) {
+		err = extractArchive(imp.url, content, dir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &ImportSource{
+		Type:   ImportSourceTypeArchive,
+		URL:    imp.url,
+		SHA256: sha,
+		Dir:    dir,
+	}, nil
+}
+
+// extractArchive extracts the archive into a temporary directory and then moves it to `dir`,
+// so an interrupted extraction never leaves a partial source in the cache
+func extractArchive(archivePath string, content []byte, dir string) error {
+	archiveFS, err := utils.ArchiveFSFromBytes(archivePath, content)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(dir), 0755)
+	if err != nil {
+		return err
+	}
+
+	tmpDir, err := os.MkdirTemp(filepath.Dir(dir), ".tmp-")
+	if err != nil {
+		return err
+	}
+
+	err = utils.ExtractFS(archiveFS, tmpDir)
+	if err != nil {
+		_ = os.RemoveAll(tmpDir)
+		return err
+	}
+
+	err = os.Rename(tmpDir, dir)
+	if err != nil {
+		_ = os.RemoveAll(tmpDir)
+		// Another process could have extracted the same source
+		if _, statErr := os.Stat(dir); statErr == nil {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// runGit executes a git command and returns its output
+func runGit(args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	l.Debug("Executing command: %s\n", cmd.String())
+
+	err := cmd.Run()
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("'%s' failed: %s %s", cmd.String(), err, strings.TrimSpace(stderr.String())))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// gitCommitExists checks if the commit exists in the repository
+func gitCommitExists(repoDir string, commit string) bool {
+	_, err := runGit("--git-dir", repoDir, "cat-file", "-e", commit+"^{commit}")
+	return err == nil
+}
+
+// hashString returns the SHA256 hash of the string
+func hashString(s string) string {
+	hash := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,151 @@
+package stack
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+)
+
+const (
+	// LockFileName is the default name of the lock file with the resolved imports of all stacks
+	LockFileName = "atmos.lock"
+
+	// LockFileVersion is the version of the lock file format
+	LockFileVersion = 1
+)
+
+// ResolvedImport is an imported stack config file and the SHA256 checksum of its content
+type ResolvedImport struct {
+	Import string `yaml:"import" json:"import"`
+	SHA256 string `yaml:"sha256" json:"sha256"`
+}
+
+// StackLock holds the resolved imports of a stack in the order they are deep-merged
+type StackLock struct {
+	Imports []ResolvedImport `yaml:"imports" json:"imports"`
+}
+
+// StacksLock is the content of the lock file
+type StacksLock struct {
+	Version int                  `yaml:"version" json:"version"`
+	Stacks  map[string]StackLock `yaml:"stacks" json:"stacks"`
+}
+
+// appendResolvedImport appends the imported file and the checksum of its content to the resolved imports
+func (f *FileSystem) appendResolvedImport(resolvedImports *[]ResolvedImport, imp string, importFile string) error {
+	if resolvedImports == nil {
+		return nil
+	}
+
+	content, err := f.getFileContent(importFile)
+	if err != nil {
+		return err
+	}
+
+	checksum := sha256.Sum256([]byte(content))
+	*resolvedImports = append(*resolvedImports, ResolvedImport{Import: imp, SHA256: hex.EncodeToString(checksum[:])})
+	return nil
+}
+
+// Lock returns the resolved imports of all stacks processed by the FileSystem
+func (f *FileSystem) Lock() StacksLock {
+	res := StacksLock{
+		Version: LockFileVersion,
+		Stacks:  map[string]StackLock{},
+	}
+
+	f.resolvedImportsSyncMap.Range(func(key, value interface{}) bool {
+		imports := value.([]ResolvedImport)
+		if imports == nil {
+			imports = []ResolvedImport{}
+		}
+		res.Stacks[key.(string)] = StackLock{Imports: imports}
+		return true
+	})
+
+	return res
+}
+
+// Verify compares the current resolved imports with the lock and returns the differences (empty if the current imports match the lock)
+func (l StacksLock) Verify(current StacksLock) []string {
+	var res []string
+
+	if l.Version != current.Version {
+		res = append(res, fmt.Sprintf("lock file version is %d, expected %d", l.Version, current.Version))
+	}
+
+	var stacks []string
+	for stack := range l.Stacks {
+		stacks = append(stacks, stack)
+	}
+	for stack := range current.Stacks {
+		if _, ok := l.Stacks[stack]; !ok {
+			stacks = append(stacks, stack)
+		}
+	}
+	sort.Strings(stacks)
+
+	for _, stack := range stacks {
+		lockedStack, locked := l.Stacks[stack]
+		currentStack, exists := current.Stacks[stack]
+
+		if !locked {
+			res = append(res, fmt.Sprintf("stack '%s' is not in the lock file", stack))
+			continue
+		}
+		if !exists {
+			res = append(res, fmt.Sprintf("stack '%s' is in the lock file, but it does not exist", stack))
+			continue
+		}
+
+		res = append(res, verifyStackImports(stack, lockedStack.Imports, currentStack.Imports)...)
+	}
+
+	return res
+}
+
+// verifyStackImports compares the current resolved imports of the stack with the locked imports
+func verifyStackImports(stack string, locked []ResolvedImport, current []ResolvedImport) []string {
+	var res []string
+
+	lockedChecksums := map[string]string{}
+	for _, imp := range locked {
+		lockedChecksums[imp.Import] = imp.SHA256
+	}
+	currentChecksums := map[string]string{}
+	for _, imp := range current {
+		currentChecksums[imp.Import] = imp.SHA256
+	}
+
+	for _, imp := range current {
+		lockedChecksum, ok := lockedChecksums[imp.Import]
+		if !ok {
+			res = append(res, fmt.Sprintf("stack '%s': import '%s' is not in the lock file", stack, imp.Import))
+		} else if lockedChecksum != imp.SHA256 {
+			res = append(res, fmt.Sprintf("stack '%s': content of the import '%s' has changed", stack, imp.Import))
+			// Report each changed import only once
+			lockedChecksums[imp.Import] = imp.SHA256
+		}
+	}
+
+	for _, imp := range locked {
+		if _, ok := currentChecksums[imp.Import]; !ok {
+			res = append(res, fmt.Sprintf("stack '%s': import '%s' is in the lock file, but it's not imported anymore", stack, imp.Import))
+		}
+	}
+
+	// The order of the imports defines the order of the deep-merge
+	if len(res) == 0 && len(locked) == len(current) {
+		for i := range locked {
+			if locked[i].Import != current[i].Import {
+				res = append(res, fmt.Sprintf("stack '%s': order of the imports has changed", stack))
+				break
+			}
+		}
+	} else if len(res) == 0 {
+		res = append(res, fmt.Sprintf("stack '%s': number of the imports has changed from %d to %d", stack, len(locked), len(current)))
+	}
+
+	return res
+}
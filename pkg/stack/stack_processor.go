@@ -5,6 +5,7 @@ import (
 	c "github.com/cloudposse/atmos/pkg/convert"
 	g "github.com/cloudposse/atmos/pkg/globals"
 	m "github.com/cloudposse/atmos/pkg/merge"
+	tf "github.com/cloudposse/atmos/pkg/terraform"
 	"github.com/cloudposse/atmos/pkg/utils"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
@@ -28,6 +29,17 @@ func ProcessYAMLConfigFiles(
 	processStackDeps bool,
 	processComponentDeps bool) ([]string, map[string]interface{}, error) {
 
+	return osFileSystem.ProcessYAMLConfigFiles(basePath, filePaths, processStackDeps, processComponentDeps)
+}
+
+// ProcessYAMLConfigFiles takes a list of paths to YAML config files in the filesystem, processes and deep-merges all imports,
+// and returns a list of stack configs
+func (f *FileSystem) ProcessYAMLConfigFiles(
+	basePath string,
+	filePaths []string,
+	processStackDeps bool,
+	processComponentDeps bool) ([]string, map[string]interface{}, error) {
+
 	count := len(filePaths)
 	listResult := make([]string, count)
 	mapResult := map[string]interface{}{}
@@ -44,7 +56,8 @@ func ProcessYAMLConfigFiles(
 				stackBasePath = path.Dir(p)
 			}
 
-			config, importsConfig, err := ProcessYAMLConfigFile(stackBasePath, p, map[string]map[interface{}]interface{}{})
+			var resolvedImports []ResolvedImport
+			config, importsConfig, err := f.processYAMLConfigFile(stackBasePath, p, map[string]map[interface{}]interface{}{}, nil, &resolvedImports)
 			if err != nil {
 				errorResult = err
 				return
@@ -84,6 +97,10 @@ func ProcessYAMLConfigFiles(
 
 			finalConfig["imports"] = uniqueImports
 
+			if processComponentDeps {
+				f.addIncludedFilesToDeps(finalConfig)
+			}
+
 			yamlConfig, err := yaml.Marshal(finalConfig)
 			if err != nil {
 				errorResult = err
@@ -97,6 +114,8 @@ func ProcessYAMLConfigFiles(
 				".yml",
 			)
 
+			f.resolvedImportsSyncMap.Store(stackName, resolvedImports)
+
 			processYAMLConfigFilesLock.Lock()
 			defer processYAMLConfigFilesLock.Unlock()
 
@@ -121,18 +140,58 @@ func ProcessYAMLConfigFile(
 	filePath string,
 	importsConfig map[string]map[interface{}]interface{}) (map[interface{}]interface{}, map[string]map[interface{}]interface{}, error) {
 
+	return osFileSystem.ProcessYAMLConfigFile(basePath, filePath, importsConfig)
+}
+
+// ProcessYAMLConfigFile takes a path to a YAML config file in the filesystem,
+// recursively processes and deep-merges all imports,
+// and returns stack config as map[interface{}]interface{}
+func (f *FileSystem) ProcessYAMLConfigFile(
+	basePath string,
+	filePath string,
+	importsConfig map[string]map[interface{}]interface{}) (map[interface{}]interface{}, map[string]map[interface{}]interface{}, error) {
+
+	return f.processYAMLConfigFile(basePath, filePath, importsConfig, nil, nil)
+}
+
+// processYAMLConfigFile processes the YAML config file and all its imports.
+// If the config file is from an external import source, `source` is the source, and `basePath` is the root of the source.
+// If `resolvedImports` is not nil, all imported files are appended to it in the order they are deep-merged
+func (f *FileSystem) processYAMLConfigFile(
+	basePath string,
+	filePath string,
+	importsConfig map[string]map[interface{}]interface{},
+	source *ImportSource,
+	resolvedImports *[]ResolvedImport) (map[interface{}]interface{}, map[string]map[interface{}]interface{}, error) {
+
 	var configs []map[interface{}]interface{}
 
-	stackYamlConfig, err := getFileContent(filePath)
+	stackYamlConfig, err := f.getFileContent(filePath)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	stackMapConfig, err := c.YAMLToMapOfInterfaces(stackYamlConfig)
+	stackMapConfig, includedFiles, err := f.parseStackYAML(basePath, filePath, stackYamlConfig)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	// The files included with `!include` are recorded in the resolved imports (and the lock file) the same way as the imports,
+	// and are added to the dependencies of the components which depend on the config file
+	if len(includedFiles) > 0 {
+		var includeKeys []string
+		for _, includedFile := range utils.UniqueStrings(includedFiles) {
+			includeKey := importKey(basePath, includedFile, source, false)
+			includeKeys = append(includeKeys, includeKey)
+
+			err = f.appendResolvedImport(resolvedImports, includeKey, includedFile)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		f.includedFilesSyncMap.Store(importKey(basePath, filePath, source, true), includeKeys)
+	}
+
 	// Find and process all imports
 	if importsSection, ok := stackMapConfig["import"]; ok {
 		imports := importsSection.([]interface{})
@@ -140,6 +199,16 @@ func ProcessYAMLConfigFile(
 		for _, im := range imports {
 			imp := im.(string)
 
+			// Process the imports from git repositories and local archives
+			if IsExternalImport(imp) {
+				externalConfigs, err := f.processExternalImport(basePath, filePath, imp, importsConfig, resolvedImports)
+				if err != nil {
+					return nil, nil, err
+				}
+				configs = append(configs, externalConfigs...)
+				continue
+			}
+
 			// If the import file is specified without extension, use `.yaml` as default
 			impWithExt := imp
 			ext := filepath.Ext(imp)
@@ -153,12 +222,12 @@ func ProcessYAMLConfigFile(
 			if impWithExtPath == filePath {
 				errorMessage := fmt.Sprintf("Invalid import in the config file %s.\nThe file imports itself in '%s'",
 					filePath,
-					strings.Replace(impWithExt, basePath+"/", "", 1))
+					utils.TrimBasePathFromPath(basePath+"/", impWithExt))
 				return nil, nil, errors.New(errorMessage)
 			}
 
 			// Find all import matches in the glob
-			importMatches, err := GetGlobMatches(impWithExtPath)
+			importMatches, err := f.GetGlobMatches(impWithExtPath)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -169,7 +238,7 @@ func ProcessYAMLConfigFile(
 					imp,
 					impWithExtPath)
 
-				importMatches, err = GetGlobMatches(impWithExtPath)
+				importMatches, err = f.GetGlobMatches(impWithExtPath)
 				if err != nil {
 					return nil, nil, err
 				}
@@ -179,19 +248,27 @@ func ProcessYAMLConfigFile(
 			}
 
 			for _, importFile := range importMatches {
-				yamlConfig, _, err := ProcessYAMLConfigFile(basePath, importFile, importsConfig)
+				yamlConfig, _, err := f.processYAMLConfigFile(basePath, importFile, importsConfig, source, resolvedImports)
 				if err != nil {
 					return nil, nil, err
 				}
 
 				configs = append(configs, yamlConfig)
-				importRelativePathWithExt := strings.Replace(importFile, basePath+"/", "", 1)
+				importRelativePathWithExt := utils.TrimBasePathFromPath(basePath+"/", importFile)
 				ext2 := filepath.Ext(importRelativePathWithExt)
 				if ext2 == "" {
 					ext2 = g.DefaultStackConfigFileExtension
 				}
 				importRelativePathWithoutExt := strings.TrimSuffix(importRelativePathWithExt, ext2)
+				if source != nil {
+					importRelativePathWithoutExt = source.importKey(importRelativePathWithoutExt)
+				}
 				importsConfig[importRelativePathWithoutExt] = yamlConfig
+
+				err = f.appendResolvedImport(resolvedImports, importRelativePathWithoutExt, importFile)
+				if err != nil {
+					return nil, nil, err
+				}
 			}
 		}
 	}
@@ -207,6 +284,47 @@ func ProcessYAMLConfigFile(
 	return result, importsConfig, nil
 }
 
+// importKey returns the name of the imported or included file: the path relative to the base path
+// (without the extension if `trimExt` is set), in the form of an external import if the file is from an import source
+func importKey(basePath string, filePath string, source *ImportSource, trimExt bool) string {
+	res := utils.TrimBasePathFromPath(basePath+"/", filePath)
+	if trimExt {
+		res = strings.TrimSuffix(res, filepath.Ext(res))
+	}
+	if source != nil {
+		res = source.importKey(res)
+	}
+	return res
+}
+
+// addIncludedFilesToDeps adds the files included with `!include` in the stack config file or its imports
+// to the dependencies of the components
+func (f *FileSystem) addIncludedFilesToDeps(finalConfig map[interface{}]interface{}) {
+	componentsSection, ok := finalConfig["components"].(map[string]map[string]ComponentConfig)
+	if !ok {
+		return
+	}
+
+	for _, components := range componentsSection {
+		for component, componentConfig := range components {
+			deps := componentConfig.Deps
+			for _, dep := range componentConfig.Deps {
+				if included, found := f.includedFilesSyncMap.Load(dep); found {
+					deps = append(deps, included.([]string)...)
+				}
+			}
+			if len(deps) == len(componentConfig.Deps) {
+				continue
+			}
+
+			deps = utils.UniqueStrings(deps)
+			sort.Strings(deps)
+			componentConfig.Deps = deps
+			components[component] = componentConfig
+		}
+	}
+}
+
 // ProcessConfig takes a raw stack config, deep-merges all variables, settings, environments and backends,
 // and returns the final stack configuration for all Terraform and helmfile components
 func ProcessConfig(
@@ -233,6 +351,7 @@ func ProcessConfig(
 	globalTerraformSection := map[interface{}]interface{}{}
 	globalHelmfileSection := map[interface{}]interface{}{}
 	globalComponentsSection := map[interface{}]interface{}{}
+	globalProvidersSection := map[interface{}]interface{}{}
 
 	terraformVars := map[interface{}]interface{}{}
 	terraformSettings := map[interface{}]interface{}{}
@@ -242,9 +361,9 @@ func ProcessConfig(
 	helmfileSettings := map[interface{}]interface{}{}
 	helmfileEnv := map[interface{}]interface{}{}
 
-	terraformComponents := map[string]interface{}{}
-	helmfileComponents := map[string]interface{}{}
-	allComponents := map[string]interface{}{}
+	terraformComponents := map[string]ComponentConfig{}
+	helmfileComponents := map[string]ComponentConfig{}
+	allComponents := map[string]map[string]ComponentConfig{}
 
 	// Global sections
 	if i, ok := config["vars"]; ok {
@@ -271,6 +390,10 @@ func ProcessConfig(
 		globalComponentsSection = i.(map[interface{}]interface{})
 	}
 
+	if i, ok := config["providers"]; ok {
+		globalProvidersSection = i.(map[interface{}]interface{})
+	}
+
 	// Terraform section
 	if i, ok := globalTerraformSection["vars"]; ok {
 		terraformVars = i.(map[interface{}]interface{})
@@ -309,6 +432,17 @@ func ProcessConfig(
 		globalBackendSection = i.(map[interface{}]interface{})
 	}
 
+	// Global providers
+	terraformProviders := map[interface{}]interface{}{}
+	if i, ok := globalTerraformSection["providers"]; ok {
+		terraformProviders = i.(map[interface{}]interface{})
+	}
+
+	globalAndTerraformProviders, err := m.Merge([]map[interface{}]interface{}{globalProvidersSection, terraformProviders})
+	if err != nil {
+		return nil, err
+	}
+
 	// Global remote state backend
 	globalRemoteStateBackendType := ""
 	globalRemoteStateBackendSection := map[interface{}]interface{}{}
@@ -391,11 +525,27 @@ func ProcessConfig(
 					componentRemoteStateBackendSection = i.(map[interface{}]interface{})
 				}
 
+				// Component providers
+				componentProvidersSection := map[interface{}]interface{}{}
+				if i, ok2 := componentMap["providers"]; ok2 {
+					componentProvidersSection = i.(map[interface{}]interface{})
+				}
+
 				componentTerraformCommand := ""
 				if i, ok2 := componentMap["command"]; ok2 {
 					componentTerraformCommand = i.(string)
 				}
 
+				// Component metadata is not inherited from the base components
+				componentMetadata := map[interface{}]interface{}{}
+				if i, ok2 := componentMap["metadata"]; ok2 {
+					componentMetadata, ok2 = i.(map[interface{}]interface{})
+					if !ok2 {
+						return nil, errors.New(fmt.Sprintf("Invalid 'metadata' section in the terraform component '%s' in the config file %s.\n"+
+							"The 'metadata' section must be a map", component, stack))
+					}
+				}
+
 				// Process base component(s)
 				baseComponentVars := map[interface{}]interface{}{}
 				baseComponentSettings := map[interface{}]interface{}{}
@@ -406,6 +556,7 @@ func ProcessConfig(
 				baseComponentBackendSection := map[interface{}]interface{}{}
 				baseComponentRemoteStateBackendType := ""
 				baseComponentRemoteStateBackendSection := map[interface{}]interface{}{}
+				baseComponentProvidersSection := map[interface{}]interface{}{}
 				var baseComponentConfig BaseComponentConfig
 				var componentInheritanceChain []string
 
@@ -426,6 +577,7 @@ func ProcessConfig(
 					baseComponentBackendSection = baseComponentConfig.BaseComponentBackendSection
 					baseComponentRemoteStateBackendType = baseComponentConfig.BaseComponentRemoteStateBackendType
 					baseComponentRemoteStateBackendSection = baseComponentConfig.BaseComponentRemoteStateBackendSection
+					baseComponentProvidersSection = baseComponentConfig.BaseComponentProvidersSection
 					componentInheritanceChain = baseComponentConfig.ComponentInheritanceChain
 				}
 
@@ -465,19 +617,6 @@ func ProcessConfig(
 					finalComponentBackend = i.(map[interface{}]interface{})
 				}
 
-				// Check if `backend` section has `workspace_key_prefix` for `s3` backend type
-				// If it does not, use the component name instead
-				// It will also be propagated to `remote_state_backend` section of `s3` type
-				if finalComponentBackendType == "s3" {
-					if _, ok2 := finalComponentBackend["workspace_key_prefix"].(string); !ok2 {
-						workspaceKeyPrefixComponent := component
-						if baseComponentName != "" {
-							workspaceKeyPrefixComponent = baseComponentName
-						}
-						finalComponentBackend["workspace_key_prefix"] = strings.Replace(workspaceKeyPrefixComponent, "/", "-", -1)
-					}
-				}
-
 				// Final remote state backend
 				finalComponentRemoteStateBackendType := finalComponentBackendType
 				if len(globalRemoteStateBackendType) > 0 {
@@ -510,6 +649,14 @@ func ProcessConfig(
 					finalComponentRemoteStateBackend = i.(map[interface{}]interface{})
 				}
 
+				// Final providers
+				finalComponentProviders, err := m.Merge([]map[interface{}]interface{}{globalAndTerraformProviders,
+					baseComponentProvidersSection,
+					componentProvidersSection})
+				if err != nil {
+					return nil, err
+				}
+
 				// Final binary to execute
 				finalComponentTerraformCommand := "terraform"
 				if len(baseComponentTerraformCommand) > 0 {
@@ -519,19 +666,32 @@ func ProcessConfig(
 					finalComponentTerraformCommand = componentTerraformCommand
 				}
 
-				comp := map[string]interface{}{}
-				comp["vars"] = finalComponentVars
-				comp["settings"] = finalComponentSettings
-				comp["env"] = finalComponentEnv
-				comp["backend_type"] = finalComponentBackendType
-				comp["backend"] = finalComponentBackend
-				comp["remote_state_backend_type"] = finalComponentRemoteStateBackendType
-				comp["remote_state_backend"] = finalComponentRemoteStateBackend
-				comp["command"] = finalComponentTerraformCommand
-				comp["inheritance"] = componentInheritanceChain
-
+				// Set the fields of the `backend` and `remote_state_backend` configs derived from the component name
+				// (e.g. `workspace_key_prefix` for `s3`, `prefix` for `gcs`) if they are not set in the stacks
+				backendComponent := component
 				if baseComponentName != "" {
-					comp["component"] = baseComponentName
+					backendComponent = baseComponentName
+				}
+
+				finalBackend := c.MapsOfInterfacesToMapsOfStringsRecursive(finalComponentBackend)
+				tf.SetBackendDefaults(finalComponentBackendType, finalBackend, backendComponent)
+
+				finalRemoteStateBackend := c.MapsOfInterfacesToMapsOfStringsRecursive(finalComponentRemoteStateBackend)
+				tf.SetBackendDefaults(finalComponentRemoteStateBackendType, finalRemoteStateBackend, backendComponent)
+
+				comp := ComponentConfig{
+					Vars:                   c.MapsOfInterfacesToMapsOfStringsRecursive(finalComponentVars),
+					Settings:               c.MapsOfInterfacesToMapsOfStringsRecursive(finalComponentSettings),
+					Env:                    c.MapsOfInterfacesToMapsOfStringsRecursive(finalComponentEnv),
+					BackendType:            finalComponentBackendType,
+					Backend:                finalBackend,
+					RemoteStateBackendType: finalComponentRemoteStateBackendType,
+					RemoteStateBackend:     finalRemoteStateBackend,
+					Providers:              c.MapsOfInterfacesToMapsOfStringsRecursive(finalComponentProviders),
+					Command:                finalComponentTerraformCommand,
+					BaseComponent:          baseComponentName,
+					Inheritance:            componentInheritanceChain,
+					Metadata:               c.MapsOfInterfacesToMapsOfStringsRecursive(componentMetadata),
 				}
 
 				// TODO: this feature is not used anywhere, it has old code and it has issues with some YAML stack configs
@@ -551,9 +711,9 @@ func ProcessConfig(
 					if err != nil {
 						return nil, err
 					}
-					comp["deps"] = componentDeps
+					comp.Deps = componentDeps
 				} else {
-					comp["deps"] = []string{}
+					comp.Deps = []string{}
 				}
 
 				terraformComponents[component] = comp
@@ -590,6 +750,16 @@ func ProcessConfig(
 					componentHelmfileCommand = i.(string)
 				}
 
+				// Component metadata is not inherited from the base components
+				componentMetadata := map[interface{}]interface{}{}
+				if i, ok2 := componentMap["metadata"]; ok2 {
+					componentMetadata, ok2 = i.(map[interface{}]interface{})
+					if !ok2 {
+						return nil, errors.New(fmt.Sprintf("Invalid 'metadata' section in the helmfile component '%s' in the config file %s.\n"+
+							"The 'metadata' section must be a map", component, stack))
+					}
+				}
+
 				// Process base component(s)
 				baseComponentVars := map[interface{}]interface{}{}
 				baseComponentSettings := map[interface{}]interface{}{}
@@ -639,15 +809,14 @@ func ProcessConfig(
 					finalComponentHelmfileCommand = componentHelmfileCommand
 				}
 
-				comp := map[string]interface{}{}
-				comp["vars"] = finalComponentVars
-				comp["settings"] = finalComponentSettings
-				comp["env"] = finalComponentEnv
-				comp["command"] = finalComponentHelmfileCommand
-				comp["inheritance"] = componentInheritanceChain
-
-				if baseComponentName != "" {
-					comp["component"] = baseComponentName
+				comp := ComponentConfig{
+					Vars:          c.MapsOfInterfacesToMapsOfStringsRecursive(finalComponentVars),
+					Settings:      c.MapsOfInterfacesToMapsOfStringsRecursive(finalComponentSettings),
+					Env:           c.MapsOfInterfacesToMapsOfStringsRecursive(finalComponentEnv),
+					Command:       finalComponentHelmfileCommand,
+					BaseComponent: baseComponentName,
+					Inheritance:   componentInheritanceChain,
+					Metadata:      c.MapsOfInterfacesToMapsOfStringsRecursive(componentMetadata),
 				}
 
 				// TODO: this feature is not used anywhere, it has old code and it has issues with some YAML stack configs
@@ -667,9 +836,9 @@ func ProcessConfig(
 					if err != nil {
 						return nil, err
 					}
-					comp["deps"] = componentDeps
+					comp.Deps = componentDeps
 				} else {
-					comp["deps"] = []string{}
+					comp.Deps = []string{}
 				}
 
 				helmfileComponents[component] = comp
@@ -697,6 +866,7 @@ type BaseComponentConfig struct {
 	BaseComponentBackendSection            map[interface{}]interface{}
 	BaseComponentRemoteStateBackendType    string
 	BaseComponentRemoteStateBackendSection map[interface{}]interface{}
+	BaseComponentProvidersSection          map[interface{}]interface{}
 	ComponentInheritanceChain              []string
 }
 
@@ -720,6 +890,7 @@ func processBaseComponentConfig(
 	var baseComponentBackendSection map[interface{}]interface{}
 	var baseComponentRemoteStateBackendType string
 	var baseComponentRemoteStateBackendSection map[interface{}]interface{}
+	var baseComponentProvidersSection map[interface{}]interface{}
 	var baseComponentMap map[interface{}]interface{}
 
 	if baseComponentSection, baseComponentSectionExist := allComponentsMap[baseComponent]; baseComponentSectionExist {
@@ -768,6 +939,11 @@ func processBaseComponentConfig(
 			baseComponentRemoteStateBackendSection = i.(map[interface{}]interface{})
 		}
 
+		// Base component providers
+		if i, ok2 := baseComponentMap["providers"]; ok2 {
+			baseComponentProvidersSection = i.(map[interface{}]interface{})
+		}
+
 		// Base component `command`
 		if baseComponentCommandSection, baseComponentCommandSectionExist := baseComponentMap["command"]; baseComponentCommandSectionExist {
 			baseComponentCommand = baseComponentCommandSection.(string)
@@ -813,6 +989,12 @@ func processBaseComponentConfig(
 		}
 		baseComponentConfig.BaseComponentRemoteStateBackendSection = merged
 
+		merged, err = m.Merge([]map[interface{}]interface{}{baseComponentConfig.BaseComponentProvidersSection, baseComponentProvidersSection})
+		if err != nil {
+			return err
+		}
+		baseComponentConfig.BaseComponentProvidersSection = merged
+
 		baseComponentConfig.ComponentInheritanceChain = append([]string{baseComponent}, baseComponentConfig.ComponentInheritanceChain...)
 	} else {
 		return errors.New("Terraform component '" + component + "' defines attribute 'component: " +
@@ -1,11 +1,20 @@
 package stack
 
 import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
 	c "github.com/cloudposse/atmos/pkg/convert"
 	u "github.com/cloudposse/atmos/pkg/utils"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 func TestStackProcessor(t *testing.T) {
@@ -76,6 +85,12 @@ func TestStackProcessor(t *testing.T) {
 	assert.Equal(t, "s3", infraVpcComponentBackendType)
 	assert.Equal(t, "s3", infraVpcComponentRemoteSateBackendType)
 
+	infraVpcComponentProviders := infraVpcComponent["providers"].(map[interface{}]interface{})
+	infraVpcComponentProvidersAws := infraVpcComponentProviders["aws"].(map[interface{}]interface{})
+	infraVpcComponentProvidersAwsTags := infraVpcComponentProvidersAws["default_tags"].(map[interface{}]interface{})["tags"].(map[interface{}]interface{})
+	assert.Equal(t, "eg", infraVpcComponentProvidersAwsTags["Namespace"])
+	assert.Equal(t, "ue2", infraVpcComponentProvidersAwsTags["Environment"])
+
 	testTestComponent := terraformComponents["test/test-component"].(map[interface{}]interface{})
 	testTestComponentBackend := testTestComponent["backend"].(map[interface{}]interface{})
 	testTestComponentBackendType := testTestComponent["backend_type"]
@@ -156,7 +171,306 @@ func TestStackProcessor(t *testing.T) {
 	assert.Equal(t, "infra/infra-server", infraInfraServerOverrideComponentInheritance[0])
 	assert.Equal(t, "1_override", infraInfraServerOverrideComponentVarsA)
 
+	typedComponents := mapConfig2.(map[interface{}]interface{})["components"].(map[string]map[string]ComponentConfig)
+	typedComponent := typedComponents["terraform"]["test/test-component-override-2"]
+	assert.Equal(t, "test/test-component", typedComponent.BaseComponent)
+	assert.Equal(t, "s3", typedComponent.BackendType)
+	assert.Equal(t, "test-test-component", typedComponent.Backend["workspace_key_prefix"])
+	assert.Equal(t, "test/test-component-override", typedComponent.Inheritance[0])
+
+	jsonConfig, err := json.Marshal(typedComponent)
+	assert.Nil(t, err)
+	var jsonComponent map[string]interface{}
+	err = json.Unmarshal(jsonConfig, &jsonComponent)
+	assert.Nil(t, err)
+	assert.Equal(t, "test/test-component", jsonComponent["component"])
+	assert.Equal(t, "s3", jsonComponent["backend_type"])
+	assert.Equal(t, "test-test-component", jsonComponent["backend"].(map[string]interface{})["workspace_key_prefix"])
+
+	var roundTripComponent ComponentConfig
+	err = json.Unmarshal(jsonConfig, &roundTripComponent)
+	assert.Nil(t, err)
+	assert.Equal(t, typedComponent.BaseComponent, roundTripComponent.BaseComponent)
+	assert.Equal(t, typedComponent.Inheritance, roundTripComponent.Inheritance)
+
 	yamlConfig, err := yaml.Marshal(mapConfig1)
 	assert.Nil(t, err)
 	t.Log(string(yamlConfig))
 }
+
+func TestStackProcessorExternalImports(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	tmpDir := t.TempDir()
+
+	// Catalog in a local git repository with a tag
+	catalogDir := filepath.Join(tmpDir, "catalog")
+	writeTestFile(t, filepath.Join(catalogDir, "catalog/globals.yaml"), "vars:\n  namespace: eg\n")
+	writeTestFile(t, filepath.Join(catalogDir, "catalog/vpc.yaml"), "import:\n  - catalog/globals\ncomponents:\n  terraform:\n    vpc:\n      vars:\n        cidr_block: 10.0.0.0/16\n")
+	runTestGit(t, catalogDir, "init", "--quiet")
+	runTestGit(t, catalogDir, "add", ".")
+	runTestGit(t, catalogDir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "v1")
+	runTestGit(t, catalogDir, "tag", "v1.0.0")
+	commit := strings.TrimSpace(runTestGit(t, catalogDir, "rev-parse", "HEAD"))
+
+	// The tag must still resolve to the first commit after new commits
+	writeTestFile(t, filepath.Join(catalogDir, "catalog/vpc.yaml"), "components:\n  terraform:\n    vpc:\n      vars:\n        cidr_block: 10.99.0.0/16\n")
+	runTestGit(t, catalogDir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-am", "v2")
+
+	// Catalog in a local archive
+	archivePath := filepath.Join(tmpDir, "catalog.tgz")
+	archive, err := os.Create(archivePath)
+	assert.Nil(t, err)
+	gzipWriter := gzip.NewWriter(archive)
+	tarWriter := tar.NewWriter(gzipWriter)
+	eksConfig := []byte("components:\n  terraform:\n    eks:\n      vars:\n        cluster_version: \"1.21\"\n")
+	assert.Nil(t, tarWriter.WriteHeader(&tar.Header{Name: "eks.yaml", Mode: 0644, Size: int64(len(eksConfig)), Typeflag: tar.TypeReg}))
+	_, err = tarWriter.Write(eksConfig)
+	assert.Nil(t, err)
+	assert.Nil(t, tarWriter.Close())
+	assert.Nil(t, gzipWriter.Close())
+	assert.Nil(t, archive.Close())
+
+	stacksDir := filepath.Join(tmpDir, "stacks")
+	stackFile := filepath.Join(stacksDir, "dev.yaml")
+	writeTestFile(t, stackFile, "import:\n"+
+		"  - git::file://"+filepath.ToSlash(catalogDir)+"//catalog/vpc?ref=v1.0.0\n"+
+		"  - archive:../catalog.tgz//eks\n"+
+		"vars:\n  stage: dev\n")
+
+	fileSystem := NewFileSystem(nil)
+	fileSystem.SetImportsCacheDir(filepath.Join(tmpDir, "cache"))
+
+	_, mapResult, err := fileSystem.ProcessYAMLConfigFiles(stacksDir, []string{stackFile}, false, false)
+	assert.Nil(t, err)
+
+	stackConfig := mapResult["dev"].(map[interface{}]interface{})
+	components := stackConfig["components"].(map[string]map[string]ComponentConfig)
+	assert.Equal(t, "10.0.0.0/16", components["terraform"]["vpc"].Vars["cidr_block"])
+	assert.Equal(t, "eg", components["terraform"]["vpc"].Vars["namespace"])
+	assert.Equal(t, "dev", components["terraform"]["vpc"].Vars["stage"])
+	assert.Equal(t, "1.21", components["terraform"]["eks"].Vars["cluster_version"])
+
+	imports := stackConfig["imports"].([]string)
+	assert.Equal(t, 3, len(imports))
+	assert.True(t, strings.HasPrefix(imports[0], "archive:../catalog.tgz//eks?sha256="))
+	assert.Equal(t, "git::file://"+filepath.ToSlash(catalogDir)+"//catalog/globals?ref="+commit, imports[1])
+	assert.Equal(t, "git::file://"+filepath.ToSlash(catalogDir)+"//catalog/vpc?ref="+commit, imports[2])
+
+	sources := fileSystem.ImportSources()
+	assert.Equal(t, 2, len(sources))
+	assert.Equal(t, ImportSourceTypeArchive, sources[0].Type)
+	assert.Equal(t, ImportSourceTypeGit, sources[1].Type)
+	assert.Equal(t, "v1.0.0", sources[1].Ref)
+	assert.Equal(t, commit, sources[1].Commit)
+
+	// Missing refs and invalid imports fail
+	writeTestFile(t, stackFile, "import:\n  - git::file://"+filepath.ToSlash(catalogDir)+"//catalog/vpc?ref=v9.9.9\n")
+	fileSystem = NewFileSystem(nil)
+	fileSystem.SetImportsCacheDir(filepath.Join(tmpDir, "cache"))
+	_, _, err = fileSystem.ProcessYAMLConfigFiles(stacksDir, []string{stackFile}, false, false)
+	assert.NotNil(t, err)
+
+	writeTestFile(t, stackFile, "import:\n  - archive:../catalog.tgz\n")
+	fileSystem = NewFileSystem(nil)
+	fileSystem.SetImportsCacheDir(filepath.Join(tmpDir, "cache"))
+	_, _, err = fileSystem.ProcessYAMLConfigFiles(stacksDir, []string{stackFile}, false, false)
+	assert.NotNil(t, err)
+
+	// The URLs and refs which would be interpreted as git options are rejected
+	for _, imp := range []string{
+		"git::--upload-pack=touch " + filepath.Join(tmpDir, "pwned") + "//catalog/vpc",
+		"git::file://" + filepath.ToSlash(catalogDir) + "//catalog/vpc?ref=--output=" + filepath.Join(tmpDir, "pwned"),
+	} {
+		writeTestFile(t, stackFile, "import:\n  - '"+imp+"'\n")
+		fileSystem = NewFileSystem(nil)
+		fileSystem.SetImportsCacheDir(filepath.Join(tmpDir, "cache"))
+		_, _, err = fileSystem.ProcessYAMLConfigFiles(stacksDir, []string{stackFile}, false, false)
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "must not start with '-'")
+	}
+	_, err = os.Stat(filepath.Join(tmpDir, "pwned"))
+	assert.True(t, os.IsNotExist(err))
+
+	// The paths which point outside of the source are rejected
+	for _, imp := range []string{
+		"git::file://" + filepath.ToSlash(catalogDir) + "//../stacks/*",
+		"git::file://" + filepath.ToSlash(catalogDir) + "//catalog/../../stacks/*",
+		"archive:../catalog.tgz//..",
+	} {
+		writeTestFile(t, stackFile, "import:\n  - '"+imp+"'\n")
+		fileSystem = NewFileSystem(nil)
+		fileSystem.SetImportsCacheDir(filepath.Join(tmpDir, "cache"))
+		_, _, err = fileSystem.ProcessYAMLConfigFiles(stacksDir, []string{stackFile}, false, false)
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "must be inside the source")
+	}
+}
+
+func writeTestFile(t *testing.T, filePath string, content string) {
+	assert.Nil(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte(content), 0644))
+}
+
+func runTestGit(t *testing.T, dir string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	assert.Nil(t, err, string(out))
+	return string(out)
+}
+
+func TestStackProcessorInvalidMetadata(t *testing.T) {
+	config, err := c.YAMLToMapOfInterfaces(`
+components:
+  terraform:
+    vpc:
+      metadata: invalid
+`)
+	assert.Nil(t, err)
+
+	_, err = ProcessConfig("", "tenant1/ue2/dev", config, false, false, "", nil, nil)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Invalid 'metadata' section in the terraform component 'vpc'")
+}
+
+func TestStackProcessorLock(t *testing.T) {
+	basePath := "../../examples/complete/stacks"
+
+	filePaths := []string{
+		"../../examples/complete/stacks/tenant1/ue2/dev.yaml",
+		"../../examples/complete/stacks/tenant1/ue2/prod.yaml",
+	}
+
+	fileSystem := NewFileSystem(nil)
+	_, _, err := fileSystem.ProcessYAMLConfigFiles(basePath, filePaths, false, false)
+	assert.Nil(t, err)
+
+	lock := fileSystem.Lock()
+	assert.Equal(t, LockFileVersion, lock.Version)
+	assert.Equal(t, 2, len(lock.Stacks))
+
+	// The imports are in the order they are deep-merged (`globals/globals` is imported by `globals/ue2-globals`)
+	devImports := lock.Stacks["tenant1/ue2/dev"].Imports
+	assert.Equal(t, "globals/tenant1-globals", devImports[0].Import)
+	assert.Equal(t, "globals/globals", devImports[1].Import)
+	assert.Equal(t, "globals/ue2-globals", devImports[2].Import)
+	assert.Equal(t, 64, len(devImports[0].SHA256))
+
+	yamlLock, err := yaml.Marshal(lock)
+	assert.Nil(t, err)
+	var lockFromFile StacksLock
+	err = yaml.Unmarshal(yamlLock, &lockFromFile)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(lockFromFile.Verify(lock)))
+
+	// Changed content
+	changedLock := copyTestLock(lock)
+	changedLock.Stacks["tenant1/ue2/dev"].Imports[0].SHA256 = "changed"
+	assert.Equal(t, []string{"stack 'tenant1/ue2/dev': content of the import 'globals/tenant1-globals' has changed"}, changedLock.Verify(lock))
+
+	// Changed order
+	reorderedLock := copyTestLock(lock)
+	reorderedImports := reorderedLock.Stacks["tenant1/ue2/prod"].Imports
+	reorderedImports[0], reorderedImports[1] = reorderedImports[1], reorderedImports[0]
+	assert.Equal(t, []string{"stack 'tenant1/ue2/prod': order of the imports has changed"}, reorderedLock.Verify(lock))
+
+	// Removed import and removed stack
+	removedLock := copyTestLock(lock)
+	removedLock.Stacks["tenant1/ue2/dev"] = StackLock{Imports: removedLock.Stacks["tenant1/ue2/dev"].Imports[1:]}
+	delete(removedLock.Stacks, "tenant1/ue2/prod")
+	assert.Equal(t, []string{
+		"stack 'tenant1/ue2/dev': import 'globals/tenant1-globals' is not in the lock file",
+		"stack 'tenant1/ue2/prod' is not in the lock file",
+	}, removedLock.Verify(lock))
+}
+
+func copyTestLock(lock StacksLock) StacksLock {
+	res := StacksLock{Version: lock.Version, Stacks: map[string]StackLock{}}
+	for stack, stackLock := range lock.Stacks {
+		res.Stacks[stack] = StackLock{Imports: append([]ResolvedImport{}, stackLock.Imports...)}
+	}
+	return res
+}
+
+func TestParseStackYAMLTags(t *testing.T) {
+	assert.Nil(t, os.Setenv("ATMOS_TEST_REGION", "us-east-2"))
+	defer os.Unsetenv("ATMOS_TEST_REGION")
+
+	fileSystem := NewFileSystem(fstest.MapFS{
+		"stacks/policies/bucket-policy.json": {Data: []byte(`{"Version": "2012-10-17", "Statement": []}`)},
+		"stacks/policies/readme.txt":         {Data: []byte("policy docs\n")},
+	})
+	fileSystem.SetExecTagEnabled(true)
+
+	config, _, err := fileSystem.parseStackYAML("stacks", "stacks/dev.yaml", `
+components:
+  terraform:
+    vpc:
+      vars:
+        name: vpc
+        region: !env ATMOS_TEST_REGION
+        stage: !env ATMOS_TEST_STAGE dev
+        policy: !include policies/bucket-policy.json
+        readme: !include policies/readme.txt
+        version: !exec echo 1.2.3
+        token: !secret env:GITHUB_TOKEN
+        db_password: !secret "exec:pass show db/prod"
+`)
+	assert.Nil(t, err)
+
+	vars := config["components"].(map[interface{}]interface{})["terraform"].(map[interface{}]interface{})["vpc"].(map[interface{}]interface{})["vars"].(map[interface{}]interface{})
+	assert.Equal(t, "vpc", vars["name"])
+	assert.Equal(t, "us-east-2", vars["region"])
+	assert.Equal(t, "dev", vars["stage"])
+	assert.Equal(t, "2012-10-17", vars["policy"].(map[interface{}]interface{})["Version"])
+	assert.Equal(t, "policy docs\n", vars["readme"])
+	assert.Equal(t, "1.2.3", vars["version"])
+	assert.Equal(t, "!secret env:GITHUB_TOKEN", vars["token"])
+	assert.Equal(t, "!secret exec:pass show db/prod", vars["db_password"])
+
+	_, _, err = fileSystem.parseStackYAML("stacks", "stacks/dev.yaml", "vars:\n  token: !secret\n    env: GITHUB_TOKEN\n")
+	assert.NotNil(t, err)
+
+	// The errors point at the file and the key which used the tag
+	_, _, err = fileSystem.parseStackYAML("stacks", "stacks/dev.yaml", "vars:\n  region: !env ATMOS_TEST_NOT_SET\n")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "stacks/dev.yaml:2: 'vars.region'")
+
+	fileSystem.SetExecTagEnabled(false)
+	_, _, err = fileSystem.parseStackYAML("stacks", "stacks/dev.yaml", "vars:\n  version: !exec echo 1.2.3\n")
+	assert.NotNil(t, err)
+}
+
+func TestStackProcessorIncludedFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"stacks/policies/bucket-policy.json": {Data: []byte(`{"Version": "2012-10-17"}`)},
+		"stacks/catalog/s3-bucket.yaml":      {Data: []byte("components:\n  terraform:\n    s3-bucket:\n      vars:\n        policy: !include policies/bucket-policy.json\n")},
+		"stacks/dev.yaml":                    {Data: []byte("import:\n  - catalog/s3-bucket\ncomponents:\n  terraform:\n    vpc:\n      vars:\n        name: vpc\n")},
+	}
+
+	fileSystem := NewFileSystem(fsys)
+	_, mapResult, err := fileSystem.ProcessYAMLConfigFiles("stacks", []string{"stacks/dev.yaml"}, false, true)
+	assert.Nil(t, err)
+
+	// The included files are recorded in the lock file
+	lock := fileSystem.Lock()
+	imports := lock.Stacks["dev"].Imports
+	assert.Equal(t, 2, len(imports))
+	assert.Equal(t, "policies/bucket-policy.json", imports[0].Import)
+	assert.Equal(t, "catalog/s3-bucket", imports[1].Import)
+
+	// The included files are the dependencies of the components using them
+	components := mapResult["dev"].(map[interface{}]interface{})["components"].(map[string]map[string]ComponentConfig)
+	assert.Equal(t, []string{"catalog/s3-bucket", "dev", "policies/bucket-policy.json"}, components["terraform"]["s3-bucket"].Deps)
+	assert.Equal(t, []string{"dev"}, components["terraform"]["vpc"].Deps)
+
+	// A change of the included file is detected
+	fsys["stacks/policies/bucket-policy.json"] = &fstest.MapFile{Data: []byte(`{"Version": "2008-10-17"}`)}
+	fileSystem = NewFileSystem(fsys)
+	_, _, err = fileSystem.ProcessYAMLConfigFiles("stacks", []string{"stacks/dev.yaml"}, false, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"stack 'dev': content of the import 'policies/bucket-policy.json' has changed"}, lock.Verify(fileSystem.Lock()))
+}
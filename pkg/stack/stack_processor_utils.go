@@ -1,24 +1,14 @@
 package stack
 
 import (
-	"fmt"
-	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
 	"sort"
 	"strings"
-	"sync"
 
-	"github.com/bmatcuk/doublestar/v4"
 	g "github.com/cloudposse/atmos/pkg/globals"
 	"github.com/cloudposse/atmos/pkg/utils"
-	"github.com/fatih/color"
-)
-
-var (
-	getFileContentSyncMap = sync.Map{}
-	getGlobMatchesSyncMap = sync.Map{}
 )
 
 // FindComponentStacks finds all infrastructure stack config files where the component or the base component is defined
@@ -165,19 +155,16 @@ func CreateComponentStackMap(basePath string, filePath string) (map[string]map[s
 				}
 
 				if componentsConfig, componentsConfigExists := finalConfig["components"]; componentsConfigExists {
-					componentsSection := componentsConfig.(map[string]interface{})
+					componentsSection := componentsConfig.(map[string]map[string]ComponentConfig)
 					stackName := strings.Replace(p, basePath+"/", "", 1)
 
-					if terraformConfig, terraformConfigExists := componentsSection["terraform"]; terraformConfigExists {
-						terraformSection := terraformConfig.(map[string]interface{})
-
+					if terraformSection, terraformConfigExists := componentsSection["terraform"]; terraformConfigExists {
 						for k := range terraformSection {
 							stackComponentMap["terraform"][stackName] = append(stackComponentMap["terraform"][stackName], k)
 						}
 					}
 
-					if helmfileConfig, helmfileConfigExists := componentsSection["helmfile"]; helmfileConfigExists {
-						helmfileSection := helmfileConfig.(map[string]interface{})
+					if helmfileSection, helmfileConfigExists := componentsSection["helmfile"]; helmfileConfigExists {
 
 						for k := range helmfileSection {
 							stackComponentMap["helmfile"][stackName] = append(stackComponentMap["helmfile"][stackName], k)
@@ -207,51 +194,3 @@ func CreateComponentStackMap(basePath string, filePath string) (map[string]map[s
 
 	return componentStackMap, nil
 }
-
-// getFileContent tries to read and return the file content from the sync map if it exists in the map,
-// otherwise it reads the file, stores its content in the map and returns the content
-func getFileContent(filePath string) (string, error) {
-	existingContent, found := getFileContentSyncMap.Load(filePath)
-	if found == true && existingContent != nil {
-		return fmt.Sprintf("%s", existingContent), nil
-	}
-
-	content, err := ioutil.ReadFile(filePath)
-	if err != nil {
-		return "", err
-	}
-	getFileContentSyncMap.Store(filePath, content)
-
-	return string(content), nil
-}
-
-// GetGlobMatches tries to read and return the Glob matches content from the sync map if it exists in the map,
-// otherwise it finds and returns all files matching the pattern, stores the files in the map and returns the files
-func GetGlobMatches(pattern string) ([]string, error) {
-	existingMatches, found := getGlobMatchesSyncMap.Load(pattern)
-	if found == true && existingMatches != nil {
-		return strings.Split(fmt.Sprintf("%s", existingMatches), ","), nil
-	}
-
-	base, cleanPattern := doublestar.SplitPattern(pattern)
-	f := os.DirFS(base)
-
-	matches, err := doublestar.Glob(f, cleanPattern)
-	if err != nil {
-		return nil, err
-	}
-
-	if matches == nil {
-		color.Red(fmt.Sprintf("Import of %s (-> %s + %s) failed to find a match.", pattern, base, cleanPattern))
-		return nil, nil
-	}
-
-	var fullMatches []string
-	for _, match := range matches {
-		fullMatches = append(fullMatches, path.Join(base, match))
-	}
-
-	getGlobMatchesSyncMap.Store(pattern, strings.Join(fullMatches, ","))
-
-	return fullMatches, nil
-}
@@ -0,0 +1,299 @@
+package stack
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	c "github.com/cloudposse/atmos/pkg/convert"
+	"github.com/cloudposse/atmos/pkg/secrets"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// The custom YAML tags evaluated when the stack config files are processed
+const (
+	// TagEnv is replaced by the value of the ENV var, e.g. `!env AWS_REGION` or `!env AWS_REGION us-east-2` (with the default value)
+	TagEnv = "!env"
+	// TagInclude is replaced by the content of the file, e.g. `!include policies/s3-bucket-policy.json`.
+	// The `.json`, `.yaml` and `.yml` files are embedded as structured data, all other files as text.
+	// The path is relative to the stacks base path (the same as the imports)
+	TagInclude = "!include"
+	// TagExec is replaced by the output of the command, e.g. `!exec git rev-parse HEAD`.
+	// The command is executed without a shell, and only if `stacks.yaml_tags.exec_enabled` is set in the CLI config
+	TagExec = "!exec"
+	// TagRef references a value of another component in the same stack or in another stack,
+	// e.g. `!ref infra/vpc.vars.cidr_block` or `!ref tenant1-ue2-prod:infra/vpc.vars.vpc_name`.
+	// The references are resolved against the final component configs after all stacks are processed
+	TagRef = "!ref"
+	// TagTerraformOutput is replaced by the output of a terraform component in the same stack or in another stack,
+	// e.g. `!terraform.output vpc vpc_id` or `!terraform.output vpc tenant1-ue2-prod vpc_id`.
+	// The outputs are read (`terraform output -json`) only when the component is executed
+	TagTerraformOutput = "!terraform.output"
+)
+
+// taggedStringYAMLTags are the custom YAML tags which are kept as strings prefixed with the tag (e.g. `!secret env:GITHUB_TOKEN`),
+// so they survive the deep-merging of the stacks and are processed later
+var taggedStringYAMLTags = []string{
+	secrets.Tag,
+	TagRef,
+	TagTerraformOutput,
+}
+
+// SetExecTagEnabled allows the `!exec` YAML tag in the stack config files
+func (f *FileSystem) SetExecTagEnabled(enabled bool) {
+	f.execTagEnabled = enabled
+}
+
+// yamlTagsProcessor evaluates the custom YAML tags in a stack config file
+type yamlTagsProcessor struct {
+	f        *FileSystem
+	basePath string
+	filePath string
+	// includes is the chain of the included files, to detect the circular includes
+	includes []string
+	// included are all the files included in the stack config file (at any level)
+	included *[]string
+	changed  bool
+}
+
+// parseStackYAML parses the stack config file, evaluates the custom YAML tags (`!env`, `!include`, `!exec`),
+// and keeps the values with the other custom tags (`!secret`, `!ref`, `!terraform.output`) as tagged strings.
+// It also returns the paths of all the files included with `!include`
+func (f *FileSystem) parseStackYAML(basePath string, filePath string, content string) (map[interface{}]interface{}, []string, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &node); err != nil {
+		return nil, nil, err
+	}
+
+	var included []string
+	p := &yamlTagsProcessor{f: f, basePath: basePath, filePath: filePath, includes: []string{filePath}, included: &included}
+	if err := p.process(&node, ""); err != nil {
+		return nil, nil, err
+	}
+
+	if p.changed {
+		out, err := yaml.Marshal(&node)
+		if err != nil {
+			return nil, nil, err
+		}
+		content = string(out)
+	}
+
+	res, err := c.YAMLToMapOfInterfaces(content)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res, included, nil
+}
+
+// process evaluates the custom YAML tags in the node and all its children.
+// `key` is the path to the node (e.g. `components.terraform.vpc.vars.name`) to show in the errors
+func (p *yamlTagsProcessor) process(node *yaml.Node, key string) error {
+	switch node.Tag {
+	case TagEnv, TagInclude, TagExec:
+		if node.Kind != yaml.ScalarNode {
+			return p.error(key, node, errors.New("the tag can only be used with a string value"))
+		}
+		var err error
+		switch node.Tag {
+		case TagEnv:
+			err = p.processEnv(node)
+		case TagInclude:
+			err = p.processInclude(node)
+		case TagExec:
+			err = p.processExec(node)
+		}
+		if err != nil {
+			return p.error(key, node, err)
+		}
+		p.changed = true
+		return nil
+	}
+
+	if isTaggedStringYAMLTag(node.Tag) {
+		if node.Kind != yaml.ScalarNode {
+			return p.error(key, node, errors.New("the tag can only be used with a string value"))
+		}
+		setStringNode(node, node.Tag+" "+node.Value)
+		p.changed = true
+		return nil
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if err := p.process(node.Content[i+1], joinYAMLKey(key, node.Content[i].Value)); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for i, n := range node.Content {
+			if err := p.process(n, fmt.Sprintf("%s[%d]", key, i)); err != nil {
+				return err
+			}
+		}
+	default:
+		for _, n := range node.Content {
+			if err := p.process(n, key); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// processEnv replaces the node with the value of the ENV var (`!env NAME` or `!env NAME default`)
+func (p *yamlTagsProcessor) processEnv(node *yaml.Node) error {
+	parts := strings.SplitN(strings.TrimSpace(node.Value), " ", 2)
+	name := parts[0]
+	if len(name) == 0 {
+		return errors.New("the name of the ENV var must be specified")
+	}
+
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		if len(parts) < 2 {
+			return errors.New(fmt.Sprintf("the ENV var '%s' is not set and no default value is specified", name))
+		}
+		value = strings.TrimSpace(parts[1])
+	}
+
+	setStringNode(node, value)
+	return nil
+}
+
+// processInclude replaces the node with the structured data from a JSON or YAML file, or with the content of any other file as text
+func (p *yamlTagsProcessor) processInclude(node *yaml.Node) error {
+	includePath := strings.TrimSpace(node.Value)
+	if len(includePath) == 0 {
+		return errors.New("the path to the file must be specified")
+	}
+
+	filePath := includePath
+	if !filepath.IsAbs(filePath) {
+		filePath = path.Join(p.basePath, includePath)
+	}
+
+	for _, included := range p.includes {
+		if included == filePath {
+			return errors.New(fmt.Sprintf("circular include of the file '%s'", includePath))
+		}
+	}
+
+	content, err := p.f.getFileContent(filePath)
+	if err != nil {
+		return err
+	}
+	*p.included = append(*p.included, filePath)
+
+	switch filepath.Ext(filePath) {
+	case ".json", ".yaml", ".yml":
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+			return errors.New(fmt.Sprintf("invalid file '%s': %s", includePath, err))
+		}
+		if len(doc.Content) == 0 {
+			setStringNode(node, "null")
+			node.Tag = "!!null"
+			return nil
+		}
+
+		// The tags in the included file are evaluated too
+		included := &yamlTagsProcessor{
+			f:        p.f,
+			basePath: p.basePath,
+			filePath: filePath,
+			includes: append(append([]string{}, p.includes...), filePath),
+			included: p.included,
+		}
+		if err := included.process(doc.Content[0], ""); err != nil {
+			return err
+		}
+
+		*node = *doc.Content[0]
+	default:
+		setStringNode(node, content)
+	}
+
+	return nil
+}
+
+// processExec replaces the node with the output of the command (without the trailing new line)
+func (p *yamlTagsProcessor) processExec(node *yaml.Node) error {
+	if !p.f.execTagEnabled {
+		return errors.New(fmt.Sprintf("the '%s' tag is disabled. "+
+			"Set 'stacks.yaml_tags.exec_enabled: true' in the CLI config or 'ATMOS_STACKS_YAML_TAGS_EXEC_ENABLED=true' ENV var to enable it", TagExec))
+	}
+
+	command := strings.TrimSpace(node.Value)
+	if len(command) == 0 {
+		return errors.New("the command must be specified")
+	}
+
+	// The same command is executed only once, even if the file is imported by many stacks
+	if output, found := p.f.execTagSyncMap.Load(command); found {
+		setStringNode(node, output.(string))
+		return nil
+	}
+
+	args := strings.Fields(command)
+	cmd := exec.Command(args[0], args[1:]...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return errors.New(fmt.Sprintf("'%s' failed: %s %s", command, err, strings.TrimSpace(stderr.String())))
+	}
+
+	output := strings.TrimRight(stdout.String(), "\r\n")
+	p.f.execTagSyncMap.Store(command, output)
+	setStringNode(node, output)
+	return nil
+}
+
+// error returns the error with the file, the line and the key which used the tag
+func (p *yamlTagsProcessor) error(key string, node *yaml.Node, err error) error {
+	return errors.New(fmt.Sprintf("%s:%d: '%s': %s %s: %s", p.filePath, node.Line, key, node.Tag, node.Value, err))
+}
+
+func setStringNode(node *yaml.Node, value string) {
+	node.Kind = yaml.ScalarNode
+	node.Tag = "!!str"
+	node.Value = value
+	node.Style = 0
+	node.Content = nil
+}
+
+func joinYAMLKey(key string, k string) string {
+	if len(key) == 0 {
+		return k
+	}
+	return key + "." + k
+}
+
+// IsTaggedString checks if the value is a string prefixed with a custom YAML tag which is processed later
+// (e.g. `!secret env:GITHUB_TOKEN` or `!terraform.output vpc vpc_id`), and is not the final value yet
+func IsTaggedString(value string) bool {
+	for _, t := range taggedStringYAMLTags {
+		if strings.HasPrefix(value, t+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+func isTaggedStringYAMLTag(tag string) bool {
+	for _, t := range taggedStringYAMLTags {
+		if tag == t {
+			return true
+		}
+	}
+	return false
+}
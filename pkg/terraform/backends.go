@@ -0,0 +1,138 @@
+package terraform
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// BackendType describes a terraform backend type: the fields required in its config, and the fields derived from the component name
+type BackendType struct {
+	// Required are the fields which must be set in the backend config. The nested fields are separated by dots (e.g. `workspaces.name`)
+	Required []string
+	// SetDefaults sets the fields which are not in the backend config from the name of the terraform component (with `/` replaced by `-`)
+	SetDefaults func(backend map[string]interface{}, component string)
+	// Validate checks the backend config in addition to the required fields
+	Validate func(backend map[string]interface{}) error
+}
+
+// BackendTypes is the registry of the supported terraform backend types.
+// The backend configs of the other types are used as is
+var BackendTypes = map[string]BackendType{
+	"s3": {
+		// `region` is required in the config (and not read from the `AWS_REGION` ENV var),
+		// since the generated backend files and the remote state data sources are used outside of the environment of atmos
+		Required: []string{"bucket", "key", "region", "workspace_key_prefix"},
+		SetDefaults: func(backend map[string]interface{}, component string) {
+			setDefault(backend, "workspace_key_prefix", component)
+		},
+	},
+	"gcs": {
+		Required: []string{"bucket", "prefix"},
+		SetDefaults: func(backend map[string]interface{}, component string) {
+			setDefault(backend, "prefix", component)
+		},
+	},
+	"azurerm": {
+		Required: []string{"storage_account_name", "container_name", "key"},
+		SetDefaults: func(backend map[string]interface{}, component string) {
+			setDefault(backend, "key", component+".terraform.tfstate")
+		},
+	},
+	"remote": {
+		Required: []string{"organization"},
+		SetDefaults: func(backend map[string]interface{}, component string) {
+			workspaces, ok := backend["workspaces"].(map[string]interface{})
+			if !ok {
+				workspaces = map[string]interface{}{}
+				backend["workspaces"] = workspaces
+			}
+			if _, ok := workspaces["name"]; !ok {
+				setDefault(workspaces, "prefix", component+"-")
+			}
+		},
+		Validate: func(backend map[string]interface{}) error {
+			_, hasName := lookupField(backend, "workspaces.name")
+			_, hasPrefix := lookupField(backend, "workspaces.prefix")
+			if hasName == hasPrefix {
+				return errors.New("exactly one of 'workspaces.name' and 'workspaces.prefix' must be set")
+			}
+			return nil
+		},
+	},
+	"http": {
+		Required: []string{"address"},
+	},
+	"consul": {
+		Required: []string{"path"},
+	},
+	"local": {},
+}
+
+// SetBackendDefaults sets the fields derived from the component name in the backend config of the provided type.
+// The `/` in the component name are replaced by `-`
+func SetBackendDefaults(backendType string, backend map[string]interface{}, component string) {
+	t, ok := BackendTypes[backendType]
+	if !ok || t.SetDefaults == nil || backend == nil {
+		return
+	}
+	t.SetDefaults(backend, strings.Replace(component, "/", "-", -1))
+}
+
+// ValidateBackend checks that the backend config has all the fields required by the backend type.
+// The backend configs of the types which are not in `BackendTypes` are not checked
+func ValidateBackend(backendType string, backend map[string]interface{}) error {
+	t, ok := BackendTypes[backendType]
+	if !ok {
+		return nil
+	}
+
+	var missing []string
+	for _, field := range t.Required {
+		if _, ok := lookupField(backend, field); !ok {
+			missing = append(missing, field)
+		}
+	}
+	sort.Strings(missing)
+
+	if len(missing) > 0 {
+		return errors.New(fmt.Sprintf("the '%s' backend config is missing '%s'", backendType, strings.Join(missing, "', '")))
+	}
+
+	if t.Validate != nil {
+		if err := t.Validate(backend); err != nil {
+			return errors.New(fmt.Sprintf("invalid '%s' backend config: %s", backendType, err))
+		}
+	}
+
+	return nil
+}
+
+// setDefault sets the field if it's not in the map or if it's `null`
+func setDefault(m map[string]interface{}, field string, value interface{}) {
+	if v, ok := m[field]; !ok || v == nil {
+		m[field] = value
+	}
+}
+
+// lookupField returns the value of the (nested) field. A field with a `null` or empty string value is considered not set
+func lookupField(m map[string]interface{}, field string) (interface{}, bool) {
+	parts := strings.Split(field, ".")
+	var value interface{} = m
+	for _, part := range parts {
+		section, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		value, ok = section[part]
+		if !ok || value == nil {
+			return nil, false
+		}
+	}
+	if s, ok := value.(string); ok && len(s) == 0 {
+		return nil, false
+	}
+	return value, true
+}
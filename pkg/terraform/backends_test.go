@@ -0,0 +1,101 @@
+package terraform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetBackendDefaults(t *testing.T) {
+	s3 := map[string]interface{}{"bucket": "eg-ue2-root-tfstate", "key": "terraform.tfstate", "region": "us-east-2"}
+	SetBackendDefaults("s3", s3, "infra/vpc")
+	assert.Equal(t, "infra-vpc", s3["workspace_key_prefix"])
+	assert.Nil(t, ValidateBackend("s3", s3))
+
+	// The fields set in the stacks are not changed
+	gcs := map[string]interface{}{"bucket": "eg-tfstate", "prefix": "vpc"}
+	SetBackendDefaults("gcs", gcs, "infra/vpc")
+	assert.Equal(t, "vpc", gcs["prefix"])
+
+	azurerm := map[string]interface{}{"storage_account_name": "eg", "container_name": "tfstate"}
+	SetBackendDefaults("azurerm", azurerm, "infra/vpc")
+	assert.Equal(t, "infra-vpc.terraform.tfstate", azurerm["key"])
+	assert.Nil(t, ValidateBackend("azurerm", azurerm))
+
+	remote := map[string]interface{}{"organization": "eg"}
+	SetBackendDefaults("remote", remote, "infra/vpc")
+	assert.Equal(t, "infra-vpc-", remote["workspaces"].(map[string]interface{})["prefix"])
+	assert.Nil(t, ValidateBackend("remote", remote))
+
+	remoteWithName := map[string]interface{}{"organization": "eg", "workspaces": map[string]interface{}{"name": "vpc"}}
+	SetBackendDefaults("remote", remoteWithName, "infra/vpc")
+	assert.Nil(t, remoteWithName["workspaces"].(map[string]interface{})["prefix"])
+	assert.Nil(t, ValidateBackend("remote", remoteWithName))
+}
+
+func TestValidateBackend(t *testing.T) {
+	err := ValidateBackend("gcs", map[string]interface{}{"prefix": "infra-vpc"})
+	assert.EqualError(t, err, "the 'gcs' backend config is missing 'bucket'")
+
+	err = ValidateBackend("s3", map[string]interface{}{"bucket": ""})
+	assert.EqualError(t, err, "the 's3' backend config is missing 'bucket', 'key', 'region', 'workspace_key_prefix'")
+
+	err = ValidateBackend("s3", map[string]interface{}{
+		"bucket":               "eg-ue2-root-tfstate",
+		"key":                  "terraform.tfstate",
+		"workspace_key_prefix": "infra-vpc",
+	})
+	assert.EqualError(t, err, "the 's3' backend config is missing 'region'")
+
+	assert.Nil(t, ValidateBackend("s3", map[string]interface{}{
+		"bucket":               "eg-ue2-root-tfstate",
+		"key":                  "terraform.tfstate",
+		"region":               "us-east-2",
+		"workspace_key_prefix": "infra-vpc",
+	}))
+
+	err = ValidateBackend("remote", map[string]interface{}{
+		"organization": "eg",
+		"workspaces":   map[string]interface{}{"name": "vpc", "prefix": "vpc-"},
+	})
+	assert.EqualError(t, err, "invalid 'remote' backend config: exactly one of 'workspaces.name' and 'workspaces.prefix' must be set")
+
+	assert.Nil(t, ValidateBackend("local", map[string]interface{}{}))
+	assert.Nil(t, ValidateBackend("pg", map[string]interface{}{}))
+}
+
+func TestRemoteStateDataSourceHCL(t *testing.T) {
+	remoteState := RemoteState{
+		BackendType: "s3",
+		Workspace:   "tenant1-ue2-dev",
+		Backend: map[string]interface{}{
+			"bucket":               "eg-ue2-root-tfstate",
+			"encrypt":              true,
+			"role_arn":             nil,
+			"workspace_key_prefix": "infra-vpc",
+		},
+	}
+
+	hcl, err := RemoteStateDataSourceHCL(DataSourceName("infra/vpc"), remoteState)
+	assert.Nil(t, err)
+	assert.Equal(t, `data "terraform_remote_state" "infra-vpc" {
+  backend   = "s3"
+  workspace = "tenant1-ue2-dev"
+  config = {
+    bucket               = "eg-ue2-root-tfstate"
+    encrypt              = true
+    role_arn             = null
+    workspace_key_prefix = "infra-vpc"
+  }
+}
+`, string(hcl))
+
+	dataSource, err := RemoteStateDataSource("vpc", remoteState)
+	assert.Nil(t, err)
+	vpc := dataSource["data"].(map[string]interface{})["terraform_remote_state"].(map[string]interface{})["vpc"].(map[string]interface{})
+	assert.Equal(t, "s3", vpc["backend"])
+	assert.Equal(t, "tenant1-ue2-dev", vpc["workspace"])
+
+	_, err = RemoteStateDataSourceHCL("vpc", RemoteState{BackendType: StaticBackendType})
+	assert.NotNil(t, err)
+}
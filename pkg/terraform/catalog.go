@@ -0,0 +1,160 @@
+package terraform
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ContextFileName is the file with the variables of the Cloud Posse null-label context (`namespace`, `tenant`, `environment`, `stage`, etc.).
+	// The context variables are usually set in the global `vars` sections of the stacks, not in the catalogs
+	ContextFileName = "context.tf"
+
+	requiredComment = "required"
+)
+
+// GenerateCatalog returns a catalog config for the terraform component with all the variables, their default values,
+// and their descriptions as comments. The required variables (without defaults) are set to `null` and marked with a `required` comment
+func GenerateCatalog(component string, variables []Variable) ([]byte, error) {
+	content, _, err := UpdateCatalog(nil, component, variables)
+	return content, err
+}
+
+// UpdateCatalog adds the variables which are not in the `vars` section of the component in the catalog config.
+// The existing values and comments are not changed. It returns the updated catalog config and the names of the added variables
+func UpdateCatalog(content []byte, component string, variables []Variable) ([]byte, []string, error) {
+	var doc yaml.Node
+
+	if len(bytes.TrimSpace(content)) > 0 {
+		err := yaml.Unmarshal(content, &doc)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{newMappingNode()}}
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 {
+		return nil, nil, errors.New("the catalog config must be a YAML document with a map")
+	}
+
+	root := doc.Content[0]
+	if isNullNode(root) {
+		root = newMappingNode()
+		doc.Content[0] = root
+	}
+
+	var err error
+	node := root
+	for _, key := range []string{"components", "terraform", component, "vars"} {
+		node, err = mappingValue(node, key)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var added []string
+	for _, variable := range variables {
+		if _, existing := findMappingValue(node, variable.Name); existing {
+			continue
+		}
+
+		keyNode, valueNode, err := variableNodes(variable)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		node.Content = append(node.Content, keyNode, valueNode)
+		added = append(added, variable.Name)
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	err = encoder.Encode(&doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = encoder.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return buf.Bytes(), added, nil
+}
+
+// variableNodes returns the YAML key and value nodes for the variable
+func variableNodes(variable Variable) (*yaml.Node, *yaml.Node, error) {
+	keyNode := &yaml.Node{
+		Kind:        yaml.ScalarNode,
+		Tag:         "!!str",
+		Value:       variable.Name,
+		HeadComment: strings.TrimSpace(variable.Description),
+	}
+
+	if variable.Required {
+		return keyNode, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null", LineComment: requiredComment}, nil
+	}
+
+	valueNode := &yaml.Node{}
+	err := valueNode.Encode(variable.Default)
+	if err != nil {
+		return nil, nil, errors.New(fmt.Sprintf("Invalid default value of the variable '%s': %s", variable.Name, err))
+	}
+
+	return keyNode, valueNode, nil
+}
+
+// mappingValue returns the value of the key in the mapping node.
+// If the key does not exist or its value is `null`, an empty mapping is added for the key
+func mappingValue(node *yaml.Node, key string) (*yaml.Node, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, errors.New(fmt.Sprintf("'%s' must be in a map in the catalog config", key))
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != key {
+			continue
+		}
+
+		value := node.Content[i+1]
+		if isNullNode(value) {
+			value = newMappingNode()
+			node.Content[i+1] = value
+		}
+		if value.Kind != yaml.MappingNode {
+			return nil, errors.New(fmt.Sprintf("'%s' must be a map in the catalog config", key))
+		}
+
+		// Add the new keys in the block style
+		value.Style = 0
+		return value, nil
+	}
+
+	value := newMappingNode()
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+	return value, nil
+}
+
+// findMappingValue returns the value of the key in the mapping node
+func findMappingValue(node *yaml.Node, key string) (*yaml.Node, bool) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1], true
+		}
+	}
+	return nil, false
+}
+
+func newMappingNode() *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+}
+
+func isNullNode(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+}
@@ -0,0 +1,71 @@
+package terraform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateCatalog(t *testing.T) {
+	variables := []Variable{
+		{Name: "region", Type: "string", Description: "AWS Region", Required: true},
+		{Name: "zones", Type: "list(string)", Description: "Availability zones", Default: []interface{}{"a", "b"}},
+		{Name: "enabled", Type: "bool", Default: true},
+	}
+
+	content, err := GenerateCatalog("infra/vpc", variables)
+	assert.Nil(t, err)
+	assert.Equal(t, `components:
+  terraform:
+    infra/vpc:
+      vars:
+        # AWS Region
+        region: null # required
+        # Availability zones
+        zones:
+          - a
+          - b
+        enabled: true
+`, string(content))
+
+	existing := `# VPC catalog
+components:
+  terraform:
+    "infra/vpc":
+      settings:
+        spacelift:
+          workspace_enabled: true
+      vars:
+        # Set in the catalog
+        region: us-east-2
+        name: common
+`
+
+	content, added, err := UpdateCatalog([]byte(existing), "infra/vpc", variables)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"zones", "enabled"}, added)
+	assert.Equal(t, `# VPC catalog
+components:
+  terraform:
+    "infra/vpc":
+      settings:
+        spacelift:
+          workspace_enabled: true
+      vars:
+        # Set in the catalog
+        region: us-east-2
+        name: common
+        # Availability zones
+        zones:
+          - a
+          - b
+        enabled: true
+`, string(content))
+
+	content, added, err = UpdateCatalog(content, "infra/vpc", variables)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(added))
+
+	_, _, err = UpdateCatalog([]byte("components: []\n"), "infra/vpc", variables)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,178 @@
+package terraform
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	DriftStatusNoChanges = "no-changes"
+	DriftStatusDrifted   = "drifted"
+	DriftStatusError     = "error"
+
+	// PlanExitCodeChanges is the exit code of `terraform plan -detailed-exitcode` when the plan has changes
+	PlanExitCodeChanges = 2
+)
+
+// DriftResult is the result of the drift detection for a component in a stack
+type DriftResult struct {
+	Stack     string  `yaml:"stack" json:"stack"`
+	Component string  `yaml:"component" json:"component"`
+	Status    string  `yaml:"status" json:"status"`
+	Duration  float64 `yaml:"duration" json:"duration"`
+	Error     string  `yaml:"error,omitempty" json:"error,omitempty"`
+	Output    string  `yaml:"output,omitempty" json:"output,omitempty"`
+}
+
+// DriftReport is the result of the drift detection for all components
+type DriftReport struct {
+	NoChanges int           `yaml:"no_changes" json:"no_changes"`
+	Drifted   int           `yaml:"drifted" json:"drifted"`
+	Errors    int           `yaml:"errors" json:"errors"`
+	Results   []DriftResult `yaml:"results" json:"results"`
+}
+
+// DriftStatus returns the drift status from the exit code of `terraform plan -detailed-exitcode`
+func DriftStatus(exitCode int) string {
+	switch exitCode {
+	case 0:
+		return DriftStatusNoChanges
+	case PlanExitCodeChanges:
+		return DriftStatusDrifted
+	default:
+		return DriftStatusError
+	}
+}
+
+// NewDriftReport returns the report with the results sorted by stack and component
+func NewDriftReport(results []DriftResult) DriftReport {
+	res := DriftReport{Results: append([]DriftResult{}, results...)}
+
+	sort.Slice(res.Results, func(i, j int) bool {
+		if res.Results[i].Stack != res.Results[j].Stack {
+			return res.Results[i].Stack < res.Results[j].Stack
+		}
+		return res.Results[i].Component < res.Results[j].Component
+	})
+
+	for _, r := range res.Results {
+		switch r.Status {
+		case DriftStatusNoChanges:
+			res.NoChanges++
+		case DriftStatusDrifted:
+			res.Drifted++
+		default:
+			res.Errors++
+		}
+	}
+
+	return res
+}
+
+// Totals returns the number of the components by drift status
+func (r DriftReport) Totals() string {
+	return fmt.Sprintf("%d drifted, %d with errors, %d without changes", r.Drifted, r.Errors, r.NoChanges)
+}
+
+// Markdown returns the report in Markdown
+func (r DriftReport) Markdown() string {
+	var buf bytes.Buffer
+	buf.WriteString("### Drift detection\n\n")
+	buf.WriteString(fmt.Sprintf("%s\n\n", r.Totals()))
+	buf.WriteString("| Stack | Component | Status |\n")
+	buf.WriteString("|-------|-----------|--------|\n")
+	for _, result := range r.Results {
+		buf.WriteString(fmt.Sprintf("| `%s` | `%s` | %s |\n", result.Stack, result.Component, result.Status))
+	}
+	return buf.String()
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message  string `xml:"message,attr"`
+	Contents string `xml:",chardata"`
+}
+
+// JUnit returns the report in the JUnit XML format, with a test suite per stack and a test case per component.
+// The drifted components are reported as failures
+func (r DriftReport) JUnit() ([]byte, error) {
+	suites := junitTestSuites{
+		Name:     "atmos terraform drift",
+		Tests:    len(r.Results),
+		Failures: r.Drifted,
+		Errors:   r.Errors,
+	}
+
+	suiteIndexes := map[string]int{}
+	suiteTimes := map[string]float64{}
+
+	for _, result := range r.Results {
+		i, ok := suiteIndexes[result.Stack]
+		if !ok {
+			i = len(suites.Suites)
+			suiteIndexes[result.Stack] = i
+			suites.Suites = append(suites.Suites, junitTestSuite{Name: result.Stack})
+		}
+		suite := &suites.Suites[i]
+
+		testCase := junitTestCase{
+			Name:      result.Component,
+			ClassName: result.Stack,
+			Time:      fmt.Sprintf("%.3f", result.Duration),
+		}
+
+		switch result.Status {
+		case DriftStatusNoChanges:
+		case DriftStatusDrifted:
+			testCase.Failure = &junitMessage{Message: "drift detected", Contents: result.Output}
+			suite.Failures++
+		default:
+			testCase.Error = &junitMessage{Message: result.Error, Contents: result.Output}
+			suite.Errors++
+		}
+
+		suite.Tests++
+		suiteTimes[result.Stack] += result.Duration
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	for i := range suites.Suites {
+		suites.Suites[i].Time = fmt.Sprintf("%.3f", suiteTimes[suites.Suites[i].Name])
+	}
+
+	res, err := xml.MarshalIndent(suites, "", strings.Repeat(" ", 2))
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), append(res, '\n')...), nil
+}
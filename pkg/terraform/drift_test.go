@@ -0,0 +1,36 @@
+package terraform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDriftReport(t *testing.T) {
+	assert.Equal(t, DriftStatusNoChanges, DriftStatus(0))
+	assert.Equal(t, DriftStatusError, DriftStatus(1))
+	assert.Equal(t, DriftStatusDrifted, DriftStatus(PlanExitCodeChanges))
+
+	report := NewDriftReport([]DriftResult{
+		{Stack: "tenant1-ue2-prod", Component: "infra/vpc", Status: DriftStatusNoChanges, Duration: 1},
+		{Stack: "tenant1-ue2-dev", Component: "infra/vpc", Status: DriftStatusDrifted, Duration: 2, Output: "1 to change"},
+		{Stack: "tenant1-ue2-dev", Component: "infra/eks", Status: DriftStatusError, Duration: 0.5, Error: "exit status 1"},
+	})
+
+	assert.Equal(t, 1, report.NoChanges)
+	assert.Equal(t, 1, report.Drifted)
+	assert.Equal(t, 1, report.Errors)
+	assert.Equal(t, "infra/eks", report.Results[0].Component)
+	assert.Equal(t, "tenant1-ue2-prod", report.Results[2].Stack)
+
+	markdown := report.Markdown()
+	assert.Contains(t, markdown, "1 drifted, 1 with errors, 1 without changes\n")
+	assert.Contains(t, markdown, "| `tenant1-ue2-dev` | `infra/vpc` | drifted |\n")
+
+	junit, err := report.JUnit()
+	assert.Nil(t, err)
+	assert.Contains(t, string(junit), `<testsuites name="atmos terraform drift" tests="3" failures="1" errors="1">`)
+	assert.Contains(t, string(junit), `<testsuite name="tenant1-ue2-dev" tests="2" failures="1" errors="1" time="2.500">`)
+	assert.Contains(t, string(junit), `<failure message="drift detected">1 to change</failure>`)
+	assert.Contains(t, string(junit), `<error message="exit status 1"></error>`)
+}
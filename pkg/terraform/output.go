@@ -0,0 +1,61 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Output is an output of a terraform component as returned by `terraform output -json`
+type Output struct {
+	Sensitive bool        `yaml:"sensitive" json:"sensitive"`
+	Type      interface{} `yaml:"type" json:"type"`
+	Value     interface{} `yaml:"value" json:"value"`
+}
+
+// ParseOutputs parses the output of `terraform output -json`
+func ParseOutputs(data []byte) (map[string]Output, error) {
+	res := map[string]Output{}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return res, nil
+	}
+
+	err := json.Unmarshal(data, &res)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("invalid 'terraform output -json' output: %s", err))
+	}
+	return res, nil
+}
+
+// OutputValues returns the values of the outputs
+func OutputValues(outputs map[string]Output) map[string]interface{} {
+	res := make(map[string]interface{}, len(outputs))
+	for name, output := range outputs {
+		res[name] = output.Value
+	}
+	return res
+}
+
+// OutputValue returns the value of the output. The path can point to a value in a map output, e.g. `vpc.id`
+func OutputValue(outputs map[string]Output, path string) (interface{}, error) {
+	keys := strings.Split(path, ".")
+
+	output, ok := outputs[keys[0]]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("the output '%s' does not exist", keys[0]))
+	}
+
+	v := output.Value
+	for _, key := range keys[1:] {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("the output '%s' does not have the key '%s'", path, key))
+		}
+		if v, ok = m[key]; !ok {
+			return nil, errors.New(fmt.Sprintf("the output '%s' does not have the key '%s'", path, key))
+		}
+	}
+	return v, nil
+}
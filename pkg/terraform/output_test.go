@@ -0,0 +1,41 @@
+package terraform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOutputs(t *testing.T) {
+	outputs, err := ParseOutputs([]byte(`{
+  "vpc_id": {"sensitive": false, "type": "string", "value": "vpc-123"},
+  "subnets": {"sensitive": false, "type": ["object", {}], "value": {"private": ["subnet-1", "subnet-2"]}},
+  "db_password": {"sensitive": true, "type": "string", "value": "hunter22"}
+}`))
+	assert.Nil(t, err)
+	assert.True(t, outputs["db_password"].Sensitive)
+
+	v, err := OutputValue(outputs, "vpc_id")
+	assert.Nil(t, err)
+	assert.Equal(t, "vpc-123", v)
+
+	v, err = OutputValue(outputs, "subnets.private")
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"subnet-1", "subnet-2"}, v)
+
+	_, err = OutputValue(outputs, "subnets.public")
+	assert.NotNil(t, err)
+
+	_, err = OutputValue(outputs, "does_not_exist")
+	assert.NotNil(t, err)
+
+	assert.Equal(t, "vpc-123", OutputValues(outputs)["vpc_id"])
+
+	// A component without outputs
+	outputs, err = ParseOutputs([]byte("{}\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(outputs))
+
+	_, err = ParseOutputs([]byte("Warning: No outputs found"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,144 @@
+package terraform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	PlanActionCreate  = "create"
+	PlanActionUpdate  = "update"
+	PlanActionDelete  = "delete"
+	PlanActionReplace = "replace"
+)
+
+// PlanResourceChange is a change of a resource in a terraform plan
+type PlanResourceChange struct {
+	Address string `yaml:"address" json:"address"`
+	Action  string `yaml:"action" json:"action"`
+}
+
+// PlanSummary is the summary of the resource changes in a terraform plan
+type PlanSummary struct {
+	Component string               `yaml:"component" json:"component"`
+	Stack     string               `yaml:"stack" json:"stack"`
+	Create    int                  `yaml:"create" json:"create"`
+	Update    int                  `yaml:"update" json:"update"`
+	Replace   int                  `yaml:"replace" json:"replace"`
+	Delete    int                  `yaml:"delete" json:"delete"`
+	Changes   []PlanResourceChange `yaml:"changes" json:"changes"`
+}
+
+// planJSON is the part of the `terraform show -json <planfile>` output used in the plan summary
+type planJSON struct {
+	ResourceChanges []struct {
+		Address string `json:"address"`
+		Change  struct {
+			Actions []string `json:"actions"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+}
+
+// ShowPlan runs `terraform show -json` on the planfile in the component folder and returns the summary of the plan
+func ShowPlan(command string, componentPath string, planFile string, env []string) (PlanSummary, error) {
+	cmd := exec.Command(command, "show", "-json", planFile)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Dir = componentPath
+	cmd.Stderr = os.Stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return PlanSummary{}, errors.New(fmt.Sprintf("Error running '%s show -json %s': %s", command, planFile, err))
+	}
+
+	return ParsePlan(output)
+}
+
+// ParsePlan returns the summary of the plan from the `terraform show -json <planfile>` output.
+// The `no-op` and `read` changes are not included
+func ParsePlan(content []byte) (PlanSummary, error) {
+	var plan planJSON
+	err := json.Unmarshal(content, &plan)
+	if err != nil {
+		return PlanSummary{}, errors.New(fmt.Sprintf("Invalid terraform plan JSON: %s", err))
+	}
+
+	res := PlanSummary{Changes: []PlanResourceChange{}}
+
+	for _, rc := range plan.ResourceChanges {
+		var action string
+		switch strings.Join(rc.Change.Actions, ",") {
+		case "create":
+			action = PlanActionCreate
+			res.Create++
+		case "update":
+			action = PlanActionUpdate
+			res.Update++
+		case "delete":
+			action = PlanActionDelete
+			res.Delete++
+		case "delete,create", "create,delete":
+			action = PlanActionReplace
+			res.Replace++
+		default:
+			continue
+		}
+		res.Changes = append(res.Changes, PlanResourceChange{Address: rc.Address, Action: action})
+	}
+
+	return res, nil
+}
+
+// HasChanges checks if the plan creates, updates, replaces or deletes any resources
+func (s PlanSummary) HasChanges() bool {
+	return len(s.Changes) > 0
+}
+
+// Totals returns the number of the resource changes by action, in the format of the terraform plan output
+func (s PlanSummary) Totals() string {
+	return fmt.Sprintf("%d to create, %d to update, %d to replace, %d to delete", s.Create, s.Update, s.Replace, s.Delete)
+}
+
+// Table returns the resource changes as a table for the console
+func (s PlanSummary) Table() string {
+	if !s.HasChanges() {
+		return "No changes.\n"
+	}
+
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 3, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ACTION\tRESOURCE")
+	for _, change := range s.Changes {
+		_, _ = fmt.Fprintf(w, "%s\t%s\n", change.Action, change.Address)
+	}
+	_ = w.Flush()
+
+	buf.WriteString(fmt.Sprintf("\nPlan: %s\n", s.Totals()))
+	return buf.String()
+}
+
+// Markdown returns the summary of the plan in Markdown (e.g. for pull request comments)
+func (s PlanSummary) Markdown() string {
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("### Plan for `%s` in `%s`\n\n", s.Component, s.Stack))
+
+	if !s.HasChanges() {
+		buf.WriteString("No changes.\n")
+		return buf.String()
+	}
+
+	buf.WriteString(fmt.Sprintf("%s\n\n", s.Totals()))
+	buf.WriteString("| Action | Resource |\n")
+	buf.WriteString("|--------|----------|\n")
+	for _, change := range s.Changes {
+		buf.WriteString(fmt.Sprintf("| %s | `%s` |\n", change.Action, change.Address))
+	}
+	return buf.String()
+}
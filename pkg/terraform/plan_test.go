@@ -0,0 +1,84 @@
+package terraform
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const planFixture = `{
+  "format_version": "0.2",
+  "resource_changes": [
+    {"address": "aws_vpc.default", "change": {"actions": ["create"]}},
+    {"address": "aws_subnet.private[0]", "change": {"actions": ["update"]}},
+    {"address": "aws_subnet.public[0]", "change": {"actions": ["delete", "create"]}},
+    {"address": "aws_eip.nat", "change": {"actions": ["create", "delete"]}},
+    {"address": "aws_route_table.default", "change": {"actions": ["delete"]}},
+    {"address": "aws_internet_gateway.default", "change": {"actions": ["no-op"]}},
+    {"address": "data.aws_availability_zones.available", "change": {"actions": ["read"]}}
+  ]
+}`
+
+// writeStubTerraform writes a script which prints the fixture for `terraform show -json <planfile>`
+func writeStubTerraform(t *testing.T, fixture string) string {
+	dir := t.TempDir()
+	fixtureFile := path.Join(dir, "plan.json")
+	err := ioutil.WriteFile(fixtureFile, []byte(fixture), 0644)
+	assert.Nil(t, err)
+
+	script := "#!/bin/sh\n" +
+		"if [ \"$1\" = \"show\" ] && [ \"$2\" = \"-json\" ] && [ -f \"$3\" ]; then cat " + fixtureFile + "; exit 0; fi\n" +
+		"echo \"unexpected arguments: $@\" >&2\nexit 1\n"
+	command := path.Join(dir, "terraform")
+	err = ioutil.WriteFile(command, []byte(script), 0755)
+	assert.Nil(t, err)
+	return command
+}
+
+func TestShowPlan(t *testing.T) {
+	command := writeStubTerraform(t, planFixture)
+
+	componentPath := t.TempDir()
+	err := ioutil.WriteFile(path.Join(componentPath, "tenant1-ue2-dev-vpc.planfile"), []byte{}, 0644)
+	assert.Nil(t, err)
+
+	summary, err := ShowPlan(command, componentPath, "tenant1-ue2-dev-vpc.planfile", nil)
+	assert.Nil(t, err)
+	assert.True(t, summary.HasChanges())
+	assert.Equal(t, 1, summary.Create)
+	assert.Equal(t, 1, summary.Update)
+	assert.Equal(t, 2, summary.Replace)
+	assert.Equal(t, 1, summary.Delete)
+	assert.Equal(t, 5, len(summary.Changes))
+	assert.Equal(t, PlanResourceChange{Address: "aws_subnet.public[0]", Action: PlanActionReplace}, summary.Changes[2])
+
+	assert.Equal(t, "ACTION    RESOURCE\n"+
+		"create    aws_vpc.default\n"+
+		"update    aws_subnet.private[0]\n"+
+		"replace   aws_subnet.public[0]\n"+
+		"replace   aws_eip.nat\n"+
+		"delete    aws_route_table.default\n"+
+		"\nPlan: 1 to create, 1 to update, 2 to replace, 1 to delete\n", summary.Table())
+
+	summary.Component = "infra/vpc"
+	summary.Stack = "tenant1-ue2-dev"
+	markdown := summary.Markdown()
+	assert.Contains(t, markdown, "### Plan for `infra/vpc` in `tenant1-ue2-dev`\n")
+	assert.Contains(t, markdown, "| replace | `aws_eip.nat` |\n")
+
+	// The planfile does not exist
+	_, err = ShowPlan(command, componentPath, "tenant1-ue2-prod-vpc.planfile", nil)
+	assert.NotNil(t, err)
+}
+
+func TestParsePlanNoChanges(t *testing.T) {
+	summary, err := ParsePlan([]byte(`{"resource_changes": [{"address": "aws_vpc.default", "change": {"actions": ["no-op"]}}]}`))
+	assert.Nil(t, err)
+	assert.False(t, summary.HasChanges())
+	assert.Equal(t, "No changes.\n", summary.Table())
+
+	_, err = ParsePlan([]byte("Error: no planfile"))
+	assert.NotNil(t, err)
+}
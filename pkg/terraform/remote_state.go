@@ -0,0 +1,117 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/pkg/errors"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// StaticBackendType is the remote state backend type which reads the outputs from the `remote_state_backend.static` section
+// instead of a terraform state. It can't be used in the `terraform_remote_state` data source
+const StaticBackendType = "static"
+
+// RemoteState is the config to read the terraform state of a component in a stack
+type RemoteState struct {
+	BackendType string                 `yaml:"backend_type" json:"backend_type"`
+	Backend     map[string]interface{} `yaml:"backend" json:"backend"`
+	Workspace   string                 `yaml:"workspace" json:"workspace"`
+}
+
+// DataSourceName returns the name of the `terraform_remote_state` data source for the component (e.g. `infra-vpc` for `infra/vpc`)
+func DataSourceName(component string) string {
+	return strings.Replace(component, "/", "-", -1)
+}
+
+// RemoteStateDataSource returns the `terraform_remote_state` data source in the terraform JSON format
+func RemoteStateDataSource(name string, remoteState RemoteState) (map[string]interface{}, error) {
+	if err := checkDataSourceBackendType(remoteState.BackendType); err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"data": map[string]interface{}{
+			"terraform_remote_state": map[string]interface{}{
+				name: map[string]interface{}{
+					"backend":   remoteState.BackendType,
+					"workspace": remoteState.Workspace,
+					"config":    remoteState.Backend,
+				},
+			},
+		},
+	}, nil
+}
+
+// RemoteStateDataSourceHCL returns the `terraform_remote_state` data source in HCL
+func RemoteStateDataSourceHCL(name string, remoteState RemoteState) ([]byte, error) {
+	if err := checkDataSourceBackendType(remoteState.BackendType); err != nil {
+		return nil, err
+	}
+
+	// Convert the backend config to a `cty` value through JSON to get the object and tuple types of the nested values
+	backend := remoteState.Backend
+	if backend == nil {
+		backend = map[string]interface{}{}
+	}
+	configJSON, err := json.Marshal(backend)
+	if err != nil {
+		return nil, err
+	}
+	configType, err := ctyjson.ImpliedType(configJSON)
+	if err != nil {
+		return nil, err
+	}
+	config, err := ctyjson.Unmarshal(configJSON, configType)
+	if err != nil {
+		return nil, err
+	}
+
+	file := hclwrite.NewEmptyFile()
+	block := file.Body().AppendNewBlock("data", []string{"terraform_remote_state", name})
+	body := block.Body()
+	body.SetAttributeValue("backend", cty.StringVal(remoteState.BackendType))
+	body.SetAttributeValue("workspace", cty.StringVal(remoteState.Workspace))
+	body.AppendUnstructuredTokens(multilineObjectAttributeTokens("config", config))
+
+	return hclwrite.Format(file.Bytes()), nil
+}
+
+// multilineObjectAttributeTokens returns the tokens of the attribute with the object value with each top-level attribute on a separate line.
+// `hclwrite` writes the object values on one line
+func multilineObjectAttributeTokens(name string, value cty.Value) hclwrite.Tokens {
+	tokens := hclwrite.Tokens{
+		{Type: hclsyntax.TokenIdent, Bytes: []byte(name)},
+		{Type: hclsyntax.TokenEqual, Bytes: []byte("=")},
+		{Type: hclsyntax.TokenOBrace, Bytes: []byte("{")},
+		{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")},
+	}
+
+	for it := value.ElementIterator(); it.Next(); {
+		key, v := it.Element()
+		keyTokens := hclwrite.TokensForValue(key)
+		if hclsyntax.ValidIdentifier(key.AsString()) {
+			keyTokens = hclwrite.Tokens{{Type: hclsyntax.TokenIdent, Bytes: []byte(key.AsString())}}
+		}
+		tokens = append(tokens, keyTokens...)
+		tokens = append(tokens, &hclwrite.Token{Type: hclsyntax.TokenEqual, Bytes: []byte("=")})
+		tokens = append(tokens, hclwrite.TokensForValue(v)...)
+		tokens = append(tokens, &hclwrite.Token{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")})
+	}
+
+	return append(tokens,
+		&hclwrite.Token{Type: hclsyntax.TokenCBrace, Bytes: []byte("}")},
+		&hclwrite.Token{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")},
+	)
+}
+
+func checkDataSourceBackendType(backendType string) error {
+	if backendType == StaticBackendType {
+		return errors.New(fmt.Sprintf("the '%s' remote state backend can't be used in the 'terraform_remote_state' data source", StaticBackendType))
+	}
+	return nil
+}
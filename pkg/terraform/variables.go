@@ -0,0 +1,115 @@
+package terraform
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/terraform-config-inspect/tfconfig"
+	"github.com/pkg/errors"
+)
+
+// Variable is a `variable` block declared in a terraform component
+type Variable struct {
+	Name        string      `yaml:"name" json:"name"`
+	Type        string      `yaml:"type,omitempty" json:"type,omitempty"`
+	Description string      `yaml:"description,omitempty" json:"description,omitempty"`
+	Default     interface{} `yaml:"default" json:"default"`
+	Required    bool        `yaml:"required" json:"required"`
+	Sensitive   bool        `yaml:"sensitive,omitempty" json:"sensitive,omitempty"`
+	File        string      `yaml:"file" json:"file"`
+	Line        int         `yaml:"line" json:"line"`
+}
+
+// LoadVariables parses the `*.tf` files in the component folder and returns the declared variables
+// in the order they are declared (sorted by file name and line)
+func LoadVariables(componentPath string) ([]Variable, error) {
+	if !tfconfig.IsModuleDir(componentPath) {
+		return nil, errors.New(fmt.Sprintf("'%s' is not a terraform component folder", componentPath))
+	}
+
+	module, diags := tfconfig.LoadModule(componentPath)
+	if diags.HasErrors() {
+		return nil, errors.New(fmt.Sprintf("Error parsing the terraform component '%s': %s", componentPath, diags.Error()))
+	}
+
+	var res []Variable
+	for _, v := range module.Variables {
+		res = append(res, Variable{
+			Name:        v.Name,
+			Type:        v.Type,
+			Description: v.Description,
+			Default:     v.Default,
+			Required:    v.Required,
+			Sensitive:   v.Sensitive,
+			File:        v.Pos.Filename,
+			Line:        v.Pos.Line,
+		})
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].File != res[j].File {
+			return res[i].File < res[j].File
+		}
+		return res[i].Line < res[j].Line
+	})
+
+	return res, nil
+}
+
+// AutoLoadedVariables returns the names of the variables set in the `terraform.tfvars`, `terraform.tfvars.json`,
+// `*.auto.tfvars` and `*.auto.tfvars.json` files in the component folder, which terraform loads automatically
+func AutoLoadedVariables(componentPath string) ([]string, error) {
+	files, err := ioutil.ReadDir(componentPath)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := hclparse.NewParser()
+	names := map[string]bool{}
+
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() {
+			continue
+		}
+
+		isJSON := name == "terraform.tfvars.json" || strings.HasSuffix(name, ".auto.tfvars.json")
+		isHCL := name == "terraform.tfvars" || strings.HasSuffix(name, ".auto.tfvars")
+		if !isJSON && !isHCL {
+			continue
+		}
+
+		filePath := filepath.Join(componentPath, name)
+
+		var file *hcl.File
+		var diags hcl.Diagnostics
+		if isJSON {
+			file, diags = parser.ParseJSONFile(filePath)
+		} else {
+			file, diags = parser.ParseHCLFile(filePath)
+		}
+		if diags.HasErrors() {
+			return nil, errors.New(fmt.Sprintf("Error parsing '%s': %s", filePath, diags.Error()))
+		}
+
+		attributes, diags := file.Body.JustAttributes()
+		if diags.HasErrors() {
+			return nil, errors.New(fmt.Sprintf("Error parsing '%s': %s", filePath, diags.Error()))
+		}
+		for attribute := range attributes {
+			names[attribute] = true
+		}
+	}
+
+	var res []string
+	for name := range names {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res, nil
+}
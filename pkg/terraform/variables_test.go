@@ -0,0 +1,41 @@
+package terraform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadVariables(t *testing.T) {
+	variables, err := LoadVariables("../../examples/complete/components/terraform/infra/vpc")
+	assert.Nil(t, err)
+
+	declared := map[string]Variable{}
+	for _, variable := range variables {
+		declared[variable.Name] = variable
+	}
+
+	assert.Equal(t, "string", declared["region"].Type)
+	assert.Equal(t, "AWS Region", declared["region"].Description)
+	assert.True(t, declared["region"].Required)
+	assert.Equal(t, "list(string)", declared["availability_zones"].Type)
+	assert.False(t, declared["availability_zones"].Required)
+	assert.Equal(t, "t3.micro", declared["nat_instance_type"].Default)
+
+	// The variables are sorted by file and line
+	assert.Contains(t, variables[0].File, "context.tf")
+	for i := 1; i < len(variables); i++ {
+		if variables[i].File == variables[i-1].File {
+			assert.Greater(t, variables[i].Line, variables[i-1].Line)
+		}
+	}
+
+	_, err = LoadVariables("../../examples/complete/stacks")
+	assert.NotNil(t, err)
+}
+
+func TestAutoLoadedVariables(t *testing.T) {
+	names, err := AutoLoadedVariables("../../examples/complete/components/terraform/infra/vpc")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"enabled"}, names)
+}
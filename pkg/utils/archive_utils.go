@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ArchiveFS reads a `.zip`, `.tar.gz` (`.tgz`) or `.tar` archive into memory and returns it as a read-only filesystem.
+// The paths in the filesystem are the paths of the files in the archive
+func ArchiveFS(archivePath string) (fs.FS, error) {
+	content, err := ioutil.ReadFile(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return ArchiveFSFromBytes(archivePath, content)
+}
+
+// ArchiveFSFromBytes returns the content of a `.zip`, `.tar.gz` (`.tgz`) or `.tar` archive as a read-only filesystem.
+// The archive type is detected from the extension of `archivePath`
+func ArchiveFSFromBytes(archivePath string, content []byte) (fs.FS, error) {
+	lowerArchivePath := strings.ToLower(archivePath)
+
+	switch {
+	case strings.HasSuffix(lowerArchivePath, ".zip"):
+		return zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	case strings.HasSuffix(lowerArchivePath, ".tar.gz"), strings.HasSuffix(lowerArchivePath, ".tgz"):
+		gzipReader, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+		defer gzipReader.Close()
+		return tarToFS(gzipReader)
+	case strings.HasSuffix(lowerArchivePath, ".tar"):
+		return tarToFS(bytes.NewReader(content))
+	default:
+		return nil, errors.New(fmt.Sprintf("Unsupported archive '%s'. Supported archive types are '.zip', '.tar.gz', '.tgz' and '.tar'", archivePath))
+	}
+}
+
+// tarToFS converts a tar archive to an in-memory zip archive (which implements `fs.FS`)
+func tarToFS(reader io.Reader) (fs.FS, error) {
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+	tarReader := tar.NewReader(reader)
+
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := strings.TrimPrefix(path.Clean(header.Name), "./")
+		if name == "." || !fs.ValidPath(name) {
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if _, err = zipWriter.Create(name + "/"); err != nil {
+				return nil, err
+			}
+		case tar.TypeReg:
+			writer, err := zipWriter.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: header.ModTime})
+			if err != nil {
+				return nil, err
+			}
+			if _, err = io.Copy(writer, tarReader); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return nil, err
+	}
+
+	return zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+}
+
+// ExtractFS copies all files from the filesystem to the directory
+func ExtractFS(fsys fs.FS, dir string) error {
+	return fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(p))
+
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+
+		content, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+
+		err = os.MkdirAll(filepath.Dir(target), 0755)
+		if err != nil {
+			return err
+		}
+
+		return ioutil.WriteFile(target, content, 0644)
+	})
+}
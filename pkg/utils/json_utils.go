@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
 	jsoniter "github.com/json-iterator/go"
 	"io/ioutil"
@@ -32,3 +33,12 @@ func WriteToFileAsJSON(filePath string, data interface{}, fileMode os.FileMode)
 	}
 	return nil
 }
+
+// ConvertToJSON converts the provided value to an indented JSON document with the map keys sorted
+func ConvertToJSON(data interface{}) ([]byte, error) {
+	j, err := json.MarshalIndent(data, "", strings.Repeat(" ", 2))
+	if err != nil {
+		return nil, err
+	}
+	return append(j, '\n'), nil
+}
@@ -17,6 +17,15 @@ func PrintAsYAML(data interface{}) error {
 	return nil
 }
 
+// ConvertToYAML converts the provided value to a YAML document
+func ConvertToYAML(data interface{}) (string, error) {
+	y, err := yaml.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return string(y), nil
+}
+
 // WriteToFileAsYAML converts the provided value to YAML and writes it to the provided file
 func WriteToFileAsYAML(filePath string, data interface{}, fileMode os.FileMode) error {
 	y, err := yaml.Marshal(data)
@@ -0,0 +1,227 @@
+package validate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cloudposse/atmos/pkg/atmos"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/pkg/errors"
+)
+
+const (
+	// PolicyPackage is the package of the Rego policies. The policies define `deny` and `warn` rules which return the violation messages
+	PolicyPackage = "atmos"
+
+	// PolicyLevelDeny is the level of the violations returned by the `deny` rules
+	PolicyLevelDeny = "deny"
+
+	// PolicyLevelWarn is the level of the violations returned by the `warn` rules
+	PolicyLevelWarn = "warn"
+)
+
+// PolicyViolation is a message returned by a `deny` or `warn` rule for a component in a stack
+type PolicyViolation struct {
+	Stack     string `yaml:"stack" json:"stack"`
+	Component string `yaml:"component" json:"component"`
+	Level     string `yaml:"level" json:"level"`
+	Message   string `yaml:"message" json:"message"`
+}
+
+func (p PolicyViolation) Error() string {
+	return fmt.Sprintf("%s: stack '%s', component '%s': %s", p.Level, p.Stack, p.Component, p.Message)
+}
+
+// PolicyEngine evaluates the Rego policies from a directory against the components.
+// The policies are compiled once, so one policy engine should be used to evaluate all components
+type PolicyEngine struct {
+	policiesBasePath string
+
+	prepareOnce sync.Once
+	query       rego.PreparedEvalQuery
+	policies    []string
+	prepareErr  error
+}
+
+// NewPolicyEngine creates a policy engine which evaluates all `.rego` files (except `_test.rego` files) in the `policiesBasePath` directory.
+// If the directory does not exist, there are no policies to evaluate
+func NewPolicyEngine(policiesBasePath string) *PolicyEngine {
+	return &PolicyEngine{
+		policiesBasePath: policiesBasePath,
+	}
+}
+
+// Policies returns the policy files evaluated by the policy engine
+func (p *PolicyEngine) Policies() ([]string, error) {
+	err := p.prepare()
+	if err != nil {
+		return nil, err
+	}
+	return p.policies, nil
+}
+
+// PolicyInput returns the input of the policies for the component.
+// It's the final component config with the component and stack names
+func PolicyInput(component atmos.Component) (map[string]interface{}, error) {
+	// The policy engine accepts only the values returned by the JSON decoder
+	j, err := json.Marshal(component.Config.ToMap())
+	if err != nil {
+		return nil, err
+	}
+	var input map[string]interface{}
+	err = json.Unmarshal(j, &input)
+	if err != nil {
+		return nil, err
+	}
+
+	input["atmos_component"] = component.Name
+	input["atmos_component_type"] = component.Type
+	input["atmos_stack"] = component.Stack
+	input["atmos_stack_file"] = component.StackFile
+	input["workspace"] = component.Workspace
+
+	return input, nil
+}
+
+// EvaluateComponent evaluates the policies against the component and returns the `deny` and `warn` violations
+func (p *PolicyEngine) EvaluateComponent(component atmos.Component) ([]PolicyViolation, error) {
+	err := p.prepare()
+	if err != nil {
+		return nil, err
+	}
+	if len(p.policies) == 0 {
+		return nil, nil
+	}
+
+	input, err := PolicyInput(component)
+	if err != nil {
+		return nil, err
+	}
+
+	resultSet, err := p.query.Eval(context.Background(), rego.EvalInput(input))
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error evaluating the policies for the component '%s' in the stack '%s': %s",
+			component.Name, component.Stack, err))
+	}
+
+	var res []PolicyViolation
+	if len(resultSet) == 0 || len(resultSet[0].Expressions) == 0 {
+		return res, nil
+	}
+
+	rules, ok := resultSet[0].Expressions[0].Value.(map[string]interface{})
+	if !ok {
+		return res, nil
+	}
+
+	for _, level := range []string{PolicyLevelDeny, PolicyLevelWarn} {
+		for _, message := range policyMessages(rules[level]) {
+			res = append(res, PolicyViolation{
+				Stack:     component.Stack,
+				Component: component.Name,
+				Level:     level,
+				Message:   message,
+			})
+		}
+	}
+
+	return res, nil
+}
+
+// HasDenyViolations checks if any of the violations is returned by a `deny` rule
+func HasDenyViolations(violations []PolicyViolation) bool {
+	for _, violation := range violations {
+		if violation.Level == PolicyLevelDeny {
+			return true
+		}
+	}
+	return false
+}
+
+// prepare reads and compiles the policies
+func (p *PolicyEngine) prepare() error {
+	p.prepareOnce.Do(func() {
+		p.policies, p.prepareErr = findPolicies(p.policiesBasePath)
+		if p.prepareErr != nil || len(p.policies) == 0 {
+			return
+		}
+
+		options := []func(r *rego.Rego){rego.Query("data." + PolicyPackage)}
+		for _, policy := range p.policies {
+			content, err := ioutil.ReadFile(policy)
+			if err != nil {
+				p.prepareErr = err
+				return
+			}
+			options = append(options, rego.Module(policy, string(content)))
+		}
+
+		p.query, p.prepareErr = rego.New(options...).PrepareForEval(context.Background())
+		if p.prepareErr != nil {
+			p.prepareErr = errors.New(fmt.Sprintf("Invalid policies in '%s': %s", p.policiesBasePath, p.prepareErr))
+		}
+	})
+	return p.prepareErr
+}
+
+// findPolicies returns the sorted paths of the `.rego` files in the directory
+func findPolicies(dir string) ([]string, error) {
+	if len(dir) == 0 {
+		return nil, nil
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var res []string
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.HasSuffix(p, ".rego") && !strings.HasSuffix(p, "_test.rego") {
+			res = append(res, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(res)
+	return res, nil
+}
+
+// policyMessages returns the messages of a `deny` or `warn` rule.
+// The rules can return strings or objects with a `msg` attribute
+func policyMessages(rule interface{}) []string {
+	values, ok := rule.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var res []string
+	for _, value := range values {
+		switch v := value.(type) {
+		case string:
+			res = append(res, v)
+		case map[string]interface{}:
+			if msg, ok := v["msg"].(string); ok {
+				res = append(res, msg)
+			} else {
+				res = append(res, fmt.Sprintf("%v", v))
+			}
+		default:
+			res = append(res, fmt.Sprintf("%v", v))
+		}
+	}
+
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,103 @@
+package validate
+
+import (
+	"sort"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json"
+)
+
+// SARIFLog is a SARIF 2.1.0 report (https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html) with the issues found in the stacks
+type SARIFLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []SARIFRun `json:"runs"`
+}
+
+type SARIFRun struct {
+	Tool    SARIFTool     `json:"tool"`
+	Results []SARIFResult `json:"results"`
+}
+
+type SARIFTool struct {
+	Driver SARIFDriver `json:"driver"`
+}
+
+type SARIFDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []SARIFRule `json:"rules"`
+}
+
+type SARIFRule struct {
+	ID               string       `json:"id"`
+	ShortDescription SARIFMessage `json:"shortDescription"`
+}
+
+type SARIFResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   SARIFMessage    `json:"message"`
+	Locations []SARIFLocation `json:"locations"`
+}
+
+type SARIFMessage struct {
+	Text string `json:"text"`
+}
+
+type SARIFLocation struct {
+	PhysicalLocation SARIFPhysicalLocation `json:"physicalLocation"`
+}
+
+type SARIFPhysicalLocation struct {
+	ArtifactLocation SARIFArtifactLocation `json:"artifactLocation"`
+}
+
+type SARIFArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// ToSARIF converts the issues to a SARIF report
+func ToSARIF(issues []Issue) SARIFLog {
+	var ruleIDs []string
+	for ruleID := range Rules {
+		ruleIDs = append(ruleIDs, ruleID)
+	}
+	sort.Strings(ruleIDs)
+
+	var rules []SARIFRule
+	for _, ruleID := range ruleIDs {
+		rules = append(rules, SARIFRule{ID: ruleID, ShortDescription: SARIFMessage{Text: Rules[ruleID]}})
+	}
+
+	results := []SARIFResult{}
+	for _, issue := range issues {
+		results = append(results, SARIFResult{
+			RuleID:  issue.Rule,
+			Level:   issue.Level,
+			Message: SARIFMessage{Text: issue.Message},
+			Locations: []SARIFLocation{
+				{PhysicalLocation: SARIFPhysicalLocation{ArtifactLocation: SARIFArtifactLocation{URI: issue.File}}},
+			},
+		})
+	}
+
+	return SARIFLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs: []SARIFRun{
+			{
+				Tool: SARIFTool{
+					Driver: SARIFDriver{
+						Name:           "atmos",
+						InformationURI: "https://github.com/cloudposse/atmos",
+						Rules:          rules,
+					},
+				},
+				Results: results,
+			},
+		},
+	}
+}
@@ -0,0 +1,476 @@
+package validate
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cloudposse/atmos/pkg/atmos"
+	c "github.com/cloudposse/atmos/pkg/config"
+	"github.com/cloudposse/atmos/pkg/convert"
+	g "github.com/cloudposse/atmos/pkg/globals"
+	s "github.com/cloudposse/atmos/pkg/stack"
+	tf "github.com/cloudposse/atmos/pkg/terraform"
+	u "github.com/cloudposse/atmos/pkg/utils"
+)
+
+const (
+	// LevelError is the level of the issues which make the stacks invalid
+	LevelError = "error"
+
+	// LevelWarning is the level of the issues which are reported only
+	LevelWarning = "warning"
+
+	RuleInvalidStack           = "invalid-stack"
+	RuleUnknownKey             = "unknown-key"
+	RuleMissingBaseComponent   = "missing-base-component"
+	RuleMissingComponentFolder = "missing-component-folder"
+	RuleMissingBackend         = "missing-backend"
+	RuleInvalidStackName       = "invalid-stack-name"
+	RuleDuplicateStack         = "duplicate-stack"
+	RuleUnusedFile             = "unused-file"
+	RuleJSONSchema             = "jsonschema"
+	RuleUndeclaredVar          = "undeclared-var"
+	RuleMissingRequiredVar     = "missing-required-var"
+	RuleVarTypeMismatch        = "var-type-mismatch"
+)
+
+var (
+	// Rules describes the rules checked by `LintStacks` and `ValidateComponentVars`
+	Rules = map[string]string{
+		RuleInvalidStack:           "The stack config file can't be processed",
+		RuleUnknownKey:             "The stack config file has an unknown top-level section",
+		RuleMissingBaseComponent:   "The component inherits from a base component which is not defined in the stack",
+		RuleMissingComponentFolder: "The folder of the component does not exist in the terraform or helmfile base path",
+		RuleMissingBackend:         "The backend config of the component does not have the fields required by the backend type",
+		RuleInvalidStackName:       "The stack does not resolve to a name using the stack name pattern",
+		RuleDuplicateStack:         "More than one stack config file resolves to the same logical stack",
+		RuleUnusedFile:             "The config file is not a stack and it's not imported by any stack",
+		RuleJSONSchema:             "The component does not pass the JSON Schema validation from its 'settings.validation' section",
+		RuleUndeclaredVar:          "The stack passes a var which is not declared as a variable in the terraform component",
+		RuleMissingRequiredVar:     "A required variable of the terraform component has no value in the stack",
+		RuleVarTypeMismatch:        "The type of the var does not match the type of the variable in the terraform component",
+	}
+
+	// stackConfigSections are the allowed top-level sections of the stack config files
+	stackConfigSections = []string{"import", "vars", "settings", "env", "providers", "terraform", "helmfile", "components"}
+
+	componentTypes = []string{"terraform", "helmfile"}
+)
+
+// Issue is a problem found in the stack config files
+type Issue struct {
+	Rule      string `yaml:"rule" json:"rule"`
+	Level     string `yaml:"level" json:"level"`
+	File      string `yaml:"file" json:"file"`
+	Stack     string `yaml:"stack,omitempty" json:"stack,omitempty"`
+	Component string `yaml:"component,omitempty" json:"component,omitempty"`
+	Message   string `yaml:"message" json:"message"`
+}
+
+func (i Issue) Error() string {
+	return fmt.Sprintf("%s: %s: %s [%s]", i.Level, i.File, i.Message, i.Rule)
+}
+
+// HasErrors checks if any of the issues has the `error` level
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Level == LevelError {
+			return true
+		}
+	}
+	return false
+}
+
+// stacksLinter holds the state of `LintStacks`
+type stacksLinter struct {
+	client     *atmos.Client
+	fileSystem *s.FileSystem
+	config     c.Configuration
+	processed  c.ProcessedConfiguration
+	validator  *Validator
+
+	issues []Issue
+	// Files imported by any stack (relative to the stacks base path, without extension)
+	imported map[string]bool
+	// importsIncomplete is set if the imports of a stack could not be processed, so not all imported files are known
+	importsIncomplete bool
+	// Stack config files for each logical stack name
+	logicalStacks map[string][]string
+}
+
+// LintStacks checks the structure of the whole stack tree and validates all components with the JSON Schemas from their `settings.validation` sections.
+// Every stack config file is processed separately, so the problems in one stack do not prevent checking the others
+func LintStacks(client *atmos.Client) ([]Issue, error) {
+	l := stacksLinter{
+		client:        client,
+		fileSystem:    client.FileSystem(),
+		config:        client.Config(),
+		processed:     client.ProcessedConfig(),
+		validator:     NewValidator(client.ProcessedConfig().SchemasDirAbsolutePath),
+		imported:      map[string]bool{},
+		logicalStacks: map[string][]string{},
+	}
+
+	for _, stackFile := range l.processed.StackConfigFilesAbsolutePaths {
+		err := l.lintStack(stackFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var logicalStacks []string
+	for logicalStack := range l.logicalStacks {
+		logicalStacks = append(logicalStacks, logicalStack)
+	}
+	sort.Strings(logicalStacks)
+
+	for _, logicalStack := range logicalStacks {
+		stackFiles := l.logicalStacks[logicalStack]
+		if len(stackFiles) < 2 {
+			continue
+		}
+		for _, stackFile := range stackFiles {
+			l.add(Issue{
+				Rule:  RuleDuplicateStack,
+				Level: LevelError,
+				File:  l.displayPath(stackFile),
+				Stack: l.stackName(stackFile),
+				Message: fmt.Sprintf("the logical stack '%s' is defined in more than one stack config file: %s",
+					logicalStack, strings.Join(l.stackNames(stackFiles), ", ")),
+			})
+		}
+	}
+
+	err := l.lintFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(l.issues, func(i, j int) bool {
+		return l.issues[i].File < l.issues[j].File
+	})
+
+	return l.issues, nil
+}
+
+// lintStack processes the stack config file and checks its components
+func (l *stacksLinter) lintStack(stackFile string) error {
+	stackName := l.stackName(stackFile)
+	file := l.displayPath(stackFile)
+	basePath := l.processed.StacksBaseAbsolutePath
+
+	config, importsConfig, err := l.fileSystem.ProcessYAMLConfigFile(basePath, stackFile, map[string]map[interface{}]interface{}{})
+	if err != nil {
+		l.add(Issue{Rule: RuleInvalidStack, Level: LevelError, File: file, Stack: stackName, Message: strings.TrimSpace(err.Error())})
+		l.importsIncomplete = true
+		return nil
+	}
+
+	for imp := range importsConfig {
+		l.imported[imp] = true
+	}
+
+	// The stack processor fails on the first missing base component, so check all of them before processing the stack
+	if l.lintBaseComponents(config, file, stackName) {
+		return nil
+	}
+
+	finalConfig, err := s.ProcessConfig(basePath, stackFile, config, false, false, "", map[string]map[string][]string{}, importsConfig)
+	if err != nil {
+		l.add(Issue{Rule: RuleInvalidStack, Level: LevelError, File: file, Stack: stackName, Message: strings.TrimSpace(err.Error())})
+		return nil
+	}
+
+	componentsSection, ok := finalConfig["components"].(map[string]map[string]s.ComponentConfig)
+	if !ok {
+		return nil
+	}
+
+	logicalStack := ""
+	invalidStackName := false
+
+	for _, componentType := range componentTypes {
+		componentTypeSection := componentsSection[componentType]
+
+		var components []string
+		for component := range componentTypeSection {
+			components = append(components, component)
+		}
+		sort.Strings(components)
+
+		for _, component := range components {
+			componentConfig := componentTypeSection[component]
+
+			l.lintComponentFolder(componentType, component, componentConfig, file, stackName)
+
+			if componentType == "terraform" {
+				l.lintComponentBackends(component, componentConfig, file, stackName)
+			}
+
+			componentStack := stackName
+			if len(l.config.Stacks.NamePattern) > 0 && !invalidStackName {
+				contextPrefix, err := c.GetContextPrefix(stackName, c.GetContextFromVars(componentConfig.Vars), l.config.Stacks.NamePattern)
+				if err != nil {
+					invalidStackName = true
+					l.add(Issue{
+						Rule:      RuleInvalidStackName,
+						Level:     LevelError,
+						File:      file,
+						Stack:     stackName,
+						Component: component,
+						Message: fmt.Sprintf("the stack does not resolve to a name using the stack name pattern '%s': %s",
+							l.config.Stacks.NamePattern, strings.TrimSpace(err.Error())),
+					})
+				} else {
+					componentStack = contextPrefix
+					if len(logicalStack) == 0 {
+						logicalStack = contextPrefix
+					}
+				}
+			}
+
+			validationErrors, err := l.validator.ValidateComponent(atmos.Component{
+				Name:      component,
+				Type:      componentType,
+				Stack:     componentStack,
+				StackFile: stackName,
+				Config:    componentConfig,
+			})
+			if err != nil {
+				return err
+			}
+			for _, validationError := range validationErrors {
+				l.add(Issue{
+					Rule:      RuleJSONSchema,
+					Level:     LevelError,
+					File:      file,
+					Stack:     stackName,
+					Component: component,
+					Message:   fmt.Sprintf("validation '%s': %s: %s", validationError.Validation, validationError.Path, validationError.Message),
+				})
+			}
+		}
+	}
+
+	if len(logicalStack) > 0 {
+		l.logicalStacks[logicalStack] = append(l.logicalStacks[logicalStack], stackFile)
+	}
+
+	return nil
+}
+
+// lintBaseComponents checks that the base components of all components are defined in the stack.
+// It returns true if any base component is missing
+func (l *stacksLinter) lintBaseComponents(config map[interface{}]interface{}, file string, stackName string) bool {
+	missing := false
+
+	componentsSection, ok := config["components"].(map[interface{}]interface{})
+	if !ok {
+		return false
+	}
+
+	for _, componentType := range componentTypes {
+		componentTypeSection, ok := componentsSection[componentType].(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+
+		var components []string
+		for component := range componentTypeSection {
+			components = append(components, fmt.Sprintf("%v", component))
+		}
+		sort.Strings(components)
+
+		for _, component := range components {
+			componentMap, ok := componentTypeSection[component].(map[interface{}]interface{})
+			if !ok {
+				continue
+			}
+			baseComponent, ok := componentMap["component"].(string)
+			if !ok || baseComponent == component {
+				continue
+			}
+			if _, ok := componentTypeSection[baseComponent]; !ok {
+				missing = true
+				l.add(Issue{
+					Rule:      RuleMissingBaseComponent,
+					Level:     LevelError,
+					File:      file,
+					Stack:     stackName,
+					Component: component,
+					Message: fmt.Sprintf("the %s component '%s' inherits from the base component '%s', which is not defined in the stack",
+						componentType, component, baseComponent),
+				})
+			}
+		}
+	}
+
+	return missing
+}
+
+// lintComponentBackends checks that the `backend` and `remote_state_backend` configs of the terraform component
+// have the fields required by their backend types
+func (l *stacksLinter) lintComponentBackends(component string, componentConfig s.ComponentConfig, file string, stackName string) {
+	sections := []struct {
+		name        string
+		backendType string
+		backend     map[string]interface{}
+	}{
+		{"backend", componentConfig.BackendType, componentConfig.Backend},
+		{"remote_state_backend", componentConfig.RemoteStateBackendType, componentConfig.RemoteStateBackend},
+	}
+
+	invalidBackendType := ""
+	for _, section := range sections {
+		// The `remote_state_backend` section inherits from the `backend` section, don't report the same issue twice
+		if len(section.backendType) == 0 || section.backendType == invalidBackendType {
+			continue
+		}
+
+		var message string
+		if len(section.backend) == 0 {
+			message = fmt.Sprintf("the component '%s' has '%s_type: %s', but there is no '%s.%s' section",
+				component, section.name, section.backendType, section.name, section.backendType)
+		} else if err := tf.ValidateBackend(section.backendType, section.backend); err != nil {
+			message = fmt.Sprintf("the '%s' section of the component '%s': %s", section.name, component, err)
+		} else {
+			continue
+		}
+
+		invalidBackendType = section.backendType
+		l.add(Issue{
+			Rule:      RuleMissingBackend,
+			Level:     LevelError,
+			File:      file,
+			Stack:     stackName,
+			Component: component,
+			Message:   message,
+		})
+	}
+}
+
+// lintComponentFolder checks that the folder of the component exists in the terraform or helmfile base path
+func (l *stacksLinter) lintComponentFolder(componentType string, component string, componentConfig s.ComponentConfig, file string, stackName string) {
+	folder := component
+	if len(componentConfig.BaseComponent) > 0 {
+		folder = componentConfig.BaseComponent
+	}
+
+	basePath := l.processed.TerraformDirAbsolutePath
+	if componentType == "helmfile" {
+		basePath = l.processed.HelmfileDirAbsolutePath
+	}
+
+	componentPath := filepath.Join(basePath, folder)
+	if exists, err := u.IsDirectory(componentPath); err == nil && exists {
+		return
+	}
+
+	l.add(Issue{
+		Rule:      RuleMissingComponentFolder,
+		Level:     LevelError,
+		File:      file,
+		Stack:     stackName,
+		Component: component,
+		Message:   fmt.Sprintf("the folder '%s' of the %s component '%s' does not exist in '%s'", folder, componentType, component, basePath),
+	})
+}
+
+// lintFiles checks the top-level sections of all config files in the stacks base path and finds the files which are not used by any stack
+func (l *stacksLinter) lintFiles() error {
+	basePath := l.processed.StacksBaseAbsolutePath
+
+	files, err := l.fileSystem.GetGlobMatches(path.Join(basePath, "**/*.{yaml,yml}"))
+	if err != nil {
+		return err
+	}
+
+	stackFiles := map[string]bool{}
+	for _, stackFile := range l.processed.StackConfigFilesAbsolutePaths {
+		stackFiles[stackFile] = true
+	}
+
+	for _, configFile := range files {
+		file := l.displayPath(configFile)
+
+		content, err := l.fileSystem.ReadFile(configFile)
+		if err != nil {
+			return err
+		}
+
+		config, err := convert.YAMLToMapOfInterfaces(content)
+		if err != nil {
+			l.add(Issue{Rule: RuleInvalidStack, Level: LevelError, File: file, Message: strings.TrimSpace(err.Error())})
+			continue
+		}
+
+		var keys []string
+		for key := range config {
+			keys = append(keys, fmt.Sprintf("%v", key))
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if !u.SliceContainsString(stackConfigSections, key) {
+				l.add(Issue{
+					Rule:  RuleUnknownKey,
+					Level: LevelError,
+					File:  file,
+					Message: fmt.Sprintf("unknown top-level section '%s'. Allowed sections are: %s",
+						key, strings.Join(stackConfigSections, ", ")),
+				})
+			}
+		}
+
+		// If the imports of a stack could not be processed, any file could be imported by it
+		if !stackFiles[configFile] && !l.imported[l.stackName(configFile)] && !l.importsIncomplete {
+			l.add(Issue{
+				Rule:    RuleUnusedFile,
+				Level:   LevelWarning,
+				File:    file,
+				Message: "the config file is not a stack and it's not imported by any stack",
+			})
+		}
+	}
+
+	return nil
+}
+
+func (l *stacksLinter) add(issue Issue) {
+	l.issues = append(l.issues, issue)
+}
+
+// stackName returns the path of the config file relative to the stacks base path without extension
+func (l *stacksLinter) stackName(configFile string) string {
+	return strings.TrimSuffix(
+		strings.TrimSuffix(
+			u.TrimBasePathFromPath(l.processed.StacksBaseAbsolutePath+"/", configFile),
+			g.DefaultStackConfigFileExtension),
+		".yml",
+	)
+}
+
+func (l *stacksLinter) stackNames(configFiles []string) []string {
+	var res []string
+	for _, configFile := range configFiles {
+		res = append(res, l.stackName(configFile))
+	}
+	return res
+}
+
+// displayPath returns the path of the config file relative to the current directory (or the path in the stacks filesystem)
+func (l *stacksLinter) displayPath(configFile string) string {
+	if !l.fileSystem.IsOS() {
+		return configFile
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(cwd, configFile); err == nil {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(configFile)
+}
@@ -0,0 +1,262 @@
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cloudposse/atmos/pkg/atmos"
+	s "github.com/cloudposse/atmos/pkg/stack"
+	"github.com/pkg/errors"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+const (
+	// SchemaTypeJSONSchema is the type of the validations which validate the component with a JSON Schema
+	SchemaTypeJSONSchema = "jsonschema"
+)
+
+// Validation is an item of the `settings.validation` section of a component
+type Validation struct {
+	Name        string `yaml:"name" json:"name"`
+	SchemaType  string `yaml:"schema_type" json:"schema_type"`
+	SchemaPath  string `yaml:"schema_path" json:"schema_path"`
+	Description string `yaml:"description" json:"description"`
+	Disabled    bool   `yaml:"disabled" json:"disabled"`
+}
+
+// Error is a validation error of a component in a stack
+type Error struct {
+	Stack      string `yaml:"stack" json:"stack"`
+	Component  string `yaml:"component" json:"component"`
+	Validation string `yaml:"validation" json:"validation"`
+	Path       string `yaml:"path" json:"path"`
+	Message    string `yaml:"message" json:"message"`
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("stack '%s', component '%s', validation '%s': %s: %s", e.Stack, e.Component, e.Validation, e.Path, e.Message)
+}
+
+// Validator validates the final (deep-merged) config of the components.
+// The compiled schemas are cached, so one validator should be used to validate all components
+type Validator struct {
+	schemasBasePath string
+
+	schemasLock sync.Mutex
+	schemas     map[string]*jsonschema.Schema
+}
+
+// NewValidator creates a validator. Relative schema paths are relative to `schemasBasePath`
+func NewValidator(schemasBasePath string) *Validator {
+	return &Validator{
+		schemasBasePath: schemasBasePath,
+		schemas:         map[string]*jsonschema.Schema{},
+	}
+}
+
+// GetValidations returns the validations from the `settings.validation` section of the component sorted by name
+func GetValidations(component atmos.Component) ([]Validation, error) {
+	validationSection, ok := component.Config.Settings["validation"]
+	if !ok || validationSection == nil {
+		return nil, nil
+	}
+
+	validationMap, ok := validationSection.(map[string]interface{})
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Invalid 'settings.validation' section of the component '%s' in the stack '%s'",
+			component.Name, component.Stack))
+	}
+
+	var res []Validation
+	for name, v := range validationMap {
+		var validation Validation
+
+		j, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(j, &validation)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid validation '%s' of the component '%s' in the stack '%s': %s",
+				name, component.Name, component.Stack, err))
+		}
+
+		validation.Name = name
+		res = append(res, validation)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+
+	return res, nil
+}
+
+// ValidateComponent validates the final vars and settings of the component with all validations from its `settings.validation` section.
+// It returns the validation errors (empty if the component is valid), or an error if the validations could not be executed
+func (v *Validator) ValidateComponent(component atmos.Component) ([]Error, error) {
+	validations, err := GetValidations(component)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []Error
+
+	for _, validation := range validations {
+		if validation.Disabled {
+			continue
+		}
+
+		if len(validation.SchemaPath) == 0 {
+			return nil, errors.New(fmt.Sprintf("'schema_path' must be provided for the validation '%s' of the component '%s' in the stack '%s'",
+				validation.Name, component.Name, component.Stack))
+		}
+
+		switch validation.SchemaType {
+		case SchemaTypeJSONSchema:
+			validationErrors, err := v.validateWithJSONSchema(component, validation)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, validationErrors...)
+		default:
+			return nil, errors.New(fmt.Sprintf("Invalid 'schema_type' '%s' of the validation '%s' of the component '%s' in the stack '%s'. "+
+				"Supported schema types are: %s",
+				validation.SchemaType, validation.Name, component.Name, component.Stack, SchemaTypeJSONSchema))
+		}
+	}
+
+	return res, nil
+}
+
+// validateWithJSONSchema validates the component with the JSON Schema of the validation
+func (v *Validator) validateWithJSONSchema(component atmos.Component, validation Validation) ([]Error, error) {
+	schema, err := v.getJSONSchema(v.schemaPath(validation))
+	if err != nil {
+		return nil, err
+	}
+
+	// The JSON Schema validator accepts only the values returned by the JSON decoder
+	j, err := json.Marshal(component.Config.ToMap())
+	if err != nil {
+		return nil, err
+	}
+	var data interface{}
+	err = json.Unmarshal(j, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = schema.Validate(data)
+	if err == nil {
+		return nil, nil
+	}
+
+	validationError, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return nil, err
+	}
+
+	var res []Error
+	for _, leaf := range leafValidationErrors(validationError) {
+		// The values of the `!secret`, `!ref` and `!terraform.output` tags are known only when the component is executed
+		if value, ok := jsonPointerValue(data, leaf.InstanceLocation).(string); ok && s.IsTaggedString(value) {
+			continue
+		}
+		res = append(res, Error{
+			Stack:      component.Stack,
+			Component:  component.Name,
+			Validation: validation.Name,
+			Path:       jsonPointerToPath(leaf.InstanceLocation),
+			Message:    leaf.Message,
+		})
+	}
+
+	return res, nil
+}
+
+// schemaPath returns the path to the schema of the validation
+func (v *Validator) schemaPath(validation Validation) string {
+	if filepath.IsAbs(validation.SchemaPath) {
+		return validation.SchemaPath
+	}
+	return filepath.Join(v.schemasBasePath, validation.SchemaPath)
+}
+
+// getJSONSchema compiles the JSON Schema or returns it from the cache
+func (v *Validator) getJSONSchema(schemaPath string) (*jsonschema.Schema, error) {
+	v.schemasLock.Lock()
+	defer v.schemasLock.Unlock()
+
+	if schema, ok := v.schemas[schemaPath]; ok {
+		return schema, nil
+	}
+
+	schema, err := jsonschema.NewCompiler().Compile(schemaPath)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid JSON Schema '%s': %s", schemaPath, err))
+	}
+
+	v.schemas[schemaPath] = schema
+	return schema, nil
+}
+
+// leafValidationErrors returns the validation errors without causes (the errors which describe what is wrong in the data)
+func leafValidationErrors(validationError *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(validationError.Causes) == 0 {
+		return []*jsonschema.ValidationError{validationError}
+	}
+
+	var res []*jsonschema.ValidationError
+	for _, cause := range validationError.Causes {
+		res = append(res, leafValidationErrors(cause)...)
+	}
+	return res
+}
+
+// jsonPointerValue returns the value at the JSON pointer (e.g. `/vars/subnets/0`) in the decoded JSON data, or `nil` if it does not exist
+func jsonPointerValue(data interface{}, pointer string) interface{} {
+	if len(pointer) == 0 {
+		return data
+	}
+
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch v := data.(type) {
+		case map[string]interface{}:
+			data = v[token]
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil
+			}
+			data = v[i]
+		default:
+			return nil
+		}
+	}
+	return data
+}
+
+// jsonPointerToPath converts a JSON pointer (e.g. `/vars/subnets/0`) to a JSON path (e.g. `$.vars.subnets[0]`)
+func jsonPointerToPath(pointer string) string {
+	res := "$"
+	if len(pointer) == 0 {
+		return res
+	}
+
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		if _, err := strconv.Atoi(token); err == nil {
+			res += "[" + token + "]"
+		} else {
+			res += "." + token
+		}
+	}
+	return res
+}
@@ -0,0 +1,465 @@
+package validate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/cloudposse/atmos/pkg/atmos"
+	tf "github.com/cloudposse/atmos/pkg/terraform"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateComponent(t *testing.T) {
+	client, err := atmos.NewClient(atmos.Options{
+		StacksBasePath:    "../../examples/complete/stacks",
+		StackNamePattern:  "{tenant}-{environment}-{stage}",
+		TerraformBasePath: "../../examples/complete/components/terraform",
+		HelmfileBasePath:  "../../examples/complete/components/helmfile",
+		SchemasBasePath:   "../../examples/complete/schemas",
+	})
+	assert.Nil(t, err)
+
+	component, err := client.Component("tenant1-ue2-dev", "infra/vpc")
+	assert.Nil(t, err)
+
+	validations, err := GetValidations(component)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(validations))
+	assert.Equal(t, "validate-infra-vpc-component-with-jsonschema", validations[0].Name)
+	assert.Equal(t, SchemaTypeJSONSchema, validations[0].SchemaType)
+
+	validator := NewValidator(client.ProcessedConfig().SchemasDirAbsolutePath)
+
+	validationErrors, err := validator.ValidateComponent(component)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(validationErrors))
+
+	// Break the final vars of the component
+	vars := map[string]interface{}{}
+	for k, val := range component.Config.Vars {
+		vars[k] = val
+	}
+	vars["cidr_block"] = "10.0.0.0"
+	vars["cidr_blok"] = "10.0.0.0/18"
+	component.Config.Vars = vars
+
+	validationErrors, err = validator.ValidateComponent(component)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(validationErrors))
+
+	paths := map[string]Error{}
+	for _, validationError := range validationErrors {
+		assert.Equal(t, "tenant1-ue2-dev", validationError.Stack)
+		assert.Equal(t, "infra/vpc", validationError.Component)
+		assert.Equal(t, "validate-infra-vpc-component-with-jsonschema", validationError.Validation)
+		paths[validationError.Path] = validationError
+	}
+	assert.Contains(t, paths, "$.vars")
+	assert.Contains(t, paths["$.vars"].Message, "cidr_blok")
+	assert.Contains(t, paths, "$.vars.cidr_block")
+	assert.Contains(t, paths["$.vars.cidr_block"].Error(), "stack 'tenant1-ue2-dev', component 'infra/vpc'")
+
+	// The tagged strings are resolved only when the component is executed
+	vars["cidr_block"] = "!terraform.output vpc-base tenant1-ue2-prod cidr_block"
+	delete(vars, "cidr_blok")
+
+	validationErrors, err = validator.ValidateComponent(component)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(validationErrors))
+
+	// Unknown schema type
+	component.Config.Settings = map[string]interface{}{
+		"validation": map[string]interface{}{
+			"test": map[string]interface{}{
+				"schema_type": "unknown",
+				"schema_path": "jsonschema/infra/vpc.json",
+			},
+		},
+	}
+	_, err = validator.ValidateComponent(component)
+	assert.NotNil(t, err)
+}
+
+func TestJSONPointerValue(t *testing.T) {
+	data := map[string]interface{}{
+		"vars": map[string]interface{}{
+			"subnets": []interface{}{"10.0.0.0/24"},
+			"a/b":     true,
+		},
+	}
+	assert.Equal(t, data, jsonPointerValue(data, ""))
+	assert.Equal(t, "10.0.0.0/24", jsonPointerValue(data, "/vars/subnets/0"))
+	assert.Equal(t, true, jsonPointerValue(data, "/vars/a~1b"))
+	assert.Nil(t, jsonPointerValue(data, "/vars/subnets/1"))
+	assert.Nil(t, jsonPointerValue(data, "/vars/subnets/0/name"))
+}
+
+func TestJSONPointerToPath(t *testing.T) {
+	assert.Equal(t, "$", jsonPointerToPath(""))
+	assert.Equal(t, "$.vars.cidr_block", jsonPointerToPath("/vars/cidr_block"))
+	assert.Equal(t, "$.vars.subnets[0].name", jsonPointerToPath("/vars/subnets/0/name"))
+	assert.Equal(t, "$.vars.a/b", jsonPointerToPath("/vars/a~1b"))
+}
+
+func TestPolicyEngine(t *testing.T) {
+	client, err := atmos.NewClient(atmos.Options{
+		StacksBasePath:    "../../examples/complete/stacks",
+		StackNamePattern:  "{tenant}-{environment}-{stage}",
+		TerraformBasePath: "../../examples/complete/components/terraform",
+		HelmfileBasePath:  "../../examples/complete/components/helmfile",
+		PoliciesBasePath:  "../../examples/complete/policies",
+	})
+	assert.Nil(t, err)
+
+	policyEngine := NewPolicyEngine(client.ProcessedConfig().PoliciesDirAbsolutePath)
+
+	policies, err := policyEngine.Policies()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(policies))
+
+	component, err := client.Component("tenant1-ue2-prod", "infra/vpc")
+	assert.Nil(t, err)
+
+	violations, err := policyEngine.EvaluateComponent(component)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(violations))
+
+	// Break the final config of the component
+	vars := map[string]interface{}{}
+	for k, val := range component.Config.Vars {
+		vars[k] = val
+	}
+	vars["nat_instance_type"] = "t3.micro"
+	vars["availability_zones"] = []interface{}{"us-east-2a"}
+	component.Config.Vars = vars
+
+	backend := map[string]interface{}{}
+	for k, val := range component.Config.Backend {
+		backend[k] = val
+	}
+	delete(backend, "encrypt")
+	component.Config.Backend = backend
+
+	violations, err = policyEngine.EvaluateComponent(component)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(violations))
+	assert.True(t, HasDenyViolations(violations))
+
+	levels := map[string]int{}
+	for _, violation := range violations {
+		assert.Equal(t, "tenant1-ue2-prod", violation.Stack)
+		assert.Equal(t, "infra/vpc", violation.Component)
+		levels[violation.Level]++
+	}
+	assert.Equal(t, 2, levels[PolicyLevelDeny])
+	assert.Equal(t, 1, levels[PolicyLevelWarn])
+	assert.Contains(t, violations[0].Error(), "deny: stack 'tenant1-ue2-prod', component 'infra/vpc'")
+
+	// No policies
+	policyEngine = NewPolicyEngine("../../examples/complete/does-not-exist")
+	violations, err = policyEngine.EvaluateComponent(component)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(violations))
+}
+
+func TestLintStacks(t *testing.T) {
+	stacksFS := fstest.MapFS{
+		"globals.yaml": {Data: []byte(`
+vars:
+  namespace: eg
+terraform:
+  backend_type: s3
+  backend:
+    s3:
+      encrypt: true
+      bucket: eg-ue2-root-tfstate
+      key: terraform.tfstate
+      region: us-east-2
+`)},
+		"catalog/vpc.yaml": {Data: []byte(`
+components:
+  terraform:
+    infra/vpc:
+      vars:
+        cidr_block: 10.0.0.0/18
+    vpc-base:
+      component: infra/vpc-missing
+`)},
+		"catalog/unused.yaml": {Data: []byte(`
+vars:
+  unused: true
+`)},
+		"tenant1/ue2/dev.yaml": {Data: []byte(`
+import:
+  - globals
+  - catalog/vpc
+vars:
+  tenant: tenant1
+  environment: ue2
+  stage: dev
+`)},
+		"tenant1/ue2/prod.yaml": {Data: []byte(`
+import:
+  - globals
+vars:
+  tenant: tenant1
+  environment: ue2
+  stage: prod
+unknown_section: {}
+components:
+  terraform:
+    infra/vpc:
+      backend_type: gcs
+      vars:
+        cidr_block: 10.1.0.0/18
+    does/not/exist:
+      vars: {}
+`)},
+		"tenant1/ue2/prod2.yaml": {Data: []byte(`
+import:
+  - globals
+vars:
+  tenant: tenant1
+  environment: ue2
+  stage: prod
+components:
+  terraform:
+    infra/vpc:
+      vars:
+        cidr_block: 10.2.0.0/18
+`)},
+		"tenant1/ue2/noname.yaml": {Data: []byte(`
+import:
+  - globals
+vars:
+  tenant: tenant1
+components:
+  terraform:
+    infra/vpc:
+      vars:
+        cidr_block: 10.3.0.0/18
+`)},
+	}
+
+	client, err := atmos.NewClient(atmos.Options{
+		FS:                stacksFS,
+		IncludedPaths:     []string{"**/*"},
+		ExcludedPaths:     []string{"globals.yaml", "catalog/**/*"},
+		StackNamePattern:  "{tenant}-{environment}-{stage}",
+		TerraformBasePath: "../../examples/complete/components/terraform",
+		HelmfileBasePath:  "../../examples/complete/components/helmfile",
+	})
+	assert.Nil(t, err)
+
+	issues, err := LintStacks(client)
+	assert.Nil(t, err)
+	assert.True(t, HasErrors(issues))
+
+	rules := map[string][]Issue{}
+	for _, issue := range issues {
+		rules[issue.Rule] = append(rules[issue.Rule], issue)
+	}
+
+	assert.Equal(t, 1, len(rules[RuleMissingBaseComponent]))
+	assert.Equal(t, "tenant1/ue2/dev.yaml", rules[RuleMissingBaseComponent][0].File)
+	assert.Equal(t, "vpc-base", rules[RuleMissingBaseComponent][0].Component)
+
+	assert.Equal(t, 1, len(rules[RuleUnknownKey]))
+	assert.Equal(t, "tenant1/ue2/prod.yaml", rules[RuleUnknownKey][0].File)
+	assert.Contains(t, rules[RuleUnknownKey][0].Message, "unknown_section")
+
+	assert.Equal(t, 1, len(rules[RuleMissingComponentFolder]))
+	assert.Equal(t, "does/not/exist", rules[RuleMissingComponentFolder][0].Component)
+
+	assert.Equal(t, 1, len(rules[RuleMissingBackend]))
+	assert.Equal(t, "infra/vpc", rules[RuleMissingBackend][0].Component)
+	assert.Equal(t, "tenant1/ue2/prod", rules[RuleMissingBackend][0].Stack)
+	assert.Contains(t, rules[RuleMissingBackend][0].Message, "the 'gcs' backend config is missing 'bucket'")
+
+	assert.Equal(t, 1, len(rules[RuleInvalidStackName]))
+	assert.Equal(t, "tenant1/ue2/noname", rules[RuleInvalidStackName][0].Stack)
+
+	assert.Equal(t, 2, len(rules[RuleDuplicateStack]))
+	assert.Contains(t, rules[RuleDuplicateStack][0].Message, "tenant1-ue2-prod")
+
+	assert.Equal(t, 1, len(rules[RuleUnusedFile]))
+	assert.Equal(t, "catalog/unused.yaml", rules[RuleUnusedFile][0].File)
+	assert.Equal(t, LevelWarning, rules[RuleUnusedFile][0].Level)
+
+	sarif := ToSARIF(issues)
+	assert.Equal(t, "2.1.0", sarif.Version)
+	assert.Equal(t, len(issues), len(sarif.Runs[0].Results))
+	assert.Equal(t, len(Rules), len(sarif.Runs[0].Tool.Driver.Rules))
+}
+
+func TestLintStacksInvalidImports(t *testing.T) {
+	stacksFS := fstest.MapFS{
+		"catalog/vpc.yaml": {Data: []byte(`
+import:
+  - catalog/vpc-defaults
+components:
+  terraform:
+    infra/vpc:
+      vars:
+        cidr_block: 10.0.0.0/18
+`)},
+		"catalog/vpc-defaults.yaml": {Data: []byte(`
+vars:
+  namespace: eg
+`)},
+		"tenant1/ue2/dev.yaml": {Data: []byte(`
+import:
+  - catalog/vpc
+  - catalog/missing
+vars:
+  tenant: tenant1
+  environment: ue2
+  stage: dev
+`)},
+	}
+
+	client, err := atmos.NewClient(atmos.Options{
+		FS:                stacksFS,
+		IncludedPaths:     []string{"**/*"},
+		ExcludedPaths:     []string{"catalog/**/*"},
+		StackNamePattern:  "{tenant}-{environment}-{stage}",
+		TerraformBasePath: "../../examples/complete/components/terraform",
+	})
+	assert.Nil(t, err)
+
+	issues, err := LintStacks(client)
+	assert.Nil(t, err)
+
+	rules := map[string][]Issue{}
+	for _, issue := range issues {
+		rules[issue.Rule] = append(rules[issue.Rule], issue)
+	}
+
+	// The stack with the missing import is invalid, but the files it imports are not reported as unused
+	assert.Equal(t, 1, len(rules[RuleInvalidStack]))
+	assert.Equal(t, "tenant1/ue2/dev.yaml", rules[RuleInvalidStack][0].File)
+	assert.Equal(t, 0, len(rules[RuleUnusedFile]))
+}
+
+func TestValidateComponentVars(t *testing.T) {
+	client, err := atmos.NewClient(atmos.Options{
+		StacksBasePath:    "../../examples/complete/stacks",
+		StackNamePattern:  "{tenant}-{environment}-{stage}",
+		TerraformBasePath: "../../examples/complete/components/terraform",
+		HelmfileBasePath:  "../../examples/complete/components/helmfile",
+	})
+	assert.Nil(t, err)
+
+	component, err := client.FindComponent("tenant1-ue2-dev", "terraform", "infra/vpc")
+	assert.Nil(t, err)
+
+	componentPath := "../../examples/complete/components/terraform/infra/vpc"
+
+	issues, err := ValidateComponentVars(component, componentPath)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(issues))
+
+	// Break the final vars of the component
+	vars := map[string]interface{}{}
+	for k, val := range component.Config.Vars {
+		vars[k] = val
+	}
+	delete(vars, "cidr_block")
+	vars["availability_zones"] = "us-east-2a"
+	vars["nat_gateway_enabled"] = "yes"
+	vars["max_subnet_count"] = []interface{}{3}
+	vars["not_declared"] = true
+	vars["region"] = nil
+	component.Config.Vars = vars
+
+	issues, err = ValidateComponentVars(component, componentPath)
+	assert.Nil(t, err)
+	assert.True(t, HasErrors(issues))
+
+	rules := map[string][]string{}
+	for _, issue := range issues {
+		assert.Equal(t, "tenant1-ue2-dev", issue.Stack)
+		assert.Equal(t, "infra/vpc", issue.Component)
+		rules[issue.Rule] = append(rules[issue.Rule], issue.Message)
+	}
+
+	assert.Equal(t, 1, len(rules[RuleUndeclaredVar]))
+	assert.Contains(t, rules[RuleUndeclaredVar][0], "not_declared")
+	assert.Equal(t, 2, len(rules[RuleMissingRequiredVar]))
+	assert.Contains(t, rules[RuleMissingRequiredVar][0], "the required variable 'region'")
+	assert.Contains(t, rules[RuleMissingRequiredVar][0], "is set to null")
+	assert.Contains(t, rules[RuleMissingRequiredVar][1], "cidr_block")
+	assert.Equal(t, 3, len(rules[RuleVarTypeMismatch]))
+
+	assert.True(t, valueMatchesType("10", "number"))
+	assert.True(t, valueMatchesType(10, "string"))
+	assert.True(t, valueMatchesType(nil, "list(string)"))
+	assert.True(t, valueMatchesType([]interface{}{}, "any"))
+	assert.False(t, valueMatchesType(map[string]interface{}{}, "list(string)"))
+	assert.True(t, valueMatchesType(map[string]interface{}{}, "object({ name = string })"))
+
+	// The tagged strings are resolved only when the component is executed
+	assert.True(t, valueMatchesType("!terraform.output vpc-base max_subnet_count", "number"))
+	assert.True(t, valueMatchesType("!secret env:AVAILABILITY_ZONES", "list(string)"))
+	assert.True(t, valueMatchesType("!ref infra/vpc-base.vars.nat_gateway_enabled", "bool"))
+	assert.False(t, valueMatchesType("!unknown value", "bool"))
+}
+
+func TestValidateGeneratedCatalogVars(t *testing.T) {
+	componentPath := "../../examples/complete/components/terraform/infra/vpc"
+
+	variables, err := tf.LoadVariables(componentPath)
+	assert.Nil(t, err)
+
+	catalog, err := tf.GenerateCatalog("infra/vpc", variables)
+	assert.Nil(t, err)
+
+	client, err := atmos.NewClient(atmos.Options{
+		FS: fstest.MapFS{
+			"catalog/vpc.yaml": {Data: catalog},
+			// The context vars are `null` in the catalog, so they are set for the component
+			"tenant1/ue2/dev.yaml": {Data: []byte(`
+import:
+  - catalog/vpc
+components:
+  terraform:
+    infra/vpc:
+      vars:
+        tenant: tenant1
+        environment: ue2
+        stage: dev
+`)},
+		},
+		IncludedPaths:    []string{"**/*"},
+		ExcludedPaths:    []string{"catalog/**/*"},
+		StackNamePattern: "{tenant}-{environment}-{stage}",
+	})
+	assert.Nil(t, err)
+
+	component, err := client.FindComponent("tenant1-ue2-dev", "terraform", "infra/vpc")
+	assert.Nil(t, err)
+
+	// The required variables are `null` in the generated catalog until they are set in the stacks
+	issues, err := ValidateComponentVars(component, componentPath)
+	assert.Nil(t, err)
+	assert.True(t, HasErrors(issues))
+
+	var missing []string
+	for _, issue := range issues {
+		if issue.Rule == RuleMissingRequiredVar {
+			missing = append(missing, issue.Message)
+		}
+	}
+
+	var required []string
+	for _, variable := range variables {
+		if variable.Required {
+			required = append(required, variable.Name)
+		}
+	}
+	assert.NotEmpty(t, required)
+	assert.Equal(t, len(required), len(missing))
+	for i, name := range required {
+		assert.Contains(t, missing[i], "the required variable '"+name+"'")
+	}
+}
@@ -0,0 +1,164 @@
+package validate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cloudposse/atmos/pkg/atmos"
+	s "github.com/cloudposse/atmos/pkg/stack"
+	tf "github.com/cloudposse/atmos/pkg/terraform"
+	u "github.com/cloudposse/atmos/pkg/utils"
+)
+
+// ValidateComponentVars compares the final vars of the terraform component with the variables declared in the `*.tf` files in the component folder.
+// It reports the vars which are not declared (warnings), the required variables without a value, and the vars whose type does not match the variable type
+func ValidateComponentVars(component atmos.Component, componentPath string) ([]Issue, error) {
+	variables, err := tf.LoadVariables(componentPath)
+	if err != nil {
+		return nil, err
+	}
+
+	autoLoaded, err := tf.AutoLoadedVariables(componentPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []Issue
+	newIssue := func(rule string, level string, message string) Issue {
+		return Issue{
+			Rule:      rule,
+			Level:     level,
+			File:      componentPath,
+			Stack:     component.Stack,
+			Component: component.Name,
+			Message:   message,
+		}
+	}
+
+	declared := map[string]tf.Variable{}
+	for _, variable := range variables {
+		declared[variable.Name] = variable
+	}
+
+	var names []string
+	for name := range component.Config.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := component.Config.Vars[name]
+
+		variable, ok := declared[name]
+		if !ok {
+			res = append(res, newIssue(RuleUndeclaredVar, LevelWarning,
+				fmt.Sprintf("the var '%s' is not declared as a variable in the component", name)))
+			continue
+		}
+
+		if !valueMatchesType(value, variable.Type) {
+			res = append(res, newIssue(RuleVarTypeMismatch, LevelError,
+				fmt.Sprintf("the var '%s' is %s, but the variable is declared as '%s' in '%s:%d'",
+					name, describeValue(value), variable.Type, variable.File, variable.Line)))
+		}
+	}
+
+	for _, variable := range variables {
+		if !variable.Required {
+			continue
+		}
+		// terraform rejects `null` for a required variable, and the varfile takes precedence over the `*.auto.tfvars` files and the ENV vars
+		if value, ok := component.Config.Vars[variable.Name]; ok {
+			if value == nil {
+				res = append(res, newIssue(RuleMissingRequiredVar, LevelError,
+					fmt.Sprintf("the required variable '%s' declared in '%s:%d' is set to null", variable.Name, variable.File, variable.Line)))
+			}
+			continue
+		}
+		if u.SliceContainsString(autoLoaded, variable.Name) {
+			continue
+		}
+		if _, ok := component.Config.Env["TF_VAR_"+variable.Name]; ok {
+			continue
+		}
+		res = append(res, newIssue(RuleMissingRequiredVar, LevelError,
+			fmt.Sprintf("the required variable '%s' declared in '%s:%d' has no value", variable.Name, variable.File, variable.Line)))
+	}
+
+	return res, nil
+}
+
+// valueMatchesType checks if terraform can convert the value to the type of the variable.
+// Only the obvious mismatches (e.g. a list for a string variable) are detected, the element types of the collections are not checked
+func valueMatchesType(value interface{}, variableType string) bool {
+	if value == nil {
+		return true
+	}
+
+	// The values of the `!secret`, `!ref` and `!terraform.output` tags are known only when the component is executed
+	if str, ok := value.(string); ok && s.IsTaggedString(str) {
+		return true
+	}
+
+	kind := variableType
+	if i := strings.Index(kind, "("); i >= 0 {
+		kind = kind[:i]
+	}
+	kind = strings.TrimSpace(kind)
+
+	switch kind {
+	case "string":
+		switch value.(type) {
+		case []interface{}, map[string]interface{}, map[interface{}]interface{}:
+			return false
+		}
+		return true
+	case "number":
+		switch v := value.(type) {
+		case int, int64, uint64, float64:
+			return true
+		case string:
+			_, err := strconv.ParseFloat(v, 64)
+			return err == nil
+		}
+		return false
+	case "bool":
+		switch v := value.(type) {
+		case bool:
+			return true
+		case string:
+			return v == "true" || v == "false"
+		}
+		return false
+	case "list", "set", "tuple":
+		_, ok := value.([]interface{})
+		return ok
+	case "map", "object":
+		switch value.(type) {
+		case map[string]interface{}, map[interface{}]interface{}:
+			return true
+		}
+		return false
+	}
+
+	// `any` or no type constraint
+	return true
+}
+
+// describeValue returns the kind of the value for the validation messages
+func describeValue(value interface{}) string {
+	switch v := value.(type) {
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}, map[interface{}]interface{}:
+		return "a map"
+	case bool:
+		return fmt.Sprintf("a bool (%v)", v)
+	case string:
+		return fmt.Sprintf("a string ('%s')", v)
+	default:
+		return fmt.Sprintf("a number (%v)", v)
+	}
+}